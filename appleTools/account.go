@@ -0,0 +1,230 @@
+package appleTools
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/xml520/wqutils/httpclient"
+	"strconv"
+)
+
+// TrustedPhone 受信任电话号码
+type TrustedPhone struct {
+	ID                 int    `json:"id"`
+	Number             string `json:"number"`
+	NumberWithDialCode string `json:"numberWithDialCode"`
+	CountryCode        string `json:"countryCode"`
+	CountryDialCode    string `json:"countryDialCode"`
+	PushMode           string `json:"pushMode"`
+	Vetted             bool   `json:"vetted"`
+}
+
+// AppPassword App专用密码
+type AppPassword struct {
+	ID          int    `json:"id"`
+	Label       string `json:"description"`
+	Password    string `json:"password,omitempty"` // 仅创建时返回
+	CreatedDate string `json:"createDatetime"`
+}
+
+// PhoneVerification 添加受信任电话号码时的验证会话
+type PhoneVerification struct {
+	Phone *TrustedPhone
+	Mode  string
+}
+
+// TrustedPhones 获取受信任电话号码
+func (a *AuthSession) TrustedPhones() ([]*TrustedPhone, error) {
+	res, err := a.accountDo("GET", "", nil)
+	if err != nil {
+		return nil, err
+	}
+	var phones []*TrustedPhone
+	for _, p := range res.ToJson("security.phoneNumbers").Array() {
+		phones = append(phones, &TrustedPhone{
+			ID:                 int(p.Get("id").Int()),
+			Number:             p.Get("obfuscatedNumber").String(),
+			NumberWithDialCode: p.Get("numberWithDialCode").String(),
+			CountryCode:        p.Get("countryCode").String(),
+			CountryDialCode:    p.Get("countryDialCode").String(),
+			PushMode:           p.Get("pushMode").String(),
+			Vetted:             p.Get("vetted").Bool(),
+		})
+	}
+	return phones, nil
+}
+
+// AddTrustedPhone 添加受信任电话号码 发送验证码后需调用 VerifyTrustedPhone
+func (a *AuthSession) AddTrustedPhone(countryCode, dialCode, number string) (*PhoneVerification, error) {
+	if number == "" {
+		return nil, errors.New("电话号码不能为空")
+	}
+	phone := &TrustedPhone{Number: number, CountryCode: countryCode, CountryDialCode: dialCode, PushMode: "sms"}
+	res, err := a.accountDo("POST", "/security/verify/phone", map[string]any{
+		"phoneNumberVerification": map[string]any{
+			"phoneNumber": map[string]any{
+				"countryCode":     countryCode,
+				"countryDialCode": dialCode,
+				"number":          number,
+				"nonFTEU":         true,
+			},
+			"mode": "sms",
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	phone.ID = int(res.ToJson("phoneNumberVerification.phoneNumber.id").Int())
+	return &PhoneVerification{Phone: phone, Mode: "sms"}, nil
+}
+
+// VerifyTrustedPhone 提交短信验证码 完成添加受信任电话号码
+func (a *AuthSession) VerifyTrustedPhone(v *PhoneVerification, code string) (*TrustedPhone, error) {
+	if v == nil || v.Phone == nil {
+		return nil, errors.New("验证会话不存在")
+	}
+	res, err := a.accountDo("PUT", "/security/verify/phone/securitycode", map[string]any{
+		"phoneNumberVerification": map[string]any{
+			"phoneNumber": map[string]any{
+				"id":              v.Phone.ID,
+				"countryCode":     v.Phone.CountryCode,
+				"countryDialCode": v.Phone.CountryDialCode,
+				"number":          v.Phone.Number,
+				"nonFTEU":         true,
+			},
+			"securityCode": map[string]string{
+				"code": code,
+			},
+			"mode": v.Mode,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	p := res.ToJson("phoneNumberVerification.phoneNumber")
+	v.Phone.NumberWithDialCode = p.Get("numberWithDialCode").String()
+	v.Phone.Vetted = true
+	return v.Phone, nil
+}
+
+// RemoveTrustedPhone 删除受信任电话号码
+func (a *AuthSession) RemoveTrustedPhone(id int) error {
+	_, err := a.accountDo("DELETE", "/security/phone/"+strconv.Itoa(id), nil)
+	return err
+}
+
+// AppPasswords 获取App专用密码列表
+func (a *AuthSession) AppPasswords() ([]*AppPassword, error) {
+	res, err := a.accountDo("GET", "/security/apps/passwords", nil)
+	if err != nil {
+		return nil, err
+	}
+	var list []*AppPassword
+	if raw := res.ToJson("appSpecificPasswords").Raw; raw != "" {
+		if err = json.Unmarshal([]byte(raw), &list); err != nil {
+			return nil, fmt.Errorf("App专用密码解析失败 %w", err)
+		}
+	}
+	return list, nil
+}
+
+// CreateAppPassword 生成App专用密码
+func (a *AuthSession) CreateAppPassword(label string) (*AppPassword, error) {
+	if label == "" {
+		return nil, errors.New("标签不能为空")
+	}
+	res, err := a.accountDo("POST", "/security/apps/password", map[string]string{"label": label})
+	if err != nil {
+		return nil, err
+	}
+	var p AppPassword
+	if err = json.Unmarshal([]byte(res.ToJson("").Raw), &p); err != nil {
+		return nil, fmt.Errorf("App专用密码解析失败 %w", err)
+	}
+	if p.Password == "" {
+		return nil, errors.New("未返回App专用密码")
+	}
+	return &p, nil
+}
+
+// RevokeAppPassword 撤销App专用密码
+func (a *AuthSession) RevokeAppPassword(id int) error {
+	_, err := a.accountDo("DELETE", "/security/apps/password/"+strconv.Itoa(id), nil)
+	return err
+}
+
+// accountToken 用登录会话换取 appleid.apple.com 的管理会话
+func (a *AuthSession) accountToken() error {
+	res, err := a.accountHttp().Get(a.Auth.accountUrl("/gs/ws/token"))
+	if res != nil && res.Response != nil {
+		defer res.Body.Close()
+	}
+	if err == nil {
+		err = accountStatusError(res)
+	}
+	if err != nil {
+		return err
+	}
+	a.Auth.setHttpCookie(res.Cookies())
+	a.extractAccountHeader(res)
+	a.accountReady = true
+	return nil
+}
+func (a *AuthSession) accountDo(method, path string, data any) (*httpclient.Response, error) {
+	if !a.accountReady {
+		if err := a.accountToken(); err != nil {
+			return nil, err
+		}
+	}
+	if data == nil {
+		data = ""
+	}
+	res, err := a.accountHttp().Json(method, a.Auth.accountUrl(path), data)
+	if res != nil && res.Response != nil {
+		// 先读取内容再关闭 调用方通过 ToJson 读取缓存的内容
+		res.ReadAll()
+		res.Body.Close()
+		if res.StatusCode == 401 {
+			// 管理会话过期 下次请求重新获取
+			a.accountReady = false
+		}
+	}
+	if err == nil {
+		err = accountStatusError(res)
+	}
+	if err != nil {
+		return res, err
+	}
+	a.Auth.setHttpCookie(res.Cookies())
+	a.extractAccountHeader(res)
+	return res, nil
+}
+
+// accountStatusError 非 2xx 响应转换为错误
+func accountStatusError(res *httpclient.Response) error {
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return nil
+	}
+	if msg := res.ToJson("serviceErrors.0.message").String(); msg != "" {
+		return errors.New(msg)
+	}
+	return fmt.Errorf("%s 请求失败 状态码：%v", res.Request.URL.String(), res.Status)
+}
+func (a *AuthSession) accountHttp() *httpclient.HttpClient {
+	return a.http().WithHeaders(map[string]string{
+		"Origin":  "https://appleid.apple.com",
+		"Referer": "https://appleid.apple.com/",
+	})
+}
+
+// extractAccountHeader appleid 每次响应都会轮换 scnt 空值不覆盖
+func (a *AuthSession) extractAccountHeader(res *httpclient.Response) {
+	if a.Header == nil {
+		a.Header = make(map[string]string)
+	}
+	for _, s := range []string{"X-Apple-ID-Session-Id", "scnt"} {
+		if v := res.Header.Get(s); v != "" {
+			a.Header[s] = v
+		}
+	}
+}
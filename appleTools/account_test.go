@@ -0,0 +1,143 @@
+package appleTools
+
+import (
+	"encoding/json"
+	"github.com/tidwall/gjson"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// replayStep 录制的一次请求响应
+type replayStep struct {
+	Method string            `json:"method"`
+	Path   string            `json:"path"`
+	Status int               `json:"status"`
+	Header map[string]string `json:"header"`
+	Body   json.RawMessage   `json:"body"`
+	Expect map[string]string `json:"expect"` // header.X / body.gjson路径 => 期望值
+}
+
+// newReplayServer 按顺序回放 testdata 中录制的响应 并校验请求
+func newReplayServer(t *testing.T, name string) *httptest.Server {
+	t.Helper()
+	buf, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var steps []replayStep
+	if err = json.Unmarshal(buf, &steps); err != nil {
+		t.Fatal(err)
+	}
+	var i int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if i >= len(steps) {
+			t.Errorf("多余的请求 %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(500)
+			return
+		}
+		step := steps[i]
+		i++
+		if r.Method != step.Method || r.URL.Path != step.Path {
+			t.Errorf("第%d步 期望 %s %s 实际 %s %s", i, step.Method, step.Path, r.Method, r.URL.Path)
+		}
+		body, _ := io.ReadAll(r.Body)
+		for k, want := range step.Expect {
+			var got string
+			switch {
+			case strings.HasPrefix(k, "header."):
+				got = r.Header.Get(strings.TrimPrefix(k, "header."))
+			case strings.HasPrefix(k, "body."):
+				got = gjson.GetBytes(body, strings.TrimPrefix(k, "body.")).String()
+			}
+			if !strings.Contains(got, want) {
+				t.Errorf("第%d步 %s 期望 %q 实际 %q", i, k, want, got)
+			}
+		}
+		for k, v := range step.Header {
+			w.Header().Add(k, v)
+		}
+		w.Header().Set("Content-Type", jsonContentType)
+		w.WriteHeader(step.Status)
+		if len(step.Body) > 0 {
+			w.Write(step.Body)
+		}
+	}))
+	t.Cleanup(func() {
+		srv.Close()
+		if i != len(steps) {
+			t.Errorf("回放未完成 %d/%d", i, len(steps))
+		}
+	})
+	return srv
+}
+
+func TestAuthSession_Account(t *testing.T) {
+	srv := newReplayServer(t, "account_replay.json")
+
+	a := &Auth{Account: "test@example.com"}
+	a.SetBaseUrl(srv.URL)
+	s := &AuthSession{Auth: a}
+	phones, err := s.TrustedPhones()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(phones) != 2 || phones[0].ID != 1 || phones[1].PushMode != "voice" || phones[1].CountryDialCode != "1" {
+		t.Fatalf("电话号码解析错误 %+v", phones)
+	}
+	v, err := s.AddTrustedPhone("CN", "86", "13800000000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Phone.ID != 3 {
+		t.Fatalf("验证会话ID错误 %+v", v.Phone)
+	}
+	phone, err := s.VerifyTrustedPhone(v, "123456")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !phone.Vetted || phone.NumberWithDialCode != "+86 138 0000 0000" {
+		t.Fatalf("验证结果错误 %+v", phone)
+	}
+	if err = s.RemoveTrustedPhone(2); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := s.AppPasswords()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Label != "transporter" {
+		t.Fatalf("App专用密码解析错误 %+v", list)
+	}
+	p, err := s.CreateAppPassword("ci")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.ID != 12 || p.Password != "abcd-efgh-ijkl-mnop" {
+		t.Fatalf("App专用密码创建错误 %+v", p)
+	}
+	if err = s.RevokeAppPassword(p.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.RevokeAppPassword(99); err == nil || err.Error() != "找不到该App专用密码" {
+		t.Fatalf("期望返回服务端错误 实际 %v", err)
+	}
+	if _, err = s.CreateAppPassword(""); err == nil {
+		t.Fatal("空标签应返回错误")
+	}
+
+	// 管理会话过期后重新获取
+	if _, err = s.AppPasswords(); err == nil {
+		t.Fatal("401 应返回错误")
+	}
+	if list, err = s.AppPasswords(); err != nil || len(list) != 0 {
+		t.Fatalf("会话过期后应重新获取 %v %v", list, err)
+	}
+	if p, err = s.CreateAppPassword("ci"); err == nil {
+		t.Fatalf("未返回密码时应返回错误 %+v", p)
+	}
+}
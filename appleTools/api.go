@@ -1,25 +1,16 @@
 package appleTools
 
 import (
-	"crypto/ecdsa"
-	"crypto/x509"
-	"encoding/base64"
-	"encoding/pem"
+	"context"
 	"errors"
 	"fmt"
-	"github.com/dgrijalva/jwt-go"
 	"github.com/xml520/wqutils/httpclient"
-	"log"
 	"strings"
-	"time"
 )
 
 var apiClient *httpclient.HttpClient
 
-const (
-	tokenExpire = 1000
-	apiBaseurl  = "https://api.appstoreconnect.apple.com/v1/"
-)
+const apiBaseurl = "https://api.appstoreconnect.apple.com/v1/"
 
 //func init() {
 //	apiClient = httpclient.NewHttpClient().Defaults(map[interface{}]interface{}{
@@ -42,9 +33,24 @@ const (
 //}
 
 type Api struct {
-	IssuerID string `json:"issuer_id" gorm:"index;comment:IssuerID"`
-	ApiID    string `json:"api_id" gorm:"index;comment:ApiID"`
-	ApiKey   string `json:"api_key" gorm:"type:text;comment:ApiKey"`
+	IssuerID string   `json:"issuer_id" gorm:"index;comment:IssuerID"`
+	ApiID    string   `json:"api_id" gorm:"index;comment:ApiID"`
+	ApiKey   string   `json:"api_key" gorm:"type:text;comment:ApiKey"`
+	pool     *ApiPool // ApiPool.Api 返回的 Api 请求交给密钥池
+	baseUrl  string
+}
+
+// SetBaseUrl 设置接口域名 如 https://api.appstoreconnect.apple.com 用于代理或 appleTest.ConnectServer
+func (a *Api) SetBaseUrl(u string) {
+	a.baseUrl = strings.TrimSuffix(u, "/")
+}
+
+// BaseUrl v1 接口地址 以 / 结尾
+func (a *Api) BaseUrl() string {
+	if a.baseUrl != "" {
+		return a.baseUrl + "/v1/"
+	}
+	return apiBaseurl
 }
 
 func newApiClient() *httpclient.HttpClient {
@@ -58,65 +64,32 @@ func newApiClient() *httpclient.HttpClient {
 			if res.StatusCode < 299 {
 				return nil
 			}
-			if msg := res.ToJson("errors.0.detail").String(); msg != "" {
-				return errors.New(msg)
-			}
-			return errors.New(fmt.Sprintf("%s 未知错误 状态码：%v", res.Request.URL.String(), res.Status))
+			return decodeApiErrors(res)
 		},
 		httpclient.OPT_TIMEOUT: 30,
 	})
 }
 
 func (a *Api) Do(method, url string, data any) (*httpclient.Response, error) {
-	token, err := a.generateToken(tokenExpire)
-	if err != nil {
-		return nil, err
+	if a.pool != nil {
+		return a.pool.Do(method, url, data)
 	}
 	if strings.ToTitle(method) == "GET" {
 		data = ""
 	}
-	return newApiClient().WithHeader("Authorization", "Bearer "+token).Json(method, apiBaseurl+url, data)
+	// httpclient 每次请求后会清除 token 等一次性请求头 重试时需要重新创建
+	return a.RateLimiter().do(context.Background(), func() (*httpclient.Response, error) {
+		client, err := a.http()
+		if err != nil {
+			return nil, err
+		}
+		return client.Json(method, a.BaseUrl()+url, data)
+	})
 }
-func (a *Api) http() *httpclient.HttpClient {
-	token, err := a.generateToken(tokenExpire)
+func (a *Api) http() (*httpclient.HttpClient, error) {
+	token, err := a.Token()
 	if err != nil {
-		log.Println("token 生成失败", err)
-	}
-	return newApiClient().WithHeader("Authorization", "Bearer "+token)
-}
-func (a *Api) generateToken(expire int64) (string, error) {
-	expires := time.Now().Unix() + expire // 19分钟有效期
-	token := jwt.Token{
-		Header: map[string]interface{}{
-			"typ": "JWT",
-			"alg": "ES256",
-			"kid": a.ApiID,
-		},
-		Claims: jwt.StandardClaims{
-			Audience:  "appstoreconnect-v1",
-			Issuer:    a.IssuerID,
-			ExpiresAt: expires,
-		},
-		Method: jwt.SigningMethodES256,
-	}
-	var pk *ecdsa.PrivateKey
-	if rawByte, err := base64.StdEncoding.DecodeString(a.ApiKey); err != nil {
-		return "", err
-	} else {
-		if block, _ := pem.Decode(rawByte); block == nil {
-			return "", errors.New("密钥文件不是pem格式")
-		} else {
-			if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err != nil {
-				return "", err
-			} else {
-				switch key.(type) {
-				case *ecdsa.PrivateKey:
-					pk = key.(*ecdsa.PrivateKey)
-				default:
-					return "", errors.New("AuthKey的类型必须为ecdsa.PrivateKey")
-				}
-			}
-		}
+		return nil, fmt.Errorf("token 生成失败 %s", err)
 	}
-	return token.SignedString(pk)
+	return newApiClient().WithHeader("Authorization", "Bearer "+token), nil
 }
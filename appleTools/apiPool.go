@@ -0,0 +1,268 @@
+package appleTools
+
+import (
+	"context"
+	"errors"
+	"github.com/xml520/wqutils/httpclient"
+	"math"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	apiPoolMaxFailures = 3           // 连续失败次数达到后暂停使用
+	apiPoolCooldown    = time.Minute // 暂停时长
+)
+
+// ErrNoApiKey 密钥池中没有可用的密钥
+var ErrNoApiKey = errors.New("没有可用的 Api 密钥")
+
+// ApiPool 多密钥池 按剩余额度和健康状况分配请求 返回 401 的密钥会被停用
+//
+//	pool := NewApiPool(keys...)
+//	doc, err := pool.Api().ListApps(nil)
+type ApiPool struct {
+	mu    sync.Mutex
+	keys  []*apiPoolKey
+	proxy *Api
+	now   func() time.Time
+}
+type apiPoolKey struct {
+	api      *Api
+	disabled error
+	failures int
+	cooldown time.Time
+	lastUsed time.Time
+}
+
+// ApiPoolKeyStatus 密钥状态 用于监控
+type ApiPoolKeyStatus struct {
+	IssuerID  string
+	ApiID     string
+	Disabled  bool
+	Reason    string // 停用原因
+	Failures  int    // 连续失败次数
+	CoolingTo time.Time
+	Quota     RateLimit
+	Stats     RateLimitStats
+}
+
+// NewApiPool 创建密钥池
+func NewApiPool(apis ...*Api) *ApiPool {
+	p := &ApiPool{now: time.Now}
+	p.proxy = &Api{pool: p}
+	for _, a := range apis {
+		p.Add(a)
+	}
+	return p
+}
+
+// Add 添加密钥 重复的密钥会被忽略
+func (p *ApiPool) Add(a *Api) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, k := range p.keys {
+		if k.api.cacheKey() == a.cacheKey() {
+			return
+		}
+	}
+	p.keys = append(p.keys, &apiPoolKey{api: a})
+}
+
+// Enable 重新启用密钥 同时清除失败记录
+func (p *ApiPool) Enable(apiID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, k := range p.keys {
+		if k.api.ApiID == apiID {
+			k.disabled, k.failures, k.cooldown = nil, 0, time.Time{}
+		}
+	}
+}
+
+// Remove 移除密钥 同时释放该密钥缓存的 token 和限流器
+func (p *ApiPool) Remove(apiID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	keys := p.keys[:0]
+	for _, k := range p.keys {
+		if k.api.ApiID == apiID {
+			k.api.releaseCache()
+			continue
+		}
+		keys = append(keys, k)
+	}
+	p.keys = keys
+}
+
+// Status 全部密钥的状态 停用的密钥没有额度数据
+func (p *ApiPool) Status() []ApiPoolKeyStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	list := make([]ApiPoolKeyStatus, 0, len(p.keys))
+	for _, k := range p.keys {
+		s := ApiPoolKeyStatus{
+			IssuerID:  k.api.IssuerID,
+			ApiID:     k.api.ApiID,
+			Disabled:  k.disabled != nil,
+			Failures:  k.failures,
+			CoolingTo: k.cooldown,
+		}
+		if k.disabled != nil {
+			s.Reason = k.disabled.Error()
+		} else {
+			s.Stats = k.api.RateLimitStats()
+			s.Quota = s.Stats.Quota
+		}
+		list = append(list, s)
+	}
+	return list
+}
+
+// Api 返回通过密钥池发送请求的 Api 可以直接调用 Api 的全部接口方法
+func (p *ApiPool) Api() *Api {
+	return p.proxy
+}
+
+// Pick 选择当前最合适的密钥
+func (p *ApiPool) Pick() (*Api, error) {
+	k, err := p.pick(nil)
+	if err != nil {
+		return nil, err
+	}
+	return k.api, nil
+}
+
+// Do 同 Api.Do
+func (p *ApiPool) Do(method, url string, data any) (*httpclient.Response, error) {
+	var res *httpclient.Response
+	err := p.Call(func(a *Api) (err error) {
+		res, err = a.Do(method, url, data)
+		return err
+	})
+	return res, err
+}
+
+// Call 使用选中的密钥执行 fn
+// 返回 401 时先重新签发 token 再试一次 仍为 401 才停用密钥
+// 401 和 429 只有 GET DELETE 请求会换下一个密钥重试 避免重复创建资源
+func (p *ApiPool) Call(fn func(a *Api) error) error {
+	tried := map[*apiPoolKey]bool{}
+	var lastErr error
+	for {
+		k, err := p.pick(tried)
+		if err != nil {
+			if lastErr != nil {
+				return lastErr
+			}
+			return err
+		}
+		tried[k] = true
+		lastErr = fn(k.api)
+		if IsApiStatus(lastErr, 401) {
+			// 401 时请求尚未执行 用新 token 重试是安全的
+			if tp, err := k.api.tokenProvider(); err == nil {
+				tp.Clear()
+			}
+			lastErr = fn(k.api)
+		}
+		if !p.report(k, lastErr) {
+			return lastErr
+		}
+	}
+}
+
+// PoolCall 带返回值的 Call
+//
+//	doc, err := PoolCall(pool, func(a *Api) (*ApiDocument[[]App], error) { return a.ListApps(nil) })
+func PoolCall[T any](p *ApiPool, fn func(a *Api) (T, error)) (T, error) {
+	var v T
+	err := p.Call(func(a *Api) (err error) {
+		v, err = fn(a)
+		return err
+	})
+	return v, err
+}
+
+// pick 跳过停用和 exclude 中的密钥 优先选择未暂停且剩余额度最多的 相同时选最久未使用的
+func (p *ApiPool) pick(exclude map[*apiPoolKey]bool) (*apiPoolKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.now()
+	var (
+		best      *apiPoolKey
+		bestScore int
+		bestCool  bool
+	)
+	for _, k := range p.keys {
+		if k.disabled != nil || exclude[k] {
+			continue
+		}
+		cool := now.Before(k.cooldown)
+		score := math.MaxInt32 // 没有额度数据的密钥优先 以便尽快拿到额度
+		if q := k.api.RateLimit(); q.Limit > 0 && now.Sub(q.UpdatedAt) < rateLimitWindow {
+			score = q.Remaining
+		}
+		switch {
+		case best == nil,
+			bestCool && !cool,
+			bestCool == cool && score > bestScore,
+			bestCool == cool && score == bestScore && k.lastUsed.Before(best.lastUsed):
+			best, bestScore, bestCool = k, score, cool
+		}
+	}
+	if best == nil {
+		return nil, ErrNoApiKey
+	}
+	best.lastUsed = now
+	return best, nil
+}
+
+// report 记录请求结果 返回是否应该换密钥重试
+func (p *ApiPool) report(k *apiPoolKey, err error) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var e *ApiErrors
+	switch {
+	case err == nil:
+		k.failures, k.cooldown = 0, time.Time{}
+	case errors.As(err, &e) && e.StatusCode == 401:
+		k.disabled = err
+		k.api.releaseCache()
+		return idempotent(e.Method)
+	case errors.As(err, &e) && e.StatusCode == 429:
+		return idempotent(e.Method)
+	case errors.As(err, &e) && e.StatusCode < 500:
+		// 参数错误等与密钥无关
+		k.failures = 0
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+	default:
+		// 网络错误或 5xx
+		if k.failures++; k.failures >= apiPoolMaxFailures {
+			k.cooldown = p.now().Add(apiPoolCooldown)
+		}
+	}
+	return false
+}
+
+// idempotent 重复发送不会产生副作用的请求
+func idempotent(method string) bool {
+	switch strings.ToUpper(method) {
+	case "GET", "HEAD", "DELETE":
+		return true
+	}
+	return false
+}
+
+func (p *ApiPool) request(ctx context.Context, method, path string, query url.Values, body any, out any) error {
+	return p.Call(func(a *Api) error {
+		return a.request(ctx, method, path, query, body, out)
+	})
+}
+func (p *ApiPool) download(ctx context.Context, path string, query url.Values) ([]byte, error) {
+	return PoolCall(p, func(a *Api) ([]byte, error) {
+		return a.download(ctx, path, query)
+	})
+}
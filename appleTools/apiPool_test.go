@@ -0,0 +1,168 @@
+package appleTools
+
+import (
+	"context"
+	"github.com/dgrijalva/jwt-go"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestApiPool(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		calls = map[string]int{}
+		quota = map[string]string{"K1": "user-hour-lim:3600;user-hour-rem:100;", "K2": "user-hour-lim:3600;user-hour-rem:3000;"}
+	)
+	first := newTestApi(t, func(w http.ResponseWriter, r *http.Request) {
+		claims := jwt.MapClaims{}
+		token, _, err := new(jwt.Parser).ParseUnverified(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), claims)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		kid := token.Header["kid"].(string)
+		mu.Lock()
+		calls[kid]++
+		n := calls[kid]
+		mu.Unlock()
+		switch {
+		case kid == "FLAKY" && n == 1:
+			writeTestJson(w, 401, `{"errors":[{"status":"401","detail":"expired"}]}`)
+			return
+		case kid == "BUSY" && n == 1:
+			writeTestJson(w, 429, `{"errors":[{"status":"429","detail":"busy"}]}`)
+			return
+		}
+		switch kid {
+		case "REVOKED":
+			writeTestJson(w, 401, `{"errors":[{"status":"401","code":"NOT_AUTHORIZED","detail":"revoked"}]}`)
+		case "DOWN":
+			writeTestJson(w, 503, `{"errors":[{"status":"503","detail":"down"}]}`)
+		case "LIMIT":
+			writeTestJson(w, 429, `{"errors":[{"status":"429","detail":"limit"}]}`)
+		default:
+			w.Header().Set("X-Rate-Limit", quota[kid])
+			if r.URL.Path == "/v1/apps/missing" {
+				writeTestJson(w, 404, `{"errors":[{"status":"404","detail":"not found"}]}`)
+				return
+			}
+			writeTestJson(w, 200, `{"data":[{"type":"apps","id":"`+kid+`","attributes":{"name":"Demo"}}]}`)
+		}
+	})
+	key := func(id string) *Api {
+		a := &Api{IssuerID: first.IssuerID, ApiID: id, ApiKey: newTestApiKey(t)}
+		a.SetBaseUrl(first.baseUrl)
+		return a
+	}
+	k1, k2, revoked := key("K1"), key("K2"), key("REVOKED")
+	pool := NewApiPool(revoked, k1, k2)
+	pool.Add(k1)
+	if len(pool.Status()) != 3 {
+		t.Fatalf("重复密钥应忽略 %+v", pool.Status())
+	}
+
+	// 没有额度数据时先用 REVOKED 重新签发 token 仍返回 401 后停用并换下一个密钥
+	doc, err := pool.Api().ListApps(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls["REVOKED"] != 2 || len(doc.Data) != 1 {
+		t.Errorf("401 后应换密钥 %v %v", calls, doc.Data)
+	}
+	// 另一个没有额度数据的密钥优先 之后选剩余额度多的 K2
+	for i := 0; i < 3; i++ {
+		if _, err = pool.Api().ListApps(nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls["REVOKED"] != 2 || calls["K1"] != 1 || calls["K2"] != 3 {
+		t.Errorf("分配错误 %v", calls)
+	}
+	st := pool.Status()
+	if !st[0].Disabled || st[0].Reason != "revoked" || st[2].Quota.Remaining != 3000 {
+		t.Errorf("状态错误 %+v", st)
+	}
+	// 停用和移除的密钥释放缓存
+	if _, ok := apiTokenProviders.Load(revoked.cacheKey()); ok {
+		t.Error("停用的密钥应释放 token 签发器")
+	}
+	if _, ok := apiRateLimiters.Load(revoked.cacheKey()); ok {
+		t.Error("停用的密钥应释放限流器")
+	}
+	pool.Remove("K2")
+	_, hasToken := apiTokenProviders.Load(k2.cacheKey())
+	_, hasLimiter := apiRateLimiters.Load(k2.cacheKey())
+	if st = pool.Status(); len(st) != 2 || st[1].ApiID != "K1" || hasToken || hasLimiter {
+		t.Errorf("移除密钥错误 %+v %v %v", st, hasToken, hasLimiter)
+	}
+	pool.Add(k2)
+
+	// 与密钥无关的错误直接返回 不换密钥
+	if _, err = pool.Api().GetApp("missing", nil); !IsApiStatus(err, 404) || calls["K1"]+calls["K2"] != 5 {
+		t.Errorf("404 应直接返回 %v %v", err, calls)
+	}
+
+	// 连续失败的密钥暂停使用
+	down := key("DOWN")
+	pool = NewApiPool(down)
+	now := time.Now()
+	pool.now = func() time.Time { return now }
+	for i := 0; i < apiPoolMaxFailures; i++ {
+		if _, err = pool.Do("GET", "apps", nil); !IsApiStatus(err, 503) {
+			t.Fatalf("应返回 503 %v", err)
+		}
+	}
+	pool.Add(k1)
+	if a, _ := pool.Pick(); a != k1 {
+		t.Errorf("暂停中的密钥不应被选中 %v", a.ApiID)
+	}
+	now = now.Add(apiPoolCooldown)
+	pool.Enable("DOWN")
+	if st = pool.Status(); st[0].Failures != 0 || !st[0].CoolingTo.IsZero() {
+		t.Errorf("启用后应清除失败记录 %+v", st[0])
+	}
+
+	// 全部停用
+	pool = NewApiPool(revoked)
+	if _, err = PoolCall(pool, func(a *Api) (*ApiDocument[[]App], error) { return a.ListApps(nil) }); !IsApiStatus(err, 401) {
+		t.Errorf("应返回最后一个错误 %v", err)
+	}
+	if _, err = pool.Pick(); err != ErrNoApiKey {
+		t.Errorf("应没有可用密钥 %v", err)
+	}
+
+	// 偶发 401 重新签发 token 后成功 不停用
+	pool = NewApiPool(key("FLAKY"))
+	if _, err = pool.Do("GET", "apps", nil); err != nil {
+		t.Fatal(err)
+	}
+	if st = pool.Status(); calls["FLAKY"] != 2 || st[0].Disabled {
+		t.Errorf("偶发 401 不应停用密钥 %v %+v", calls, st[0])
+	}
+
+	// 429 重试成功 不停用
+	busy := key("BUSY")
+	busy.RateLimiter().sleep = func(ctx context.Context, d time.Duration) error { return nil }
+	pool = NewApiPool(busy)
+	if _, err = pool.Do("GET", "apps", nil); err != nil {
+		t.Fatal(err)
+	}
+	if st = pool.Status(); calls["BUSY"] != 2 || st[0].Disabled || st[0].Failures != 0 {
+		t.Errorf("429 后成功不应停用密钥 %v %+v", calls, st[0])
+	}
+
+	// 非幂等请求 429 后不换密钥 避免重复创建
+	limit := key("LIMIT")
+	limit.RateLimiter().SetRetry(0, 0)
+	pool = NewApiPool(limit, k1)
+	k1Calls := calls["K1"]
+	if _, err = pool.Do("POST", "apps", `{}`); !IsApiStatus(err, 429) || calls["K1"] != k1Calls {
+		t.Errorf("POST 不应换密钥重试 %v %v", err, calls)
+	}
+	if _, err = pool.Do("GET", "apps", nil); err != nil || calls["K1"] != k1Calls+1 {
+		t.Errorf("GET 应换密钥重试 %v %v", err, calls)
+	}
+}
@@ -0,0 +1,198 @@
+package appleTools
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"github.com/dgrijalva/jwt-go"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	apiTokenAudience = "appstoreconnect-v1"
+	apiTokenExpire   = 19 * time.Minute // 苹果要求不超过20分钟
+	apiTokenRefresh  = time.Minute      // 剩余时间少于该值时重新签发
+)
+
+// apiTokenProviders 按密钥缓存 Api 的 token 签发器 Api 被复制也能共用
+var apiTokenProviders sync.Map
+
+// ApiTokenProvider App Store Connect token 签发器 密钥只解析一次 token 缓存到快过期
+type ApiTokenProvider struct {
+	issuerID string // 为空时签发个人密钥 token
+	keyID    string
+	key      *ecdsa.PrivateKey
+	expire   time.Duration
+	now      func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]apiToken
+}
+type apiToken struct {
+	value  string
+	expire time.Time
+}
+
+// ParseApiKey 解析 .p8 密钥 支持原始PEM 或 PEM 的 base64
+func ParseApiKey(key string) (*ecdsa.PrivateKey, error) {
+	raw := []byte(strings.TrimSpace(key))
+	if len(raw) == 0 {
+		return nil, errors.New("ApiKey 不能为空")
+	}
+	if !strings.Contains(string(raw), "-----BEGIN") {
+		var err error
+		if raw, err = base64.StdEncoding.DecodeString(string(raw)); err != nil {
+			return nil, errors.New("ApiKey 既不是PEM也不是base64: " + err.Error())
+		}
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.New("密钥文件不是pem格式")
+	}
+	key_, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	pk, ok := key_.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("AuthKey的类型必须为ecdsa.PrivateKey")
+	}
+	if pk.Curve != elliptic.P256() {
+		return nil, errors.New("AuthKey必须为P-256曲线")
+	}
+	return pk, nil
+}
+
+// NewApiTokenProvider 创建签发器 issuerID 为空表示个人密钥
+func NewApiTokenProvider(issuerID, keyID, key string) (*ApiTokenProvider, error) {
+	if keyID == "" {
+		return nil, errors.New("ApiID 不能为空")
+	}
+	pk, err := ParseApiKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return &ApiTokenProvider{
+		issuerID: issuerID,
+		keyID:    keyID,
+		key:      pk,
+		expire:   apiTokenExpire,
+		now:      time.Now,
+		cache:    make(map[string]apiToken),
+	}, nil
+}
+
+// SetExpire 设置 token 有效期 最长20分钟
+func (p *ApiTokenProvider) SetExpire(d time.Duration) {
+	if d > 20*time.Minute {
+		d = 20 * time.Minute
+	}
+	p.mu.Lock()
+	p.expire = d
+	p.cache = make(map[string]apiToken)
+	p.mu.Unlock()
+}
+
+// Token 获取 token scope 形如 "GET /v1/apps" 为空时不限制
+func (p *ApiTokenProvider) Token(scope ...string) (string, error) {
+	cacheKey := strings.Join(scope, "\n")
+	now := p.now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if t, ok := p.cache[cacheKey]; ok && t.expire.Sub(now) > apiTokenRefresh {
+		return t.value, nil
+	}
+	expire := now.Add(p.expire)
+	value, err := p.sign(jwt.MapClaims{"aud": apiTokenAudience, "iat": now.Unix(), "exp": expire.Unix()}, scope)
+	if err != nil {
+		return "", err
+	}
+	p.cache[cacheKey] = apiToken{value: value, expire: expire}
+	return value, nil
+}
+
+// BundleToken 获取 App Store Server API token 需要使用内购密钥 声明中包含 bid
+func (p *ApiTokenProvider) BundleToken(bundleID string) (string, error) {
+	if bundleID == "" {
+		return "", errors.New("BundleID 不能为空")
+	}
+	cacheKey := "bid\n" + bundleID
+	now := p.now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if t, ok := p.cache[cacheKey]; ok && t.expire.Sub(now) > apiTokenRefresh {
+		return t.value, nil
+	}
+	expire := now.Add(p.expire)
+	value, err := p.sign(jwt.MapClaims{"aud": apiTokenAudience, "iat": now.Unix(), "exp": expire.Unix(), "bid": bundleID}, nil)
+	if err != nil {
+		return "", err
+	}
+	p.cache[cacheKey] = apiToken{value: value, expire: expire}
+	return value, nil
+}
+
+// Clear 清除缓存的 token 下次请求重新签发
+func (p *ApiTokenProvider) Clear() {
+	p.mu.Lock()
+	p.cache = make(map[string]apiToken)
+	p.mu.Unlock()
+}
+
+// sign 使用 ES256 签名 补充团队或个人密钥所需的声明
+func (p *ApiTokenProvider) sign(claims jwt.MapClaims, scope []string) (string, error) {
+	if p.issuerID != "" {
+		claims["iss"] = p.issuerID
+	} else {
+		claims["sub"] = "user"
+	}
+	if len(scope) > 0 {
+		claims["scope"] = scope
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["kid"] = p.keyID
+	return token.SignedString(p.key)
+}
+
+// tokenProvider 取缓存的签发器 密钥变化时重新解析
+func (a *Api) tokenProvider() (*ApiTokenProvider, error) {
+	cacheKey := a.cacheKey()
+	if p, ok := apiTokenProviders.Load(cacheKey); ok {
+		return p.(*ApiTokenProvider), nil
+	}
+	p, err := NewApiTokenProvider(a.IssuerID, a.ApiID, a.ApiKey)
+	if err != nil {
+		return nil, err
+	}
+	actual, _ := apiTokenProviders.LoadOrStore(cacheKey, p)
+	return actual.(*ApiTokenProvider), nil
+}
+
+// cacheKey 密钥的唯一标识
+func (a *Api) cacheKey() string {
+	sum := sha256.Sum256([]byte(a.ApiKey))
+	return a.IssuerID + "/" + a.ApiID + "/" + hex.EncodeToString(sum[:])
+}
+
+// releaseCache 删除密钥缓存的签发器和限流器 密钥停用或移除时调用 避免缓存一直增长
+func (a *Api) releaseCache() {
+	key := a.cacheKey()
+	apiTokenProviders.Delete(key)
+	apiRateLimiters.Delete(key)
+}
+
+// Token 获取 App Store Connect token
+func (a *Api) Token(scope ...string) (string, error) {
+	p, err := a.tokenProvider()
+	if err != nil {
+		return "", err
+	}
+	return p.Token(scope...)
+}
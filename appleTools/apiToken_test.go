@@ -0,0 +1,122 @@
+package appleTools
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"github.com/dgrijalva/jwt-go"
+	"testing"
+	"time"
+)
+
+func testPem(t *testing.T, key any) string {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+}
+
+func TestParseApiKey(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	raw := testPem(t, key)
+	for _, s := range []string{raw, "\n" + raw + "\n", base64.StdEncoding.EncodeToString([]byte(raw))} {
+		pk, err := ParseApiKey(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !pk.Equal(key) {
+			t.Fatal("解析出的密钥不一致")
+		}
+	}
+	p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 1024)
+	for name, s := range map[string]string{
+		"空":     "",
+		"乱码":    "not a key",
+		"P-384": testPem(t, p384),
+		"RSA":   testPem(t, rsaKey),
+	} {
+		if _, err := ParseApiKey(s); err == nil {
+			t.Errorf("%s 应返回错误", name)
+		}
+	}
+}
+
+func TestApiTokenProvider(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	p, err := NewApiTokenProvider("issuer", "KEY123", testPem(t, key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	p.now = func() time.Time { return now }
+
+	t1, err := p.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t2, _ := p.Token()
+	if t1 != t2 {
+		t.Fatal("有效期内应返回缓存的 token")
+	}
+	claims := parseTestToken(t, t1, key)
+	if claims["iss"] != "issuer" || claims["aud"] != apiTokenAudience || int64(claims["exp"].(float64)) != now.Add(apiTokenExpire).Unix() {
+		t.Fatalf("声明错误 %+v", claims)
+	}
+
+	scoped, _ := p.Token("GET /v1/apps")
+	if scoped == t1 {
+		t.Fatal("不同 scope 不应共用 token")
+	}
+	if scope := parseTestToken(t, scoped, key)["scope"].([]any); len(scope) != 1 || scope[0] != "GET /v1/apps" {
+		t.Fatalf("scope 错误 %+v", scope)
+	}
+
+	now = now.Add(apiTokenExpire - apiTokenRefresh + time.Second)
+	if t3, _ := p.Token(); t3 == t1 {
+		t.Fatal("快过期时应重新签发")
+	}
+
+	individual, err := NewApiTokenProvider("", "KEY123", base64.StdEncoding.EncodeToString([]byte(testPem(t, key))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, _ := individual.Token()
+	claims = parseTestToken(t, token, key)
+	if claims["sub"] != "user" || claims["iss"] != nil {
+		t.Fatalf("个人密钥声明错误 %+v", claims)
+	}
+	if _, err = NewApiTokenProvider("issuer", "", testPem(t, key)); err == nil {
+		t.Fatal("缺少 ApiID 应返回错误")
+	}
+}
+
+func TestApi_TokenError(t *testing.T) {
+	api := &Api{IssuerID: "issuer", ApiID: "KEY", ApiKey: "bad"}
+	if _, err := api.Do("GET", "apps", nil); err == nil {
+		t.Fatal("密钥错误时应返回错误而不是继续请求")
+	}
+	if _, err := api.ListApps(nil); err == nil {
+		t.Fatal("密钥错误时应返回错误而不是继续请求")
+	}
+}
+
+func parseTestToken(t *testing.T, token string, key *ecdsa.PrivateKey) jwt.MapClaims {
+	t.Helper()
+	parsed, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
+		if token.Header["kid"] != "KEY123" {
+			t.Errorf("kid 错误 %v", token.Header["kid"])
+		}
+		return &key.PublicKey, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed.Claims.(jwt.MapClaims)
+}
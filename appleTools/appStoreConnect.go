@@ -1 +1,294 @@
 package appleTools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/xml520/wqutils/httpclient"
+	"net/url"
+	"strings"
+)
+
+// ApiResource App Store Connect JSON:API 资源
+type ApiResource[T any] struct {
+	Type          string                      `json:"type"`
+	ID            string                      `json:"id,omitempty"`
+	Attributes    T                           `json:"attributes"`
+	Relationships map[string]*ApiRelationship `json:"relationships,omitempty"`
+	Links         *ApiLinks                   `json:"links,omitempty"`
+}
+
+// ApiIncluded include 返回的资源 属性按需用 DecodeIncluded 解析
+type ApiIncluded = ApiResource[json.RawMessage]
+
+// ApiLinkage 资源标识
+type ApiLinkage struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// ApiRelationship 资源关系 data 可能是单个资源也可能是数组
+type ApiRelationship struct {
+	Data  json.RawMessage `json:"data,omitempty"`
+	Links *ApiLinks       `json:"links,omitempty"`
+	Meta  *ApiMeta        `json:"meta,omitempty"` // include 时被 limit 截断的关系 paging.total 为总数
+}
+
+// ApiLinks 资源链接
+type ApiLinks struct {
+	Self    string `json:"self,omitempty"`
+	Related string `json:"related,omitempty"`
+	First   string `json:"first,omitempty"`
+	Next    string `json:"next,omitempty"`
+}
+
+// ApiPaging 分页信息
+type ApiPaging struct {
+	Total int `json:"total"`
+	Limit int `json:"limit"`
+}
+
+// ApiMeta 响应元信息
+type ApiMeta struct {
+	Paging ApiPaging `json:"paging"`
+}
+
+// ApiDocument JSON:API 响应文档
+type ApiDocument[T any] struct {
+	Data     T             `json:"data"`
+	Included []ApiIncluded `json:"included,omitempty"`
+	Links    ApiLinks      `json:"links"`
+	Meta     ApiMeta       `json:"meta"`
+}
+
+type apiRequest[T any] struct {
+	Data T `json:"data"`
+}
+
+// ApiErrorSource 出错的字段
+type ApiErrorSource struct {
+	Pointer   string `json:"pointer,omitempty"`
+	Parameter string `json:"parameter,omitempty"`
+}
+
+// ApiError errors[] 数组中的一项
+type ApiError struct {
+	ID     string          `json:"id"`
+	Status string          `json:"status"`
+	Code   string          `json:"code"`
+	Title  string          `json:"title"`
+	Detail string          `json:"detail"`
+	Source *ApiErrorSource `json:"source,omitempty"`
+}
+
+// ApiErrors App Store Connect 返回的错误 Error() 为第一条错误的 detail
+type ApiErrors struct {
+	StatusCode int
+	Method     string
+	Url        string
+	Errors     []ApiError
+}
+
+func (e *ApiErrors) Error() string {
+	for _, item := range e.Errors {
+		if item.Detail != "" {
+			return item.Detail
+		}
+		if item.Title != "" {
+			return item.Title
+		}
+	}
+	return fmt.Sprintf("%s 未知错误 状态码：%v", e.Url, e.StatusCode)
+}
+
+// HasCode 是否包含指定错误码 如 ENTITY_ERROR.ATTRIBUTE.INVALID
+func (e *ApiErrors) HasCode(code string) bool {
+	for _, item := range e.Errors {
+		if item.Code == code || strings.HasPrefix(item.Code, code+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// IsApiStatus 判断错误是否为指定状态码的 App Store Connect 错误
+func IsApiStatus(err error, status int) bool {
+	var e *ApiErrors
+	return errors.As(err, &e) && e.StatusCode == status
+}
+
+func decodeApiErrors(res *httpclient.Response) *ApiErrors {
+	e := &ApiErrors{StatusCode: res.StatusCode}
+	if res.Request != nil {
+		e.Method = res.Request.Method
+		e.Url = res.Request.URL.String()
+	}
+	if buf, err := res.ReadAll(); err == nil && len(buf) > 0 {
+		var body struct {
+			Errors []ApiError `json:"errors"`
+		}
+		if json.Unmarshal(buf, &body) == nil {
+			e.Errors = body.Errors
+		}
+	}
+	return e
+}
+
+// ToOne 构造单个资源的关系
+func ToOne(typ, id string) *ApiRelationship {
+	buf, _ := json.Marshal(ApiLinkage{Type: typ, ID: id})
+	return &ApiRelationship{Data: buf}
+}
+
+// ToMany 构造多个资源的关系
+func ToMany(typ string, ids ...string) *ApiRelationship {
+	list := make([]ApiLinkage, 0, len(ids))
+	for _, id := range ids {
+		list = append(list, ApiLinkage{Type: typ, ID: id})
+	}
+	buf, _ := json.Marshal(list)
+	return &ApiRelationship{Data: buf}
+}
+
+// One 单个资源关系 未返回 data 时为 nil
+func (r *ApiRelationship) One() *ApiLinkage {
+	if r == nil || len(r.Data) == 0 || r.Data[0] != '{' {
+		return nil
+	}
+	var l ApiLinkage
+	if json.Unmarshal(r.Data, &l) != nil {
+		return nil
+	}
+	return &l
+}
+
+// Many 多个资源关系
+func (r *ApiRelationship) Many() []ApiLinkage {
+	if r == nil || len(r.Data) == 0 || r.Data[0] != '[' {
+		return nil
+	}
+	var l []ApiLinkage
+	json.Unmarshal(r.Data, &l)
+	return l
+}
+
+// Truncated include 返回的关系被截断 需要另外分页获取
+func (r *ApiRelationship) Truncated() bool {
+	return r != nil && r.Meta != nil && r.Meta.Paging.Total > len(r.Many())
+}
+
+// Related 取资源关系 不存在时返回 nil
+func (r *ApiResource[T]) Related(name string) *ApiRelationship {
+	if r.Relationships == nil {
+		return nil
+	}
+	return r.Relationships[name]
+}
+
+// DecodeIncluded 从 included 中找到指定资源并解析属性
+func DecodeIncluded[T any](included []ApiIncluded, typ, id string) (*ApiResource[T], error) {
+	for _, inc := range included {
+		if inc.Type == typ && inc.ID == id {
+			return decodeIncluded[T](inc)
+		}
+	}
+	return nil, fmt.Errorf("included 中不存在 %s %s", typ, id)
+}
+
+// url 相对路径拼接 v1 地址 以 / 开头的拼接域名 完整地址原样返回
+func (a *Api) url(path string) string {
+	switch {
+	case strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://"):
+		return path
+	case strings.HasPrefix(path, "/") && a.baseUrl != "":
+		return a.baseUrl + path
+	case strings.HasPrefix(path, "/"):
+		u, _ := url.Parse(apiBaseurl)
+		return u.Scheme + "://" + u.Host + path
+	default:
+		return a.BaseUrl() + path
+	}
+}
+
+// request 发送请求并把响应解析到 out
+func (a *Api) request(ctx context.Context, method, path string, query url.Values, body any, out any) error {
+	if a.pool != nil {
+		return a.pool.request(ctx, method, path, query, body, out)
+	}
+	u := a.url(path)
+	if len(query) > 0 {
+		if strings.Contains(u, "?") {
+			u += "&" + query.Encode()
+		} else {
+			u += "?" + query.Encode()
+		}
+	}
+	if body == nil {
+		body = ""
+	}
+	// httpclient 每次请求后会清除 token 和 context 重试时需要重新创建
+	res, err := a.RateLimiter().do(ctx, func() (*httpclient.Response, error) {
+		client, err := a.http()
+		if err != nil {
+			return nil, err
+		}
+		if ctx != nil {
+			client.WithOption(httpclient.OPT_CONTEXT, ctx)
+		}
+		return client.Json(method, u, body)
+	})
+	if res != nil && res.Response != nil {
+		defer res.Body.Close()
+	}
+	if err != nil {
+		return err
+	}
+	if out == nil {
+		return nil
+	}
+	buf, err := res.ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(buf) == 0 {
+		return nil
+	}
+	return json.Unmarshal(buf, out)
+}
+
+func apiList[T any](a *Api, path string, query url.Values) (*ApiDocument[[]ApiResource[T]], error) {
+	var doc ApiDocument[[]ApiResource[T]]
+	if err := a.request(context.Background(), "GET", path, query, nil, &doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+func apiGet[T any](a *Api, path string, query url.Values) (*ApiDocument[ApiResource[T]], error) {
+	var doc ApiDocument[ApiResource[T]]
+	if err := a.request(context.Background(), "GET", path, query, nil, &doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// apiSave 创建(POST)或修改(PATCH)资源
+func apiSave[T any, R any](a *Api, method, path string, data R) (*ApiResource[T], error) {
+	var doc ApiDocument[ApiResource[T]]
+	if err := a.request(context.Background(), method, path, nil, apiRequest[R]{Data: data}, &doc); err != nil {
+		return nil, err
+	}
+	return &doc.Data, nil
+}
+func apiDelete(a *Api, path string) error {
+	return a.request(context.Background(), "DELETE", path, nil, nil, nil)
+}
+
+// apiLinkage 修改 to-many 关系 POST 添加 DELETE 移除 PATCH 替换
+func apiLinkage(a *Api, method, path string, typ string, ids ...string) error {
+	list := make([]ApiLinkage, 0, len(ids))
+	for _, id := range ids {
+		list = append(list, ApiLinkage{Type: typ, ID: id})
+	}
+	return a.request(context.Background(), method, path, nil, apiRequest[[]ApiLinkage]{Data: list}, nil)
+}
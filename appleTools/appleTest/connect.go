@@ -0,0 +1,970 @@
+package appleTest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+	"howett.net/plist"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	connectAudience  = "appstoreconnect-v1"
+	connectHourLimit = 3600
+	connectTeamID    = "TEAMTEST01"
+)
+
+// connectReadOnly 不允许通过接口创建的资源
+var connectReadOnly = map[string]bool{"apps": true, "builds": true, "users": true}
+
+// Relation 资源关系
+type Relation struct {
+	Type string
+	IDs  []string
+	Many bool
+}
+
+// ToOne 单个资源的关系
+func ToOne(typ, id string) Relation {
+	return Relation{Type: typ, IDs: []string{id}}
+}
+
+// ToMany 多个资源的关系
+func ToMany(typ string, ids ...string) Relation {
+	return Relation{Type: typ, IDs: ids, Many: true}
+}
+
+// ConnectResource 内存中的 JSON:API 资源 属性为 JSON 解码后的值
+type ConnectResource struct {
+	Type          string
+	ID            string
+	Attributes    map[string]any
+	Relationships map[string]Relation
+}
+
+func (r *ConnectResource) clone() *ConnectResource {
+	c := &ConnectResource{Type: r.Type, ID: r.ID, Attributes: map[string]any{}, Relationships: map[string]Relation{}}
+	for k, v := range r.Attributes {
+		c.Attributes[k] = v
+	}
+	for k, v := range r.Relationships {
+		v.IDs = append([]string{}, v.IDs...)
+		c.Relationships[k] = v
+	}
+	return c
+}
+
+// String 属性的字符串值
+func (r *ConnectResource) String(key string) string {
+	if v, ok := r.Attributes[key]; ok && v != nil {
+		return fmt.Sprint(v)
+	}
+	return ""
+}
+
+type connectKey struct {
+	issuerID string // 为空表示个人密钥
+	pub      *ecdsa.PublicKey
+	used     int
+	window   time.Time
+}
+
+// ConnectServer 模拟 api.appstoreconnect.apple.com 的核心 JSON:API 资源
+// apps builds devices profiles certificates bundleIds users userInvitations 保存在内存中
+// 请求必须带上已配置密钥签发的 ES256 token 配合 Api.SetBaseUrl(server.URL) 使用
+type ConnectServer struct {
+	*httptest.Server
+	HourLimit int // 每个密钥每小时的请求额度 默认3600 小于0不限制
+
+	mu        sync.Mutex
+	keys      map[string]*connectKey
+	resources map[string][]*ConnectResource
+	seq       int
+	ca        *x509.Certificate
+	caKey     *ecdsa.PrivateKey
+}
+
+// NewConnectServer 启动模拟服务
+func NewConnectServer() *ConnectServer {
+	s := &ConnectServer{
+		HourLimit: connectHourLimit,
+		keys:      make(map[string]*connectKey),
+		resources: make(map[string][]*ConnectResource),
+	}
+	s.ca, s.caKey = newConnectCA()
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// AddKey 配置可用的 Api 密钥公钥 issuerID 为空表示个人密钥
+func (s *ConnectServer) AddKey(issuerID, keyID string, pub *ecdsa.PublicKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[keyID] = &connectKey{issuerID: issuerID, pub: pub}
+}
+
+// NewKey 生成并配置密钥 返回 PEM 的 base64 可直接作为 Api.ApiKey
+func (s *ConnectServer) NewKey(issuerID, keyID string) string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		panic(err)
+	}
+	s.AddKey(issuerID, keyID, &key.PublicKey)
+	return base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+}
+
+// RevokeKey 撤销密钥 之后的请求返回 401
+func (s *ConnectServer) RevokeKey(keyID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.keys, keyID)
+}
+
+// Add 添加资源 返回资源ID
+func (s *ConnectServer) Add(typ string, attributes map[string]any, relationships map[string]Relation) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := &ConnectResource{Type: typ, Attributes: normalize(attributes), Relationships: map[string]Relation{}}
+	for k, v := range relationships {
+		res.Relationships[k] = v
+	}
+	s.insert(res)
+	return res.ID
+}
+
+// AddApp 添加应用
+func (s *ConnectServer) AddApp(name, bundleID string) string {
+	return s.Add("apps", map[string]any{"name": name, "bundleId": bundleID, "sku": bundleID, "primaryLocale": "en-US"}, nil)
+}
+
+// AddBuild 添加构建版本 processingState 为空时为 VALID
+func (s *ConnectServer) AddBuild(appID, version, processingState string) string {
+	if processingState == "" {
+		processingState = "VALID"
+	}
+	return s.Add("builds", map[string]any{
+		"version":         version,
+		"processingState": processingState,
+		"uploadedDate":    time.Now().UTC().Format(time.RFC3339),
+		"expired":         false,
+	}, map[string]Relation{"app": ToOne("apps", appID)})
+}
+
+// AddUser 添加团队成员
+func (s *ConnectServer) AddUser(username string, roles ...string) string {
+	return s.Add("users", map[string]any{"username": username, "roles": roles, "allAppsVisible": true}, map[string]Relation{"visibleApps": ToMany("apps")})
+}
+
+// AcceptInvitation 模拟被邀请人接受邀请 返回新成员ID
+func (s *ConnectServer) AcceptInvitation(email string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, inv := range s.resources["userInvitations"] {
+		if !strings.EqualFold(inv.String("email"), email) {
+			continue
+		}
+		user := &ConnectResource{Type: "users", Attributes: map[string]any{"username": inv.String("email")}, Relationships: map[string]Relation{"visibleApps": ToMany("apps")}}
+		for _, k := range []string{"firstName", "lastName", "roles", "allAppsVisible", "provisioningAllowed"} {
+			user.Attributes[k] = inv.Attributes[k]
+		}
+		if rel, ok := inv.Relationships["visibleApps"]; ok {
+			user.Relationships["visibleApps"] = rel
+		}
+		s.remove(inv.Type, inv.ID)
+		s.insert(user)
+		return user.ID, true
+	}
+	return "", false
+}
+
+// Get 资源副本 不存在时返回 nil
+func (s *ConnectServer) Get(typ, id string) *ConnectResource {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if res := s.find(typ, id); res != nil {
+		return res.clone()
+	}
+	return nil
+}
+
+// List 某类资源的副本 按创建顺序
+func (s *ConnectServer) List(typ string) []*ConnectResource {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]*ConnectResource, 0, len(s.resources[typ]))
+	for _, res := range s.resources[typ] {
+		list = append(list, res.clone())
+	}
+	return list
+}
+
+// Update 修改资源属性 如把构建版本改为 VALID
+func (s *ConnectServer) Update(typ, id string, attributes map[string]any) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := s.find(typ, id)
+	if res == nil {
+		return false
+	}
+	for k, v := range normalize(attributes) {
+		res.Attributes[k] = v
+	}
+	return true
+}
+
+func (s *ConnectServer) serve(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.authorize(w, r); !ok {
+		return
+	}
+	if !strings.HasPrefix(r.URL.Path, "/v1/") {
+		writeConnectError(w, 404, "NOT_FOUND", "The path provided does not match a defined resource type.")
+		return
+	}
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/"), "/"), "/")
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		s.list(w, r, parts[0])
+	case len(parts) == 1 && r.Method == http.MethodPost:
+		s.create(w, r, parts[0])
+	case len(parts) == 2 && r.Method == http.MethodGet:
+		s.get(w, r, parts[0], parts[1])
+	case len(parts) == 2 && r.Method == http.MethodPatch:
+		s.update(w, r, parts[0], parts[1])
+	case len(parts) == 2 && r.Method == http.MethodDelete:
+		s.delete(w, parts[0], parts[1])
+	case len(parts) == 3 && r.Method == http.MethodGet:
+		s.related(w, r, parts[0], parts[1], parts[2])
+	case len(parts) == 4 && parts[2] == "relationships":
+		s.linkage(w, r, parts[0], parts[1], parts[3])
+	default:
+		writeConnectError(w, 405, "METHOD_NOT_ALLOWED", "The request method is not allowed for this resource.")
+	}
+}
+
+// authorize 校验 token 并扣除额度
+func (s *ConnectServer) authorize(w http.ResponseWriter, r *http.Request) (*connectKey, bool) {
+	key, err := s.verifyToken(r)
+	if err != nil {
+		writeConnectError(w, 401, "NOT_AUTHORIZED", err.Error())
+		return nil, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.HourLimit < 0 {
+		return key, true
+	}
+	if now := time.Now(); now.Sub(key.window) >= time.Hour {
+		key.used, key.window = 0, now
+	}
+	if key.used >= s.HourLimit {
+		w.Header().Set("X-Rate-Limit", fmt.Sprintf("user-hour-lim:%d;user-hour-rem:0;", s.HourLimit))
+		writeConnectError(w, 429, "RATE_LIMIT_EXCEEDED", "The request rate limit has been reached.")
+		return nil, false
+	}
+	key.used++
+	w.Header().Set("X-Rate-Limit", fmt.Sprintf("user-hour-lim:%d;user-hour-rem:%d;", s.HourLimit, s.HourLimit-key.used))
+	return key, true
+}
+func (s *ConnectServer) verifyToken(r *http.Request) (*connectKey, error) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return nil, errors.New("缺少 Bearer token")
+	}
+	var key *connectKey
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(strings.TrimPrefix(auth, "Bearer "), claims, func(t *jwt.Token) (interface{}, error) {
+		if t.Method != jwt.SigningMethodES256 {
+			return nil, errors.New("签名算法必须为 ES256")
+		}
+		kid, _ := t.Header["kid"].(string)
+		s.mu.Lock()
+		key = s.keys[kid]
+		s.mu.Unlock()
+		if key == nil {
+			return nil, errors.New("未知的密钥 " + kid)
+		}
+		return key.pub, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !claims.VerifyAudience(connectAudience, true) {
+		return nil, errors.New("aud 错误")
+	}
+	iat, _ := claims["iat"].(float64)
+	exp, _ := claims["exp"].(float64)
+	if iat == 0 || exp == 0 || exp-iat > 20*60 {
+		return nil, errors.New("token 有效期不能超过20分钟")
+	}
+	if key.issuerID != "" && claims["iss"] != key.issuerID {
+		return nil, errors.New("iss 错误")
+	}
+	if key.issuerID == "" && claims["sub"] != "user" {
+		return nil, errors.New("个人密钥 sub 必须为 user")
+	}
+	if scope, ok := claims["scope"].([]interface{}); ok && !inScope(scope, r) {
+		return nil, errors.New("请求不在 token 的 scope 中")
+	}
+	return key, nil
+}
+func inScope(scope []interface{}, r *http.Request) bool {
+	for _, item := range scope {
+		s, _ := item.(string)
+		if path, _, _ := strings.Cut(s, "?"); path == r.Method+" "+r.URL.Path {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *ConnectServer) list(w http.ResponseWriter, r *http.Request, typ string) {
+	q := r.URL.Query()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var list []*ConnectResource
+	for _, res := range s.resources[typ] {
+		if matchFilters(res, q) {
+			list = append(list, res)
+		}
+	}
+	sortResources(list, q.Get("sort"))
+	s.writeList(w, r, list)
+}
+
+// writeList 按 limit 和 cursor 分页 调用时需持有锁
+func (s *ConnectServer) writeList(w http.ResponseWriter, r *http.Request, list []*ConnectResource) {
+	q := r.URL.Query()
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 200 {
+		writeConnectError(w, 400, "PARAMETER_ERROR.INVALID", "limit 不能超过200")
+		return
+	}
+	offset, _ := strconv.Atoi(q.Get("cursor"))
+	total := len(list)
+	if offset > total {
+		offset = total
+	}
+	page := list[offset:]
+	links := map[string]string{"self": s.URL + r.URL.RequestURI()}
+	if len(page) > limit {
+		page = page[:limit]
+		q.Set("cursor", strconv.Itoa(offset+limit))
+		links["next"] = s.URL + r.URL.Path + "?" + q.Encode()
+	}
+	data := make([]map[string]any, 0, len(page))
+	for _, res := range page {
+		data = append(data, s.render(res))
+	}
+	writeJson(w, 200, map[string]any{
+		"data":     data,
+		"included": s.included(page, r.URL.Query().Get("include")),
+		"links":    links,
+		"meta":     map[string]any{"paging": map[string]int{"total": total, "limit": limit}},
+	})
+}
+func (s *ConnectServer) get(w http.ResponseWriter, r *http.Request, typ, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := s.find(typ, id)
+	if res == nil {
+		writeNotFound(w, typ, id)
+		return
+	}
+	s.writeOne(w, r, 200, res)
+}
+func (s *ConnectServer) writeOne(w http.ResponseWriter, r *http.Request, status int, res *ConnectResource) {
+	writeJson(w, status, map[string]any{
+		"data":     s.render(res),
+		"included": s.included([]*ConnectResource{res}, r.URL.Query().Get("include")),
+		"links":    map[string]string{"self": s.resourceUrl(res)},
+	})
+}
+
+func (s *ConnectServer) create(w http.ResponseWriter, r *http.Request, typ string) {
+	if connectReadOnly[typ] {
+		writeConnectError(w, 403, "FORBIDDEN_ERROR", fmt.Sprintf("The resource '%s' does not allow 'CREATE'.", typ))
+		return
+	}
+	body, err := decodeConnectBody(r)
+	if err != nil || body.Type != typ {
+		writeConnectError(w, 409, "ENTITY_ERROR", "请求中的资源类型错误")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := &ConnectResource{Type: typ, Attributes: body.Attributes, Relationships: map[string]Relation{}}
+	if res.Attributes == nil {
+		res.Attributes = map[string]any{}
+	}
+	if !s.setRelationships(w, res, body.Relationships) {
+		return
+	}
+	if status, code, detail := s.prepare(res); status != 0 {
+		writeConnectError(w, status, code, detail)
+		return
+	}
+	s.insert(res)
+	s.writeOne(w, r, 201, res)
+}
+func (s *ConnectServer) update(w http.ResponseWriter, r *http.Request, typ, id string) {
+	body, err := decodeConnectBody(r)
+	if err != nil || body.Type != typ || body.ID != id {
+		writeConnectError(w, 409, "ENTITY_ERROR", "请求中的资源类型或ID错误")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := s.find(typ, id)
+	if res == nil {
+		writeNotFound(w, typ, id)
+		return
+	}
+	next := res.clone()
+	for k, v := range body.Attributes {
+		next.Attributes[k] = v
+	}
+	if !s.setRelationships(w, next, body.Relationships) {
+		return
+	}
+	*res = *next
+	s.writeOne(w, r, 200, res)
+}
+func (s *ConnectServer) delete(w http.ResponseWriter, typ, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if connectReadOnly[typ] && typ != "users" {
+		writeConnectError(w, 403, "FORBIDDEN_ERROR", fmt.Sprintf("The resource '%s' does not allow 'DELETE'.", typ))
+		return
+	}
+	if !s.remove(typ, id) {
+		writeNotFound(w, typ, id)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+// related GET /v1/{type}/{id}/{relationship} 先取资源自身的关系 没有时反查指向该资源的资源 如 apps/{id}/builds
+func (s *ConnectServer) related(w http.ResponseWriter, r *http.Request, typ, id, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := s.find(typ, id)
+	if res == nil {
+		writeNotFound(w, typ, id)
+		return
+	}
+	rel, ok := res.Relationships[name]
+	if ok && !rel.Many {
+		if len(rel.IDs) == 0 {
+			writeJson(w, 200, map[string]any{"data": nil})
+			return
+		}
+		if target := s.find(rel.Type, rel.IDs[0]); target != nil {
+			s.writeOne(w, r, 200, target)
+			return
+		}
+		writeNotFound(w, rel.Type, rel.IDs[0])
+		return
+	}
+	var list []*ConnectResource
+	if ok {
+		for _, rid := range rel.IDs {
+			if target := s.find(rel.Type, rid); target != nil {
+				list = append(list, target)
+			}
+		}
+	} else {
+		back := strings.TrimSuffix(typ, "s")
+		for _, target := range s.resources[name] {
+			if contains(target.Relationships[back].IDs, id) {
+				list = append(list, target)
+			}
+		}
+	}
+	q := r.URL.Query()
+	var filtered []*ConnectResource
+	for _, target := range list {
+		if matchFilters(target, q) {
+			filtered = append(filtered, target)
+		}
+	}
+	s.writeList(w, r, filtered)
+}
+
+// linkage /v1/{type}/{id}/relationships/{relationship} 读取 添加 删除 替换关系
+func (s *ConnectServer) linkage(w http.ResponseWriter, r *http.Request, typ, id, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := s.find(typ, id)
+	if res == nil {
+		writeNotFound(w, typ, id)
+		return
+	}
+	rel := res.Relationships[name]
+	if r.Method == http.MethodGet {
+		data := make([]map[string]string, 0, len(rel.IDs))
+		for _, rid := range rel.IDs {
+			data = append(data, map[string]string{"type": rel.Type, "id": rid})
+		}
+		writeJson(w, 200, map[string]any{"data": data})
+		return
+	}
+	var body connectRelationship
+	json.NewDecoder(r.Body).Decode(&body)
+	next, err := decodeRelation(body.Data)
+	if err != nil {
+		writeConnectError(w, 409, "ENTITY_ERROR.RELATIONSHIP.INVALID", err.Error())
+		return
+	}
+	if !s.exists(next) {
+		writeConnectError(w, 409, "ENTITY_ERROR.RELATIONSHIP.INVALID", "关联的资源不存在")
+		return
+	}
+	if rel.Type == "" {
+		rel = Relation{Type: next.Type, Many: next.Many}
+	}
+	switch r.Method {
+	case http.MethodPost:
+		for _, rid := range next.IDs {
+			if !contains(rel.IDs, rid) {
+				rel.IDs = append(rel.IDs, rid)
+			}
+		}
+	case http.MethodDelete:
+		var left []string
+		for _, rid := range rel.IDs {
+			if !contains(next.IDs, rid) {
+				left = append(left, rid)
+			}
+		}
+		rel.IDs = left
+	case http.MethodPatch:
+		rel.IDs = next.IDs
+	default:
+		writeConnectError(w, 405, "METHOD_NOT_ALLOWED", "The request method is not allowed for this resource.")
+		return
+	}
+	res.Relationships[name] = rel
+	w.WriteHeader(204)
+}
+
+// prepare 创建前按资源类型校验并补充服务端生成的属性 返回非0状态码表示失败
+func (s *ConnectServer) prepare(res *ConnectResource) (int, string, string) {
+	now := time.Now().UTC()
+	attr := res.Attributes
+	switch res.Type {
+	case "bundleIds":
+		if res.String("identifier") == "" {
+			return 409, "ENTITY_ERROR.ATTRIBUTE.REQUIRED", "identifier 不能为空"
+		}
+		if s.findBy("bundleIds", "identifier", res.String("identifier")) != nil {
+			return 409, "ENTITY_ERROR.ATTRIBUTE.INVALID", "An App ID with Identifier '" + res.String("identifier") + "' is not available."
+		}
+		attr["seedId"] = connectTeamID
+		setDefault(attr, "platform", "IOS")
+	case "devices":
+		if res.String("udid") == "" {
+			return 409, "ENTITY_ERROR.ATTRIBUTE.REQUIRED", "udid 不能为空"
+		}
+		if s.findBy("devices", "udid", res.String("udid")) != nil {
+			return 409, "ENTITY_ERROR.ATTRIBUTE.INVALID.DUPLICATE", "A device with number '" + res.String("udid") + "' already exists on this team."
+		}
+		attr["status"] = "ENABLED"
+		attr["addedDate"] = now.Format(time.RFC3339)
+		setDefault(attr, "platform", "IOS")
+		setDefault(attr, "deviceClass", "IPHONE")
+	case "certificates":
+		content, err := s.signCsr(res.String("csrContent"), now)
+		if err != nil {
+			return 409, "ENTITY_ERROR.ATTRIBUTE.INVALID", "csrContent 无效 " + err.Error()
+		}
+		delete(attr, "csrContent")
+		cert, _ := x509.ParseCertificate(content)
+		attr["certificateContent"] = base64.StdEncoding.EncodeToString(content)
+		attr["serialNumber"] = strings.ToUpper(cert.SerialNumber.Text(16))
+		attr["expirationDate"] = cert.NotAfter.Format(time.RFC3339)
+		attr["name"] = "Apple Distribution: Test Team"
+		attr["displayName"] = "Test Team"
+		setDefault(attr, "platform", "IOS")
+	case "profiles":
+		if res.String("name") == "" || res.String("profileType") == "" || len(res.Relationships["bundleId"].IDs) == 0 {
+			return 409, "ENTITY_ERROR.ATTRIBUTE.REQUIRED", "name profileType bundleId 不能为空"
+		}
+		if s.findBy("profiles", "name", res.String("name")) != nil {
+			return 409, "ENTITY_ERROR.ATTRIBUTE.INVALID", "Multiple profiles found with the name '" + res.String("name") + "'."
+		}
+		attr["profileState"] = "ACTIVE"
+		attr["createdDate"] = now.Format(time.RFC3339)
+		attr["expirationDate"] = now.AddDate(1, 0, 0).Format(time.RFC3339)
+		attr["uuid"] = newUuid()
+		attr["profileContent"] = base64.StdEncoding.EncodeToString(s.profileContent(res, now))
+		setDefault(attr, "platform", "IOS")
+	case "userInvitations":
+		email := res.String("email")
+		if email == "" {
+			return 409, "ENTITY_ERROR.ATTRIBUTE.REQUIRED", "email 不能为空"
+		}
+		if s.findBy("users", "username", email) != nil || s.findBy("userInvitations", "email", email) != nil {
+			return 409, "ENTITY_ERROR.ATTRIBUTE.INVALID", "The user with email '" + email + "' is already a team member or has a pending invitation."
+		}
+		attr["expirationDate"] = now.AddDate(0, 0, 30).Format(time.RFC3339)
+	}
+	return 0, "", ""
+}
+
+// signCsr 使用模拟的 WWDR 证书签发 csrContent 支持 base64 DER 或 PEM
+func (s *ConnectServer) signCsr(csr string, now time.Time) ([]byte, error) {
+	raw := []byte(csr)
+	if block, _ := pem.Decode(raw); block != nil {
+		raw = block.Bytes
+	} else if der, err := base64.StdEncoding.DecodeString(csr); err == nil {
+		raw = der
+	}
+	req, err := x509.ParseCertificateRequest(raw)
+	if err != nil {
+		return nil, err
+	}
+	if err = req.CheckSignature(); err != nil {
+		return nil, err
+	}
+	s.seq++
+	return x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(int64(s.seq)),
+		Subject:      req.Subject,
+		NotBefore:    now.Add(-time.Minute),
+		NotAfter:     now.AddDate(1, 0, 0),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}, s.ca, req.PublicKey, s.caKey)
+}
+
+// profileContent 描述文件内容 只包含 plist 没有 CMS 签名
+func (s *ConnectServer) profileContent(res *ConnectResource, now time.Time) []byte {
+	var udids []string
+	for _, id := range res.Relationships["devices"].IDs {
+		if d := s.find("devices", id); d != nil {
+			udids = append(udids, d.String("udid"))
+		}
+	}
+	var certs [][]byte
+	for _, id := range res.Relationships["certificates"].IDs {
+		if c := s.find("certificates", id); c != nil {
+			der, _ := base64.StdEncoding.DecodeString(c.String("certificateContent"))
+			certs = append(certs, der)
+		}
+	}
+	appID := ""
+	if b := s.find("bundleIds", res.Relationships["bundleId"].IDs[0]); b != nil {
+		appID = connectTeamID + "." + b.String("identifier")
+	}
+	buf, _ := plist.MarshalIndent(map[string]any{
+		"AppIDName":                   res.String("name"),
+		"ApplicationIdentifierPrefix": []string{connectTeamID},
+		"CreationDate":                now,
+		"ExpirationDate":              now.AddDate(1, 0, 0),
+		"Name":                        res.String("name"),
+		"Platform":                    []string{"iOS"},
+		"TeamIdentifier":              []string{connectTeamID},
+		"TeamName":                    "Test Team",
+		"UUID":                        res.String("uuid"),
+		"Version":                     1,
+		"ProvisionedDevices":          udids,
+		"DeveloperCertificates":       certs,
+		"Entitlements":                map[string]any{"application-identifier": appID},
+	}, plist.XMLFormat, "\t")
+	return buf
+}
+
+// included 按 include 参数返回直接关联的资源 调用时需持有锁
+func (s *ConnectServer) included(list []*ConnectResource, include string) []map[string]any {
+	out := []map[string]any{}
+	if include == "" {
+		return out
+	}
+	seen := map[string]bool{}
+	for _, res := range list {
+		for _, name := range strings.Split(include, ",") {
+			rel := res.Relationships[name]
+			for _, id := range rel.IDs {
+				target := s.find(rel.Type, id)
+				if target == nil || seen[rel.Type+"/"+id] {
+					continue
+				}
+				seen[rel.Type+"/"+id] = true
+				out = append(out, s.render(target))
+			}
+		}
+	}
+	return out
+}
+func (s *ConnectServer) render(res *ConnectResource) map[string]any {
+	rels := map[string]any{}
+	for name, rel := range res.Relationships {
+		var data any
+		if rel.Many {
+			list := make([]map[string]string, 0, len(rel.IDs))
+			for _, id := range rel.IDs {
+				list = append(list, map[string]string{"type": rel.Type, "id": id})
+			}
+			data = list
+		} else if len(rel.IDs) > 0 {
+			data = map[string]string{"type": rel.Type, "id": rel.IDs[0]}
+		}
+		rels[name] = map[string]any{
+			"data":  data,
+			"links": map[string]string{"related": s.resourceUrl(res) + "/" + name},
+		}
+	}
+	return map[string]any{
+		"type":          res.Type,
+		"id":            res.ID,
+		"attributes":    res.Attributes,
+		"relationships": rels,
+		"links":         map[string]string{"self": s.resourceUrl(res)},
+	}
+}
+func (s *ConnectServer) resourceUrl(res *ConnectResource) string {
+	return s.URL + "/v1/" + res.Type + "/" + res.ID
+}
+
+// setRelationships 解析并校验请求中的关系 失败时写入错误响应
+func (s *ConnectServer) setRelationships(w http.ResponseWriter, res *ConnectResource, rels map[string]connectRelationship) bool {
+	for name, item := range rels {
+		rel, err := decodeRelation(item.Data)
+		if err == nil && !s.exists(rel) {
+			err = fmt.Errorf("关联的 %s 不存在", name)
+		}
+		if err != nil {
+			writeConnectError(w, 409, "ENTITY_ERROR.RELATIONSHIP.INVALID", err.Error())
+			return false
+		}
+		res.Relationships[name] = rel
+	}
+	return true
+}
+func (s *ConnectServer) exists(rel Relation) bool {
+	for _, id := range rel.IDs {
+		if s.find(rel.Type, id) == nil {
+			return false
+		}
+	}
+	return true
+}
+func (s *ConnectServer) insert(res *ConnectResource) {
+	s.seq++
+	res.ID = strconv.Itoa(1000000000 + s.seq)
+	s.resources[res.Type] = append(s.resources[res.Type], res)
+}
+func (s *ConnectServer) remove(typ, id string) bool {
+	list := s.resources[typ]
+	for i, res := range list {
+		if res.ID == id {
+			s.resources[typ] = append(list[:i:i], list[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+func (s *ConnectServer) find(typ, id string) *ConnectResource {
+	for _, res := range s.resources[typ] {
+		if res.ID == id {
+			return res
+		}
+	}
+	return nil
+}
+func (s *ConnectServer) findBy(typ, key, value string) *ConnectResource {
+	for _, res := range s.resources[typ] {
+		if strings.EqualFold(res.String(key), value) {
+			return res
+		}
+	}
+	return nil
+}
+
+type connectBody struct {
+	Type          string                         `json:"type"`
+	ID            string                         `json:"id"`
+	Attributes    map[string]any                 `json:"attributes"`
+	Relationships map[string]connectRelationship `json:"relationships"`
+}
+type connectRelationship struct {
+	Data json.RawMessage `json:"data"`
+}
+
+func decodeConnectBody(r *http.Request) (*connectBody, error) {
+	var body struct {
+		Data connectBody `json:"data"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	return &body.Data, nil
+}
+func decodeRelation(data json.RawMessage) (Relation, error) {
+	type linkage struct {
+		Type string `json:"type"`
+		ID   string `json:"id"`
+	}
+	var (
+		one  linkage
+		many []linkage
+	)
+	if err := json.Unmarshal(data, &many); err == nil {
+		rel := Relation{Many: true}
+		for _, l := range many {
+			rel.Type = l.Type
+			rel.IDs = append(rel.IDs, l.ID)
+		}
+		return rel, nil
+	}
+	if err := json.Unmarshal(data, &one); err != nil || one.ID == "" {
+		return Relation{}, errors.New("关系格式错误")
+	}
+	return ToOne(one.Type, one.ID), nil
+}
+
+// matchFilters filter[属性或关系]=v1,v2 不支持 filter[a.b] 这类嵌套筛选 会被忽略
+func matchFilters(res *ConnectResource, q url.Values) bool {
+	for key, values := range q {
+		if !strings.HasPrefix(key, "filter[") || !strings.HasSuffix(key, "]") {
+			continue
+		}
+		field := key[len("filter[") : len(key)-1]
+		if strings.Contains(field, ".") {
+			continue
+		}
+		if !matchField(res, field, strings.Split(values[0], ",")) {
+			return false
+		}
+	}
+	return true
+}
+func matchField(res *ConnectResource, field string, values []string) bool {
+	if field == "id" {
+		return contains(values, res.ID)
+	}
+	if rel, ok := res.Relationships[field]; ok {
+		for _, id := range rel.IDs {
+			if contains(values, id) {
+				return true
+			}
+		}
+		return false
+	}
+	switch v := res.Attributes[field].(type) {
+	case nil:
+		return false
+	case []any:
+		for _, item := range v {
+			if contains(values, fmt.Sprint(item)) {
+				return true
+			}
+		}
+		return false
+	default:
+		return contains(values, fmt.Sprint(v))
+	}
+}
+
+// sortResources 只支持单个字段 前加 - 为倒序
+func sortResources(list []*ConnectResource, field string) {
+	if field == "" {
+		return
+	}
+	desc := strings.HasPrefix(field, "-")
+	field = strings.TrimPrefix(field, "-")
+	sort.SliceStable(list, func(i, j int) bool {
+		a, b := list[i].String(field), list[j].String(field)
+		if field == "id" {
+			a, b = list[i].ID, list[j].ID
+		}
+		if desc {
+			return a > b
+		}
+		return a < b
+	})
+}
+
+// normalize 经过一次 JSON 编解码 与请求中解析出的属性保持一致
+func normalize(attributes map[string]any) map[string]any {
+	out := map[string]any{}
+	buf, _ := json.Marshal(attributes)
+	json.Unmarshal(buf, &out)
+	return out
+}
+func setDefault(attr map[string]any, key string, value any) {
+	if v, ok := attr[key]; !ok || v == nil || v == "" {
+		attr[key] = value
+	}
+}
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+func newUuid() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return fmt.Sprintf("%X-%X-%X-%X-%X", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+func newConnectCA() (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Apple Worldwide Developer Relations Certification Authority (Test)"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().AddDate(10, 0, 0),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		panic(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return cert, key
+}
+func writeConnectError(w http.ResponseWriter, status int, code, detail string) {
+	writeJson(w, status, map[string]any{
+		"errors": []map[string]string{{
+			"id":     newUuid(),
+			"status": strconv.Itoa(status),
+			"code":   code,
+			"title":  http.StatusText(status),
+			"detail": detail,
+		}},
+	})
+}
+func writeNotFound(w http.ResponseWriter, typ, id string) {
+	writeConnectError(w, 404, "NOT_FOUND", fmt.Sprintf("There is no resource of type '%s' with id '%s'", typ, id))
+}
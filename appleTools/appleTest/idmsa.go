@@ -0,0 +1,353 @@
+// Package appleTest 提供苹果服务的本地模拟 用于离线测试 appleTools
+package appleTest
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	TrustCookie   = "DES58b0eba556d80ed2b98707e15ffafd344" // 双重验证通过后下发的信任cookie名
+	SessionCookie = "myacinfo"
+)
+
+// SignInMode 模拟账号的登录结果
+type SignInMode int
+
+const (
+	SignInSuccess   SignInMode = iota // 直接登录成功
+	SignInTwoFactor                   // 需要双重验证 409
+	SignInRepair                      // 需要修复 412
+)
+
+// Account 模拟的 Apple ID
+type Account struct {
+	Account     string
+	Password    string
+	Mode        SignInMode
+	Phones      []Phone  // 受信任电话号码
+	Code        string   // 正确的验证码 默认 123456
+	Throttle    int      // 前 Throttle 次登录返回 503
+	RepairSteps []string // 412 时 /repair/options 返回的 requiredSteps
+
+	signIns int
+	smsSent int
+}
+
+// Phone 受信任电话号码
+type Phone struct {
+	ID     int
+	Number string
+	Mode   string // sms voice
+}
+
+type idmsaSession struct {
+	account  *Account
+	verified bool
+}
+
+// IdmsaServer 模拟 idmsa.apple.com 与 appleid.apple.com 的登录相关接口
+type IdmsaServer struct {
+	*httptest.Server
+	HashcashBits      int    // 大于0时登录前要求 X-Apple-HC 证明
+	HashcashChallenge string // 默认 appleTest
+	HashcashStatus    int    // 大于0时获取挑战的 GET 请求返回该状态码
+
+	mu       sync.Mutex
+	accounts map[string]*Account
+	sessions map[string]*idmsaSession
+	seq      int
+}
+
+// NewIdmsaServer 启动模拟服务 配合 Auth.SetBaseUrl(server.URL) 使用
+func NewIdmsaServer(accounts ...*Account) *IdmsaServer {
+	s := &IdmsaServer{
+		HashcashChallenge: "appleTest",
+		accounts:          make(map[string]*Account),
+		sessions:          make(map[string]*idmsaSession),
+	}
+	for _, a := range accounts {
+		s.AddAccount(a)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/appleauth/auth/signin", s.signIn)
+	mux.HandleFunc("/appleauth/auth/signin/init", s.signInInit)
+	mux.HandleFunc("/appleauth/auth/signin/complete", s.signIn)
+	mux.HandleFunc("/appleauth/auth", s.authOptions)
+	mux.HandleFunc("/appleauth/auth/verify/phone", s.sendSMS)
+	mux.HandleFunc("/appleauth/auth/verify/phone/securitycode", s.verifyCode)
+	mux.HandleFunc("/appleauth/auth/verify/trusteddevice/securitycode", s.verifyCode)
+	mux.HandleFunc("/appleauth/auth/2sv/trust", s.trust)
+	mux.HandleFunc("/appleauth/auth/repair/complete", s.repairComplete)
+	mux.HandleFunc("/account/manage/repair/options", s.repairOptions)
+	mux.HandleFunc("/account/manage/privacy/accept", s.repairStep("privacy_consent"))
+	mux.HandleFunc("/account/manage/repair/terms/accept", s.repairStep("terms_and_conditions"))
+	mux.HandleFunc("/account/manage/repair/hsa2/later", s.repairStep("hsa2_enrollment"))
+	s.Server = httptest.NewServer(echoSession(mux))
+	return s
+}
+
+// AddAccount 添加模拟账号
+func (s *IdmsaServer) AddAccount(a *Account) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if a.Code == "" {
+		a.Code = "123456"
+	}
+	s.accounts[a.Account] = a
+}
+
+// SignIns 账号收到的登录请求次数
+func (s *IdmsaServer) SignIns(account string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if a, ok := s.accounts[account]; ok {
+		return a.signIns
+	}
+	return 0
+}
+
+// SMSSent 账号收到的重发短信请求次数
+func (s *IdmsaServer) SMSSent(account string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if a, ok := s.accounts[account]; ok {
+		return a.smsSent
+	}
+	return 0
+}
+
+func (s *IdmsaServer) signInInit(w http.ResponseWriter, r *http.Request) {
+	writeJson(w, 200, map[string]any{"iteration": 1000, "salt": "c2FsdA==", "protocol": "s2k", "c": "d-1"})
+}
+func (s *IdmsaServer) signIn(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		if s.HashcashStatus > 0 {
+			w.WriteHeader(s.HashcashStatus)
+			return
+		}
+		if s.HashcashBits > 0 {
+			w.Header().Set("X-Apple-HC-Bits", strconv.Itoa(s.HashcashBits))
+			w.Header().Set("X-Apple-HC-Challenge", s.HashcashChallenge)
+		}
+		w.WriteHeader(200)
+		return
+	}
+	if s.HashcashBits > 0 && !s.validHashcash(r.Header.Get("X-Apple-HC")) {
+		writeError(w, 400, "-20209", "hashcash 校验失败")
+		return
+	}
+	var body struct {
+		AccountName string `json:"accountName"`
+		Password    string `json:"password"`
+	}
+	json.NewDecoder(r.Body).Decode(&body)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, ok := s.accounts[body.AccountName]
+	if !ok || a.Password != body.Password {
+		writeError(w, 401, "-20101", "您的 Apple ID 或密码输入有误。")
+		return
+	}
+	a.signIns++
+	if a.signIns <= a.Throttle {
+		w.WriteHeader(503)
+		return
+	}
+	id := s.newSession(w, a)
+	switch {
+	case a.Mode == SignInTwoFactor && !hasCookie(r, TrustCookie):
+		writeJson(w, 409, map[string]any{"authType": "hsa2"})
+	case a.Mode == SignInRepair:
+		w.Header().Set("X-Apple-Repair-Session-Token", "repair-"+id)
+		writeJson(w, 412, map[string]any{"authType": "hsa2"})
+	default:
+		s.setSessionCookie(w, a)
+		writeJson(w, 200, map[string]any{"authType": "hsa2"})
+	}
+}
+func (s *IdmsaServer) authOptions(w http.ResponseWriter, r *http.Request) {
+	sess := s.session(r)
+	if sess == nil {
+		writeError(w, 401, "-20101", "会话不存在")
+		return
+	}
+	var phones []map[string]any
+	for _, p := range sess.account.Phones {
+		phones = append(phones, map[string]any{
+			"id":                 p.ID,
+			"numberWithDialCode": p.Number,
+			"pushMode":           p.Mode,
+		})
+	}
+	mode := "sms"
+	if len(phones) == 0 {
+		mode = "trusteddevice"
+	}
+	writeJson(w, 200, map[string]any{"trustedPhoneNumbers": phones, "mode": mode})
+}
+func (s *IdmsaServer) sendSMS(w http.ResponseWriter, r *http.Request) {
+	sess := s.session(r)
+	if sess == nil || r.Method != http.MethodPut {
+		writeError(w, 401, "-20101", "会话不存在")
+		return
+	}
+	s.mu.Lock()
+	sess.account.smsSent++
+	s.mu.Unlock()
+	writeJson(w, 200, map[string]any{"mode": "sms"})
+}
+func (s *IdmsaServer) verifyCode(w http.ResponseWriter, r *http.Request) {
+	sess := s.session(r)
+	if sess == nil {
+		writeError(w, 401, "-20101", "会话不存在")
+		return
+	}
+	var body struct {
+		SecurityCode struct {
+			Code string `json:"code"`
+		} `json:"securityCode"`
+	}
+	json.NewDecoder(r.Body).Decode(&body)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if body.SecurityCode.Code != sess.account.Code {
+		writeError(w, 400, "-21669", "验证码不正确")
+		return
+	}
+	sess.verified = true
+	w.WriteHeader(204)
+}
+func (s *IdmsaServer) trust(w http.ResponseWriter, r *http.Request) {
+	sess := s.session(r)
+	if sess == nil || !sess.verified {
+		writeError(w, 401, "-20101", "尚未完成双重验证")
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: TrustCookie, Value: "HSARMTKNSRVXWFla" + sess.account.Account, Path: "/"})
+	s.setSessionCookie(w, sess.account)
+	w.WriteHeader(204)
+}
+func (s *IdmsaServer) repairOptions(w http.ResponseWriter, r *http.Request) {
+	sess := s.repairSession(r)
+	if sess == nil {
+		writeError(w, 401, "-20101", "修复会话不存在")
+		return
+	}
+	s.mu.Lock()
+	steps := append([]string{}, sess.account.RepairSteps...)
+	s.mu.Unlock()
+	writeJson(w, 200, map[string]any{"requiredSteps": steps})
+}
+func (s *IdmsaServer) repairStep(step string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sess := s.repairSession(r)
+		if sess == nil {
+			writeError(w, 401, "-20101", "修复会话不存在")
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		var left []string
+		for _, st := range sess.account.RepairSteps {
+			if st != step {
+				left = append(left, st)
+			}
+		}
+		sess.account.RepairSteps = left
+		w.WriteHeader(204)
+	}
+}
+func (s *IdmsaServer) repairComplete(w http.ResponseWriter, r *http.Request) {
+	sess := s.repairSession(r)
+	if sess == nil {
+		writeError(w, 401, "-20101", "修复会话不存在")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(sess.account.RepairSteps) > 0 {
+		writeError(w, 412, "-20751", "尚有未完成的修复步骤")
+		return
+	}
+	s.setSessionCookie(w, sess.account)
+	writeJson(w, 200, map[string]any{})
+}
+
+func (s *IdmsaServer) newSession(w http.ResponseWriter, a *Account) string {
+	s.seq++
+	id := "session-" + strconv.Itoa(s.seq)
+	s.sessions[id] = &idmsaSession{account: a}
+	w.Header().Set("X-Apple-ID-Session-Id", id)
+	w.Header().Set("scnt", "scnt-"+id)
+	return id
+}
+func (s *IdmsaServer) session(r *http.Request) *idmsaSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := r.Header.Get("X-Apple-ID-Session-Id")
+	if sess, ok := s.sessions[id]; ok && r.Header.Get("scnt") == "scnt-"+id {
+		return sess
+	}
+	return nil
+}
+func (s *IdmsaServer) repairSession(r *http.Request) *idmsaSession {
+	sess := s.session(r)
+	if sess == nil || r.Header.Get("X-Apple-Repair-Session-Token") != "repair-"+r.Header.Get("X-Apple-ID-Session-Id") {
+		return nil
+	}
+	return sess
+}
+func (s *IdmsaServer) setSessionCookie(w http.ResponseWriter, a *Account) {
+	http.SetCookie(w, &http.Cookie{Name: SessionCookie, Value: "DAW" + a.Account, Path: "/"})
+}
+func (s *IdmsaServer) validHashcash(hc string) bool {
+	parts := strings.Split(hc, ":")
+	if len(parts) != 6 || parts[1] != strconv.Itoa(s.HashcashBits) || parts[3] != s.HashcashChallenge {
+		return false
+	}
+	sum := sha1.Sum([]byte(hc))
+	for bits, i := s.HashcashBits, 0; bits > 0; bits, i = bits-8, i+1 {
+		if bits >= 8 && sum[i] != 0 || bits < 8 && sum[i]>>(8-bits) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// echoSession 与苹果一致 每个响应都带上会话头
+func echoSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, h := range []string{"X-Apple-ID-Session-Id", "scnt"} {
+			if v := r.Header.Get(h); v != "" {
+				w.Header().Set(h, v)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+func hasCookie(r *http.Request, name string) bool {
+	// Auth 通过 Cookie 请求头发送 形如 a=1;b=2;
+	for _, c := range strings.Split(r.Header.Get("Cookie"), ";") {
+		if kv := strings.SplitN(strings.TrimSpace(c), "=", 2); kv[0] == name {
+			return true
+		}
+	}
+	return false
+}
+func writeJson(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+func writeError(w http.ResponseWriter, status int, code, msg string) {
+	writeJson(w, status, map[string]any{
+		"serviceErrors": []map[string]string{{"code": code, "message": msg}},
+	})
+}
@@ -12,6 +12,7 @@ import (
 
 const (
 	authBaseUrl                      = `https://idmsa.apple.com/appleauth/auth`
+	accountBaseUrl                   = `https://appleid.apple.com/account/manage`
 	jsonContentType                  = `application/json`
 	appleAuthXAppleWidgetKeyAppStore = `e0b80c3bf78523bfe80974d320935bfa30add02e1bff88ec2166c6bd5a706c42`
 	language                         = `zh-CN,zh;q=0.9,ga;q=0.8,et;q=0.7`
@@ -58,13 +59,15 @@ type Auth struct {
 	Account  string `json:"account" gorm:"uniqueIndex;comment:账号名"`
 	Password string `json:"password" gorm:"comment:密码"`
 	Web
-	proxy string
+	proxy   string
+	baseUrl string
 }
 type AuthSession struct {
 	Auth         *Auth             `json:"auth"`
 	Header       map[string]string `json:"header"`
 	Mobiles      []*authMobile     `json:"mobiles"`
 	SelectMobile *authMobile       `json:"select_mobile"`
+	accountReady bool
 }
 type authMobile struct {
 	ID     int    `json:"id"`
@@ -108,11 +111,32 @@ func (a *Auth) SetProxy(u string) {
 	a.proxy = u
 }
 
+// SetBaseUrl 将 idmsa.apple.com 和 appleid.apple.com 的请求都发往 u 用于测试
+func (a *Auth) SetBaseUrl(u string) {
+	a.baseUrl = strings.TrimSuffix(u, "/")
+}
+func (a *Auth) authUrl(path string) string {
+	if a.baseUrl != "" {
+		return a.baseUrl + "/appleauth/auth" + path
+	}
+	return authBaseUrl + path
+}
+func (a *Auth) accountUrl(path string) string {
+	if a.baseUrl != "" {
+		return a.baseUrl + "/account/manage" + path
+	}
+	return accountBaseUrl + path
+}
+
 // SignInV2 登录
 func (a *Auth) SignInV2() (session *AuthSession, err error) {
 	var res *httpclient.Response
 	session = &AuthSession{Auth: a}
-	_initUrl := authBaseUrl + `/signin/init`
+	if err = session.fetchHashcash(); err != nil {
+		err = fmt.Errorf("hashcash %w", err)
+		return
+	}
+	_initUrl := a.authUrl(`/signin/init`)
 	_initData := map[string]any{
 		"accountName": a.Account,
 		"a":           createA(),
@@ -123,7 +147,7 @@ func (a *Auth) SignInV2() (session *AuthSession, err error) {
 		return
 	}
 
-	_url := authBaseUrl + `/signin/complete?isRememberMeEnabled=true`
+	_url := a.authUrl(`/signin/complete?isRememberMeEnabled=true`)
 	_data := map[string]any{
 		"accountName": a.Account,
 		"password":    a.Password,
@@ -135,12 +159,12 @@ func (a *Auth) SignInV2() (session *AuthSession, err error) {
 		case AuthError409:
 			session.extractHeader(res)
 			if err1 := session.extractMobile(); err1 != nil {
-				err = fmt.Errorf(err.Error()+" %s", err1)
+				err = fmt.Errorf("%w %s", err, err1)
 			}
 			return
 		case AuthError412:
 			session.extractHeader(res, "X-Apple-Repair-Session-Token")
-			if err = session.accept(); err != nil {
+			if err = session.repair(); err != nil {
 				return
 			}
 		default:
@@ -169,7 +193,11 @@ func randStr(length int) string {
 func (a *Auth) SignIn() (session *AuthSession, err error) {
 	var res *httpclient.Response
 	session = &AuthSession{Auth: a}
-	_url := authBaseUrl + `/signin?isRememberMeEnabled=true`
+	if err = session.fetchHashcash(); err != nil {
+		err = fmt.Errorf("hashcash %w", err)
+		return
+	}
+	_url := a.authUrl(`/signin?isRememberMeEnabled=true`)
 	_data := map[string]any{
 		"accountName": a.Account,
 		"password":    a.Password,
@@ -181,12 +209,12 @@ func (a *Auth) SignIn() (session *AuthSession, err error) {
 		case AuthError409:
 			session.extractHeader(res)
 			if err1 := session.extractMobile(); err1 != nil {
-				err = fmt.Errorf(err.Error()+" %s", err1)
+				err = fmt.Errorf("%w %s", err, err1)
 			}
 			return
 		case AuthError412:
 			session.extractHeader(res, "X-Apple-Repair-Session-Token")
-			if err = session.accept(); err != nil {
+			if err = session.repair(); err != nil {
 				return
 			}
 		default:
@@ -206,7 +234,7 @@ func (a *AuthSession) CheckCode(code string) error {
 		_data map[string]any
 	)
 	if a.SelectMobile.Mode == "sms" {
-		_url = authBaseUrl + `/verify/phone/securitycode`
+		_url = a.Auth.authUrl(`/verify/phone/securitycode`)
 		_data = map[string]any{
 			"phoneNumber": map[string]int{
 				"id": a.SelectMobile.ID,
@@ -217,7 +245,7 @@ func (a *AuthSession) CheckCode(code string) error {
 			"mode": "sms",
 		}
 	} else {
-		_url = authBaseUrl + `/verify/trusteddevice/securitycode`
+		_url = a.Auth.authUrl(`/verify/trusteddevice/securitycode`)
 		_data = map[string]any{
 			"securityCode": map[string]string{
 				"code": code,
@@ -235,7 +263,7 @@ func (a *AuthSession) CheckCode(code string) error {
 
 // SendSMS 重新发送验证码
 func (a *AuthSession) SendSMS() error {
-	res, err := a.http().PutJson(authBaseUrl+"/verify/phone", map[string]interface{}{
+	res, err := a.http().PutJson(a.Auth.authUrl("/verify/phone"), map[string]interface{}{
 		"phoneNumber": map[string]int{"id": a.SelectMobile.ID},
 		"mode":        "sms",
 	})
@@ -248,7 +276,7 @@ func (a *AuthSession) SendSMS() error {
 	return err
 }
 func (a *AuthSession) trustCookie() error {
-	if res, err := a.http().Get(authBaseUrl+"/2sv/trust", nil); err != nil {
+	if res, err := a.http().Get(a.Auth.authUrl("/2sv/trust"), nil); err != nil {
 		return err
 	} else {
 		a.Auth.setHttpCookie(res.Cookies())
@@ -257,7 +285,7 @@ func (a *AuthSession) trustCookie() error {
 	return nil
 }
 func (a *AuthSession) accept() error {
-	res, err := a.http().PostJson(authBaseUrl+"/repair/complete", nil)
+	res, err := a.http().PostJson(a.Auth.authUrl("/repair/complete"), nil)
 	if err != nil {
 		return fmt.Errorf("complete %s", err)
 	}
@@ -328,7 +356,7 @@ func (a *AuthSession) extractHeader(res *httpclient.Response, h ...string) {
 	}
 }
 func (a *AuthSession) extractMobile() error {
-	res, err := a.http().Get(authBaseUrl)
+	res, err := a.http().Get(a.Auth.authUrl(""))
 	if err != nil {
 		return err
 	}
@@ -1,8 +1,11 @@
 package appleTools
 
 import (
+	"errors"
 	"fmt"
 	"github.com/dop251/goja"
+	"github.com/xml520/wqutils/appleTools/appleTest"
+	"strings"
 	"testing"
 )
 
@@ -53,3 +56,114 @@ func TestAuth_SignInV2(t *testing.T) {
 		fmt.Printf("%+v %+v", s, s.Mobiles)
 	}
 }
+
+func TestAuth_SignInFake(t *testing.T) {
+	srv := appleTest.NewIdmsaServer(&appleTest.Account{Account: "ok@test.com", Password: "pwd"})
+	defer srv.Close()
+	srv.HashcashBits = 8
+
+	a := &Auth{Account: "ok@test.com", Password: "pwd"}
+	a.SetBaseUrl(srv.URL)
+	if _, err := a.SignIn(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(a.Cookie, appleTest.SessionCookie+"=") {
+		t.Fatalf("登录后应保存cookie %s", a.Cookie)
+	}
+	b := &Auth{Account: "ok@test.com", Password: "pwd"}
+	b.SetBaseUrl(srv.URL)
+	if _, err := b.SignInV2(); err != nil {
+		t.Fatal(err)
+	}
+	// 获取挑战失败时不带 X-Apple-HC 继续登录
+	srv.HashcashBits, srv.HashcashStatus = 0, 503
+	d := &Auth{Account: "ok@test.com", Password: "pwd"}
+	d.SetBaseUrl(srv.URL)
+	if _, err := d.SignIn(); err != nil {
+		t.Fatalf("获取挑战失败不应中断登录 %v", err)
+	}
+	srv.HashcashStatus = 0
+	c := &Auth{Account: "ok@test.com", Password: "wrong"}
+	c.SetBaseUrl(srv.URL)
+	if _, err := c.SignIn(); err == nil || err.Error() != "您的 Apple ID 或密码输入有误。" {
+		t.Fatalf("期望密码错误 实际 %v", err)
+	}
+}
+
+func TestAuth_SignInFakeTwoFactor(t *testing.T) {
+	srv := appleTest.NewIdmsaServer(&appleTest.Account{
+		Account:  "2fa@test.com",
+		Password: "pwd",
+		Mode:     appleTest.SignInTwoFactor,
+		Phones:   []appleTest.Phone{{ID: 1, Number: "+86 ••• •••• ••12", Mode: "sms"}, {ID: 2, Number: "+1 (•••) •••-••34", Mode: "sms"}},
+		Code:     "654321",
+	})
+	defer srv.Close()
+
+	a := &Auth{Account: "2fa@test.com", Password: "pwd"}
+	a.SetBaseUrl(srv.URL)
+	s, err := a.SignIn()
+	if !errors.Is(err, AuthError409) {
+		t.Fatalf("期望 AuthError409 实际 %v", err)
+	}
+	if len(s.Mobiles) != 2 || s.SelectMobile == nil || s.SelectMobile.ID != 1 || s.SelectMobile.Mode != "sms" {
+		t.Fatalf("电话号码解析错误 %+v", s.Mobiles)
+	}
+	if err = s.SendSMS(); err != nil {
+		t.Fatal(err)
+	}
+	if srv.SMSSent("2fa@test.com") != 1 {
+		t.Fatal("未发送短信")
+	}
+	if err = s.CheckCode("000000"); err == nil || err.Error() != "验证码不正确" {
+		t.Fatalf("期望验证码错误 实际 %v", err)
+	}
+	if err = s.CheckCode("654321"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(a.Cookie, appleTest.TrustCookie+"=") {
+		t.Fatalf("验证后应保存信任cookie %s", a.Cookie)
+	}
+	// 带信任cookie再次登录不需要双重验证
+	if _, err = a.SignIn(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAuth_SignInFakeRepair(t *testing.T) {
+	srv := appleTest.NewIdmsaServer(
+		&appleTest.Account{Account: "privacy@test.com", Password: "pwd", Mode: appleTest.SignInRepair, RepairSteps: []string{"privacy_consent", "hsa2_enrollment"}},
+		&appleTest.Account{Account: "question@test.com", Password: "pwd", Mode: appleTest.SignInRepair, RepairSteps: []string{"security_questions"}},
+	)
+	defer srv.Close()
+
+	a := &Auth{Account: "privacy@test.com", Password: "pwd"}
+	a.SetBaseUrl(srv.URL)
+	if _, err := a.SignIn(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(a.Cookie, appleTest.SessionCookie+"=") {
+		t.Fatalf("修复完成后应保存cookie %s", a.Cookie)
+	}
+	b := &Auth{Account: "question@test.com", Password: "pwd"}
+	b.SetBaseUrl(srv.URL)
+	_, err := b.SignIn()
+	var repairErr *RepairRequired
+	if !errors.As(err, &repairErr) || repairErr.Actions[0] != RepairSecurityQuestions {
+		t.Fatalf("期望 RepairRequired 实际 %v", err)
+	}
+}
+
+func TestAuth_SignInFakeThrottle(t *testing.T) {
+	srv := appleTest.NewIdmsaServer(&appleTest.Account{Account: "busy@test.com", Password: "pwd", Throttle: 1})
+	defer srv.Close()
+
+	a := &Auth{Account: "busy@test.com", Password: "pwd"}
+	a.SetBaseUrl(srv.URL)
+	if _, err := a.SignIn(); err != AuthError503 {
+		t.Fatalf("期望 AuthError503 实际 %v", err)
+	}
+	if _, err := a.SignIn(); err != nil {
+		t.Fatal(err)
+	}
+}
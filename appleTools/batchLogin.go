@@ -0,0 +1,199 @@
+package appleTools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// LoginEventType 批量登录事件类型
+type LoginEventType int
+
+const (
+	LoginStart    LoginEventType = iota + 1 // 开始登录
+	LoginSuccess                            // 登录成功
+	LoginNeedCode                           // 需要双重验证 已加入验证码队列
+	LoginRetry                              // 登录太频繁 等待后重试
+	LoginFailed                             // 登录失败
+)
+
+// LoginEvent 批量登录进度
+type LoginEvent struct {
+	Type    LoginEventType
+	Auth    *Auth
+	Session *AuthSession
+	Err     error
+	Attempt int // 第几次尝试
+	Done    int // 已结束的账号数
+	Total   int
+}
+
+// BatchLoginResult 批量登录结果
+type BatchLoginResult struct {
+	Success  []*AuthSession
+	NeedCode []*AuthSession
+	Failed   map[*Auth]error
+}
+
+// BatchLogin 并发登录多个账号
+type BatchLogin struct {
+	Concurrency int                               // 并发数 默认5
+	Proxies     []string                          // 按顺序轮流分配给未设置代理的账号
+	IPs         []string                          // 按顺序轮流分配给未设置 AuthIP 的账号
+	MaxRetry    int                               // 遇到 AuthError503 的最大重试次数 默认3
+	Backoff     time.Duration                     // 首次重试等待时间 之后翻倍 默认1分钟
+	MaxBackoff  time.Duration                     // 最长等待时间 默认10分钟
+	Events      chan<- LoginEvent                 // 进度事件 可为空 需要及时读取 否则会阻塞登录
+	NeedCode    chan<- *AuthSession               // 需要输入验证码的会话队列 可为空 需要及时读取
+	SignIn      func(*Auth) (*AuthSession, error) // 默认 (*Auth).SignIn
+}
+
+// batchRun 单次 Run 的进度 多次 Run 互不影响
+type batchRun struct {
+	*BatchLogin
+	mu     sync.Mutex
+	done   int
+	result *BatchLoginResult
+}
+
+// Run 登录全部账号 ctx 取消后未开始的账号记为失败 单个账号 panic 时记为该账号失败
+func (b *BatchLogin) Run(ctx context.Context, auths []*Auth) *BatchLoginResult {
+	var (
+		wg     sync.WaitGroup
+		result = &BatchLoginResult{Failed: make(map[*Auth]error)}
+		limit  = make(chan struct{}, b.concurrency())
+		run    = &batchRun{BatchLogin: b, result: result}
+	)
+	for i, auth := range auths {
+		b.assign(i, auth)
+		select {
+		case limit <- struct{}{}:
+			// 取消和空位同时就绪时 select 随机选择 需要再检查一次
+			if ctx.Err() != nil {
+				<-limit
+				run.finish(ctx, &LoginEvent{Type: LoginFailed, Auth: auth, Err: ctx.Err(), Total: len(auths)})
+				continue
+			}
+		case <-ctx.Done():
+			run.finish(ctx, &LoginEvent{Type: LoginFailed, Auth: auth, Err: ctx.Err(), Total: len(auths)})
+			continue
+		}
+		wg.Add(1)
+		go func(auth *Auth) {
+			defer wg.Done()
+			e := run.safeLogin(ctx, auth, len(auths))
+			// 先释放并发位 发送事件时阻塞不影响其他账号登录
+			<-limit
+			run.finish(ctx, e)
+		}(auth)
+	}
+	wg.Wait()
+	return result
+}
+
+// assign 为账号分配代理和出口IP
+func (b *BatchLogin) assign(i int, auth *Auth) {
+	if auth.proxy == "" && len(b.Proxies) > 0 {
+		auth.SetProxy(b.Proxies[i%len(b.Proxies)])
+	}
+	if auth.AuthIP == "" && len(b.IPs) > 0 {
+		auth.SetWebIP(b.IPs[i%len(b.IPs)])
+	}
+}
+
+// safeLogin 登录过程中的 panic 转换为该账号的失败
+func (b *batchRun) safeLogin(ctx context.Context, auth *Auth, total int) (e *LoginEvent) {
+	defer func() {
+		if r := recover(); r != nil {
+			e = &LoginEvent{Type: LoginFailed, Auth: auth, Err: fmt.Errorf("登录异常 %v", r), Total: total}
+		}
+	}()
+	return b.login(ctx, auth, total)
+}
+func (b *batchRun) login(ctx context.Context, auth *Auth, total int) *LoginEvent {
+	signIn := b.SignIn
+	if signIn == nil {
+		signIn = (*Auth).SignIn
+	}
+	backoff := b.Backoff
+	if backoff <= 0 {
+		backoff = time.Minute
+	}
+	for attempt := 1; ; attempt++ {
+		b.emit(ctx, &LoginEvent{Type: LoginStart, Auth: auth, Attempt: attempt, Total: total})
+		session, err := signIn(auth)
+		switch {
+		case err == nil:
+			return &LoginEvent{Type: LoginSuccess, Auth: auth, Session: session, Attempt: attempt, Total: total}
+		case errors.Is(err, AuthError409):
+			return &LoginEvent{Type: LoginNeedCode, Auth: auth, Session: session, Err: err, Attempt: attempt, Total: total}
+		case errors.Is(err, AuthError503) && attempt <= b.maxRetry():
+			b.emit(ctx, &LoginEvent{Type: LoginRetry, Auth: auth, Err: err, Attempt: attempt, Total: total})
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return &LoginEvent{Type: LoginFailed, Auth: auth, Err: ctx.Err(), Attempt: attempt, Total: total}
+			}
+			if backoff *= 2; backoff > b.maxBackoff() {
+				backoff = b.maxBackoff()
+			}
+		default:
+			return &LoginEvent{Type: LoginFailed, Auth: auth, Session: session, Err: err, Attempt: attempt, Total: total}
+		}
+	}
+}
+func (b *batchRun) finish(ctx context.Context, e *LoginEvent) {
+	b.mu.Lock()
+	b.done++
+	e.Done = b.done
+	switch e.Type {
+	case LoginSuccess:
+		b.result.Success = append(b.result.Success, e.Session)
+	case LoginNeedCode:
+		b.result.NeedCode = append(b.result.NeedCode, e.Session)
+	default:
+		b.result.Failed[e.Auth] = e.Err
+	}
+	b.mu.Unlock()
+	if e.Type == LoginNeedCode && b.NeedCode != nil {
+		select {
+		case b.NeedCode <- e.Session:
+		case <-ctx.Done():
+		}
+	}
+	b.emit(ctx, e)
+}
+func (b *batchRun) emit(ctx context.Context, e *LoginEvent) {
+	if b.Events == nil {
+		return
+	}
+	if e.Done == 0 {
+		b.mu.Lock()
+		e.Done = b.done
+		b.mu.Unlock()
+	}
+	select {
+	case b.Events <- *e:
+	case <-ctx.Done():
+	}
+}
+func (b *BatchLogin) concurrency() int {
+	if b.Concurrency <= 0 {
+		return 5
+	}
+	return b.Concurrency
+}
+func (b *BatchLogin) maxRetry() int {
+	if b.MaxRetry <= 0 {
+		return 3
+	}
+	return b.MaxRetry
+}
+func (b *BatchLogin) maxBackoff() time.Duration {
+	if b.MaxBackoff <= 0 {
+		return 10 * time.Minute
+	}
+	return b.MaxBackoff
+}
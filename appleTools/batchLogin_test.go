@@ -0,0 +1,201 @@
+package appleTools
+
+import (
+	"context"
+	"errors"
+	"github.com/xml520/wqutils/appleTools/appleTest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBatchLogin_Run(t *testing.T) {
+	var (
+		running, peak int32
+		mu            sync.Mutex
+		calls         = make(map[string]int)
+	)
+	auths := []*Auth{{Account: "ok"}, {Account: "code"}, {Account: "busy"}, {Account: "bad"}, {Account: "ip", Web: Web{AuthIP: "10.0.0.9"}}}
+	events := make(chan LoginEvent, 100)
+	codes := make(chan *AuthSession, 1)
+	b := &BatchLogin{
+		Concurrency: 2,
+		Proxies:     []string{"http://p1", "http://p2"},
+		IPs:         []string{"10.0.0.1"},
+		Backoff:     time.Millisecond,
+		Events:      events,
+		NeedCode:    codes,
+		SignIn: func(a *Auth) (*AuthSession, error) {
+			if n := atomic.AddInt32(&running, 1); n > atomic.LoadInt32(&peak) {
+				atomic.StoreInt32(&peak, n)
+			}
+			defer atomic.AddInt32(&running, -1)
+			time.Sleep(5 * time.Millisecond)
+			mu.Lock()
+			calls[a.Account]++
+			n := calls[a.Account]
+			mu.Unlock()
+			s := &AuthSession{Auth: a}
+			switch a.Account {
+			case "code":
+				return s, AuthError409
+			case "busy":
+				if n < 3 {
+					return s, AuthError503
+				}
+			case "bad":
+				return s, errors.New("密码错误")
+			}
+			return s, nil
+		},
+	}
+	res := b.Run(context.Background(), auths)
+	close(events)
+
+	if peak > 2 {
+		t.Errorf("并发数超出限制 %d", peak)
+	}
+	if len(res.Success) != 3 || len(res.NeedCode) != 1 || len(res.Failed) != 1 {
+		t.Fatalf("结果错误 success=%d code=%d failed=%d", len(res.Success), len(res.NeedCode), len(res.Failed))
+	}
+	if calls["busy"] != 3 {
+		t.Errorf("503 应重试 实际登录 %d 次", calls["busy"])
+	}
+	if s := <-codes; s.Auth.Account != "code" {
+		t.Errorf("验证码队列错误 %s", s.Auth.Account)
+	}
+	if auths[0].proxy != "http://p1" || auths[1].proxy != "http://p2" || auths[0].AuthIP != "10.0.0.1" {
+		t.Errorf("代理分配错误 %s %s %s", auths[0].proxy, auths[1].proxy, auths[0].AuthIP)
+	}
+	if auths[4].AuthIP != "10.0.0.9" {
+		t.Errorf("已设置的IP不应被覆盖 %s", auths[4].AuthIP)
+	}
+	var retry, finished int
+	for e := range events {
+		switch e.Type {
+		case LoginRetry:
+			retry++
+		case LoginSuccess, LoginNeedCode, LoginFailed:
+			finished++
+			if e.Total != len(auths) || e.Done < 1 || e.Done > len(auths) {
+				t.Errorf("进度错误 %d/%d", e.Done, e.Total)
+			}
+		}
+	}
+	if retry != 2 || finished != len(auths) {
+		t.Errorf("事件数量错误 retry=%d finished=%d", retry, finished)
+	}
+}
+
+func TestBatchLogin_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	b := &BatchLogin{
+		Concurrency: 1,
+		Backoff:     time.Hour,
+		SignIn: func(a *Auth) (*AuthSession, error) {
+			cancel()
+			return &AuthSession{Auth: a}, AuthError503
+		},
+	}
+	res := b.Run(ctx, []*Auth{{Account: "a"}, {Account: "b"}})
+	if len(res.Failed) != 2 {
+		t.Fatalf("取消后应全部失败 %+v", res.Failed)
+	}
+	for _, err := range res.Failed {
+		if err != context.Canceled {
+			t.Errorf("期望 context.Canceled 实际 %v", err)
+		}
+	}
+}
+
+func TestBatchLogin_Fake(t *testing.T) {
+	srv := appleTest.NewIdmsaServer(
+		&appleTest.Account{Account: "a@test.com", Password: "pwd"},
+		&appleTest.Account{Account: "b@test.com", Password: "pwd", Throttle: 2},
+		&appleTest.Account{Account: "c@test.com", Password: "pwd", Mode: appleTest.SignInTwoFactor, Phones: []appleTest.Phone{{ID: 1, Number: "+86 ••12", Mode: "sms"}}},
+	)
+	defer srv.Close()
+
+	var auths []*Auth
+	for _, name := range []string{"a@test.com", "b@test.com", "c@test.com"} {
+		a := &Auth{Account: name, Password: "pwd"}
+		a.SetBaseUrl(srv.URL)
+		auths = append(auths, a)
+	}
+	b := &BatchLogin{Concurrency: 2, Backoff: time.Millisecond}
+	res := b.Run(context.Background(), auths)
+	if len(res.Success) != 2 || len(res.NeedCode) != 1 || len(res.Failed) != 0 {
+		t.Fatalf("结果错误 %+v", res)
+	}
+	if srv.SignIns("b@test.com") != 3 {
+		t.Errorf("503 应重试 实际登录 %d 次", srv.SignIns("b@test.com"))
+	}
+	if s := res.NeedCode[0]; s.Auth.Account != "c@test.com" || len(s.Mobiles) != 1 {
+		t.Errorf("验证码队列错误 %+v", s)
+	}
+}
+
+func TestBatchLogin_SlowConsumer(t *testing.T) {
+	codes := make(chan *AuthSession)
+	loggedIn := make(chan string, 2)
+	b := &BatchLogin{
+		Concurrency: 1,
+		NeedCode:    codes,
+		SignIn: func(a *Auth) (*AuthSession, error) {
+			if a.Account == "code" {
+				return &AuthSession{Auth: a}, AuthError409
+			}
+			loggedIn <- a.Account
+			return &AuthSession{Auth: a}, nil
+		},
+	}
+	done := make(chan *BatchLoginResult)
+	go func() {
+		done <- b.Run(context.Background(), []*Auth{{Account: "code"}, {Account: "a"}, {Account: "b"}})
+	}()
+	// 验证码队列未被读取时 其他账号仍然可以登录
+	for i := 0; i < 2; i++ {
+		select {
+		case <-loggedIn:
+		case <-time.After(time.Second):
+			t.Fatal("验证码队列阻塞了其他账号登录")
+		}
+	}
+	if s := <-codes; s.Auth.Account != "code" {
+		t.Errorf("验证码队列错误 %s", s.Auth.Account)
+	}
+	if res := <-done; len(res.Success) != 2 || len(res.NeedCode) != 1 {
+		t.Fatalf("结果错误 %+v", res)
+	}
+}
+
+func TestBatchLogin_Panic(t *testing.T) {
+	b := &BatchLogin{
+		Concurrency: 2,
+		SignIn: func(a *Auth) (*AuthSession, error) {
+			if a.Account == "bad" {
+				var mobiles []*authMobile
+				_ = mobiles[0]
+			}
+			return &AuthSession{Auth: a}, nil
+		},
+	}
+	auths := []*Auth{{Account: "bad"}, {Account: "ok"}}
+	// 同一个 BatchLogin 并发 Run 进度互不影响
+	var wg sync.WaitGroup
+	results := make([]*BatchLoginResult, 2)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = b.Run(context.Background(), auths)
+		}(i)
+	}
+	wg.Wait()
+	for _, res := range results {
+		if len(res.Success) != 1 || res.Failed[auths[0]] == nil {
+			t.Fatalf("panic 应记为该账号失败 %+v", res)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package appleTools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	BuildStateNotFound   = "NOT_FOUND" // 上传后构建版本还未出现在列表中
+	BuildStateProcessing = "PROCESSING"
+	BuildStateValid      = "VALID"
+	BuildStateInvalid    = "INVALID"
+	BuildStateFailed     = "FAILED"
+)
+
+// BuildStateChange 构建版本处理状态变化
+type BuildStateChange struct {
+	State string
+	Build *Build // NOT_FOUND 时为 nil
+	Time  time.Time
+}
+
+// BuildProcessingError 构建版本处理失败 INVALID 或 FAILED
+type BuildProcessingError struct {
+	State string
+	Build *Build
+}
+
+func (e *BuildProcessingError) Error() string {
+	return fmt.Sprintf("构建版本 %s 处理失败 %s", e.Build.Attributes.Version, e.State)
+}
+
+// WaitBuildOptions 等待构建版本处理参数
+type WaitBuildOptions struct {
+	AppID       string                  // 应用ID
+	Version     string                  // 版本号 CFBundleShortVersionString 可为空
+	BuildNumber string                  // 构建号 CFBundleVersion
+	Interval    time.Duration           // 轮询间隔 默认30秒
+	Timeout     time.Duration           // 超时时间 默认1小时
+	Changes     chan<- BuildStateChange // 状态变化 可为空
+}
+
+// WaitForBuild 轮询构建版本直到处理完成 VALID 返回构建版本 INVALID/FAILED 返回 BuildProcessingError
+// 网络错误和5xx会继续轮询 其他接口错误直接返回
+func (a *Api) WaitForBuild(ctx context.Context, opt WaitBuildOptions) (*Build, error) {
+	if opt.AppID == "" || opt.BuildNumber == "" {
+		return nil, errors.New("应用ID和构建号不能为空")
+	}
+	if opt.Interval <= 0 {
+		opt.Interval = 30 * time.Second
+	}
+	if opt.Timeout <= 0 {
+		opt.Timeout = time.Hour
+	}
+	ctx, cancel := context.WithTimeout(ctx, opt.Timeout)
+	defer cancel()
+	q := NewApiQuery().Filter("app", opt.AppID).Filter("version", opt.BuildNumber).Sort("-uploadedDate").Limit(1)
+	if opt.Version != "" {
+		q.Filter("preReleaseVersion.version", opt.Version)
+	}
+	var (
+		last  string
+		query = q.Values()
+	)
+	for {
+		var doc ApiDocument[[]Build]
+		err := a.request(ctx, "GET", "builds", query, nil, &doc)
+		var apiErr *ApiErrors
+		if errors.As(err, &apiErr) && apiErr.StatusCode < 500 {
+			return nil, err
+		}
+		if err == nil {
+			state, build := BuildStateNotFound, (*Build)(nil)
+			if len(doc.Data) > 0 {
+				build = &doc.Data[0]
+				if state = build.Attributes.ProcessingState; state == "" {
+					state = BuildStateProcessing
+				}
+			}
+			if state != last {
+				last = state
+				if opt.Changes != nil {
+					select {
+					case opt.Changes <- BuildStateChange{State: state, Build: build, Time: time.Now()}:
+					case <-ctx.Done():
+					}
+				}
+			}
+			switch state {
+			case BuildStateValid:
+				return build, nil
+			case BuildStateInvalid, BuildStateFailed:
+				return build, &BuildProcessingError{State: state, Build: build}
+			}
+		}
+		select {
+		case <-time.After(opt.Interval):
+		case <-ctx.Done():
+			return nil, fmt.Errorf("等待构建版本 %s 处理%s 最后状态 %s %w", opt.BuildNumber, waitStopped(ctx.Err()), last, ctx.Err())
+		}
+	}
+}
+
+// waitStopped 区分轮询是被调用方取消还是等待超时
+func waitStopped(err error) string {
+	if errors.Is(err, context.Canceled) {
+		return "已取消"
+	}
+	return "超时"
+}
@@ -0,0 +1,92 @@
+package appleTools
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestApi_WaitForBuild(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		step   int
+		states = []string{"", "", "PROCESSING", "500", "PROCESSING", "VALID"}
+	)
+	// 超时或取消后服务端可能仍在处理上一个请求
+	reset := func(s ...string) {
+		mu.Lock()
+		step, states = 0, s
+		mu.Unlock()
+	}
+	api := newTestApi(t, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("filter[app]") != "app1" || q.Get("filter[version]") != "12" || q.Get("filter[preReleaseVersion.version]") != "1.2" {
+			t.Errorf("构建版本筛选错误 %s", r.URL.RawQuery)
+		}
+		mu.Lock()
+		state := states[step]
+		if step < len(states)-1 {
+			step++
+		}
+		mu.Unlock()
+		switch state {
+		case "":
+			writeTestJson(w, 200, `{"data":[]}`)
+		case "500":
+			writeTestJson(w, 500, `{"errors":[{"status":"500","detail":"busy"}]}`)
+		case "bad":
+			writeTestJson(w, 403, `{"errors":[{"status":"403","detail":"forbidden"}]}`)
+		default:
+			writeTestJson(w, 200, `{"data":[{"type":"builds","id":"b1","attributes":{"version":"12","processingState":"`+state+`"}}]}`)
+		}
+	})
+	changes := make(chan BuildStateChange, 10)
+	build, err := api.WaitForBuild(context.Background(), WaitBuildOptions{
+		AppID: "app1", Version: "1.2", BuildNumber: "12", Interval: time.Millisecond, Changes: changes,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if build.ID != "b1" {
+		t.Fatalf("构建版本错误 %+v", build)
+	}
+	close(changes)
+	var got []string
+	for c := range changes {
+		got = append(got, c.State)
+	}
+	if len(got) != 3 || got[0] != BuildStateNotFound || got[1] != BuildStateProcessing || got[2] != BuildStateValid {
+		t.Fatalf("状态变化错误 %v", got)
+	}
+
+	reset("PROCESSING", "INVALID")
+	_, err = api.WaitForBuild(context.Background(), WaitBuildOptions{AppID: "app1", Version: "1.2", BuildNumber: "12", Interval: time.Millisecond})
+	var perr *BuildProcessingError
+	if !errors.As(err, &perr) || perr.State != BuildStateInvalid {
+		t.Fatalf("应返回处理失败 %v", err)
+	}
+
+	reset("")
+	_, err = api.WaitForBuild(context.Background(), WaitBuildOptions{AppID: "app1", Version: "1.2", BuildNumber: "12", Interval: time.Millisecond, Timeout: 20 * time.Millisecond})
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "处理超时") {
+		t.Fatalf("应超时 %v", err)
+	}
+
+	// 调用方取消不应报告为超时
+	reset("")
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	_, err = api.WaitForBuild(ctx, WaitBuildOptions{AppID: "app1", Version: "1.2", BuildNumber: "12", Interval: time.Millisecond})
+	if !errors.Is(err, context.Canceled) || !strings.Contains(err.Error(), "处理已取消") {
+		t.Fatalf("应返回已取消 %v", err)
+	}
+
+	reset("bad")
+	if _, err = api.WaitForBuild(context.Background(), WaitBuildOptions{AppID: "app1", Version: "1.2", BuildNumber: "12", Interval: time.Millisecond}); !IsApiStatus(err, 403) {
+		t.Fatalf("应直接返回接口错误 %v", err)
+	}
+}
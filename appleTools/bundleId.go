@@ -0,0 +1,152 @@
+package appleTools
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+const (
+	CapabilityPushNotifications = "PUSH_NOTIFICATIONS"
+	CapabilityAppGroups         = "APP_GROUPS"
+	CapabilityAssociatedDomains = "ASSOCIATED_DOMAINS"
+	CapabilityICloud            = "ICLOUD"
+	CapabilityInAppPurchase     = "IN_APP_PURCHASE"
+	CapabilityGameCenter        = "GAME_CENTER"
+)
+
+// ICloudSetting iCloud 设置 XCODE_5 兼容旧版 XCODE_6 使用 CloudKit
+func ICloudSetting(version string) CapabilitySetting {
+	return CapabilitySetting{Key: "ICLOUD_VERSION", Options: []CapabilityOption{{Key: version, Enabled: true}}}
+}
+
+// BundleIDSpec 套装ID期望状态
+type BundleIDSpec struct {
+	Name         string
+	Identifier   string
+	Platform     string                         // 默认 IOS
+	Capabilities map[string][]CapabilitySetting // 功能类型 => 设置 无设置时为 nil
+	Prune        bool                           // 关闭 Capabilities 之外已开启的功能
+	DryRun       bool                           // 只计算变更不提交
+}
+
+// BundleIDPlan 期望状态与现有状态的差异
+type BundleIDPlan struct {
+	BundleID *BundleID
+	Created  bool     // 套装ID是新建的
+	Enable   []string // 需要开启的功能
+	Update   []string // 设置不一致需要修改的功能
+	Disable  []string // 需要关闭的功能
+}
+
+// Changed 是否有变更
+func (p *BundleIDPlan) Changed() bool {
+	return p.Created || len(p.Enable)+len(p.Update)+len(p.Disable) > 0
+}
+
+// EnsureBundleID 使套装ID及其功能达到期望状态 只提交有差异的部分 重复调用不会产生变更
+func (a *Api) EnsureBundleID(spec BundleIDSpec) (*BundleIDPlan, error) {
+	if spec.Identifier == "" {
+		return nil, errors.New("套装ID不能为空")
+	}
+	if spec.Platform == "" {
+		spec.Platform = "IOS"
+	}
+	if spec.Name == "" {
+		spec.Name = spec.Identifier
+	}
+	plan := &BundleIDPlan{}
+	bundle, err := a.lookupBundleID(spec.Identifier)
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]BundleIDCapability)
+	if bundle == nil {
+		plan.Created = true
+		if !spec.DryRun {
+			if bundle, err = a.CreateBundleID(BundleIDAttributes{Name: spec.Name, Identifier: spec.Identifier, Platform: spec.Platform}); err != nil {
+				return nil, fmt.Errorf("创建套装ID失败 %w", err)
+			}
+		}
+	}
+	// 新建的 iOS 套装ID 默认已开启内购和 Game Center 同样以服务端返回为准
+	if bundle != nil {
+		caps, err := a.ListBundleIDCapabilities(bundle.ID, NewApiQuery().Limit(200).Values())
+		if err != nil {
+			return nil, fmt.Errorf("获取功能列表失败 %w", err)
+		}
+		for _, c := range caps.Data {
+			existing[c.Attributes.CapabilityType] = c
+		}
+	}
+	plan.BundleID = bundle
+	for _, typ := range sortedKeys(spec.Capabilities) {
+		c, ok := existing[typ]
+		switch {
+		case !ok:
+			plan.Enable = append(plan.Enable, typ)
+		case spec.Capabilities[typ] != nil && !sameSettings(c.Attributes.Settings, spec.Capabilities[typ]):
+			plan.Update = append(plan.Update, typ)
+		}
+	}
+	if spec.Prune {
+		for typ := range existing {
+			if _, ok := spec.Capabilities[typ]; !ok {
+				plan.Disable = append(plan.Disable, typ)
+			}
+		}
+		sort.Strings(plan.Disable)
+	}
+	if spec.DryRun {
+		return plan, nil
+	}
+	for _, typ := range plan.Enable {
+		if _, err = a.EnableCapability(bundle.ID, typ, spec.Capabilities[typ]...); err != nil {
+			return plan, fmt.Errorf("开启 %s 失败 %w", typ, err)
+		}
+	}
+	for _, typ := range plan.Update {
+		if _, err = a.UpdateCapability(existing[typ].ID, typ, spec.Capabilities[typ]...); err != nil {
+			return plan, fmt.Errorf("修改 %s 失败 %w", typ, err)
+		}
+	}
+	for _, typ := range plan.Disable {
+		if err = a.DisableCapability(existing[typ].ID); err != nil {
+			return plan, fmt.Errorf("关闭 %s 失败 %w", typ, err)
+		}
+	}
+	return plan, nil
+}
+
+// sameSettings 比较已开启的选项 忽略顺序和名称描述
+func sameSettings(a, b []CapabilitySetting) bool {
+	x, y := enabledOptions(a), enabledOptions(b)
+	if len(x) != len(y) {
+		return false
+	}
+	for k, v := range x {
+		if y[k] != v {
+			return false
+		}
+	}
+	return true
+}
+func enabledOptions(settings []CapabilitySetting) map[string]bool {
+	m := make(map[string]bool)
+	for _, s := range settings {
+		for _, o := range s.Options {
+			if o.Enabled {
+				m[s.Key+"/"+o.Key] = true
+			}
+		}
+	}
+	return m
+}
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
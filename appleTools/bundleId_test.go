@@ -0,0 +1,140 @@
+package appleTools
+
+import (
+	"fmt"
+	"github.com/tidwall/gjson"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeBundleIDs 内存中的套装ID和功能
+type fakeBundleIDs struct {
+	mu      sync.Mutex
+	bundles map[string]string // id => identifier
+	caps    map[string]string // capability id => 资源 json
+	owner   map[string]string // capability id => bundle id
+	writes  []string
+	seq     int
+}
+
+func (f *fakeBundleIDs) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	body, _ := io.ReadAll(r.Body)
+	if r.Method != "GET" {
+		f.writes = append(f.writes, r.Method+" "+r.URL.Path)
+	}
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/v1/bundleIds":
+		var list []string
+		for id, identifier := range f.bundles {
+			if identifier == r.URL.Query().Get("filter[identifier]") {
+				list = append(list, `{"type":"bundleIds","id":"`+id+`","attributes":{"identifier":"`+identifier+`"}}`)
+			}
+		}
+		writeTestJson(w, 200, `{"data":[`+strings.Join(list, ",")+`]}`)
+	case r.Method == "POST" && r.URL.Path == "/v1/bundleIds":
+		f.seq++
+		id := fmt.Sprintf("B%d", f.seq)
+		f.bundles[id] = gjson.GetBytes(body, "data.attributes.identifier").String()
+		// 新建的 iOS 套装ID 默认开启内购和 Game Center
+		for _, typ := range []string{CapabilityInAppPurchase, CapabilityGameCenter} {
+			f.seq++
+			cid := fmt.Sprintf("C%d", f.seq)
+			f.owner[cid] = id
+			f.caps[cid] = `{"type":"bundleIdCapabilities","id":"` + cid + `","attributes":{"capabilityType":"` + typ + `"}}`
+		}
+		writeTestJson(w, 201, `{"data":{"type":"bundleIds","id":"`+id+`","attributes":{"identifier":"`+f.bundles[id]+`"}}}`)
+	case r.Method == "GET" && strings.HasSuffix(r.URL.Path, "/bundleIdCapabilities"):
+		bid := strings.Split(r.URL.Path, "/")[3]
+		var list []string
+		for id, c := range f.caps {
+			if f.owner[id] == bid {
+				list = append(list, c)
+			}
+		}
+		writeTestJson(w, 200, `{"data":[`+strings.Join(list, ",")+`]}`)
+	case r.Method == "POST" && r.URL.Path == "/v1/bundleIdCapabilities":
+		bid, typ := gjson.GetBytes(body, "data.relationships.bundleId.data.id").String(), gjson.GetBytes(body, "data.attributes.capabilityType").String()
+		for id, c := range f.caps {
+			if f.owner[id] == bid && gjson.Get(c, "attributes.capabilityType").String() == typ {
+				writeTestJson(w, 409, `{"errors":[{"status":"409","detail":"capability already enabled"}]}`)
+				return
+			}
+		}
+		f.seq++
+		id := fmt.Sprintf("C%d", f.seq)
+		f.owner[id] = bid
+		f.caps[id] = `{"type":"bundleIdCapabilities","id":"` + id + `","attributes":` + gjson.GetBytes(body, "data.attributes").Raw + `}`
+		writeTestJson(w, 201, `{"data":`+f.caps[id]+`}`)
+	case r.Method == "PATCH" && strings.HasPrefix(r.URL.Path, "/v1/bundleIdCapabilities/"):
+		id := strings.TrimPrefix(r.URL.Path, "/v1/bundleIdCapabilities/")
+		f.caps[id] = `{"type":"bundleIdCapabilities","id":"` + id + `","attributes":` + gjson.GetBytes(body, "data.attributes").Raw + `}`
+		writeTestJson(w, 200, `{"data":`+f.caps[id]+`}`)
+	case r.Method == "DELETE" && strings.HasPrefix(r.URL.Path, "/v1/bundleIdCapabilities/"):
+		delete(f.caps, strings.TrimPrefix(r.URL.Path, "/v1/bundleIdCapabilities/"))
+		w.WriteHeader(204)
+	default:
+		writeTestJson(w, 404, `{"errors":[{"status":"404","detail":"not found"}]}`)
+	}
+}
+
+func TestApi_EnsureBundleID(t *testing.T) {
+	fake := &fakeBundleIDs{bundles: map[string]string{}, caps: map[string]string{}, owner: map[string]string{}}
+	api := newTestApi(t, fake.ServeHTTP)
+	spec := BundleIDSpec{
+		Identifier: "com.demo.white",
+		Capabilities: map[string][]CapabilitySetting{
+			CapabilityPushNotifications: nil,
+			CapabilityAppGroups:         nil,
+			CapabilityICloud:            {ICloudSetting("XCODE_5")},
+			CapabilityInAppPurchase:     nil,
+		},
+		DryRun: true,
+	}
+	plan, err := api.EnsureBundleID(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.Created || len(plan.Enable) != 4 || len(fake.writes) != 0 {
+		t.Fatalf("预览不应提交变更 %+v %v", plan, fake.writes)
+	}
+
+	spec.DryRun = false
+	if plan, err = api.EnsureBundleID(spec); err != nil {
+		t.Fatal(err)
+	}
+	// 默认开启的内购不重复开启
+	if !plan.Created || plan.BundleID == nil || len(plan.Enable) != 3 || len(fake.writes) != 4 || len(fake.caps) != 5 {
+		t.Fatalf("创建套装ID错误 %+v %v", plan, fake.writes)
+	}
+
+	if plan, err = api.EnsureBundleID(spec); err != nil {
+		t.Fatal(err)
+	}
+	if plan.Changed() || len(fake.writes) != 4 {
+		t.Fatalf("重复调用不应产生变更 %+v %v", plan, fake.writes)
+	}
+
+	spec.Capabilities = map[string][]CapabilitySetting{
+		CapabilityICloud:            {ICloudSetting("XCODE_6")},
+		CapabilityAssociatedDomains: nil,
+	}
+	spec.Prune = true
+	if plan, err = api.EnsureBundleID(spec); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(plan.Enable, ",") != CapabilityAssociatedDomains || strings.Join(plan.Update, ",") != CapabilityICloud ||
+		strings.Join(plan.Disable, ",") != "APP_GROUPS,GAME_CENTER,IN_APP_PURCHASE,PUSH_NOTIFICATIONS" {
+		t.Fatalf("差异计算错误 %+v", plan)
+	}
+	if len(fake.caps) != 2 {
+		t.Fatalf("功能数量错误 %v", fake.caps)
+	}
+	if plan, err = api.EnsureBundleID(spec); err != nil || plan.Changed() {
+		t.Fatalf("应已达到期望状态 %+v %v", plan, err)
+	}
+}
@@ -0,0 +1,362 @@
+package appleTools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// IapCatalog 内购和订阅目录 可从 YAML 或 JSON 文件读取
+type IapCatalog struct {
+	InAppPurchases     []CatalogProduct `json:"inAppPurchases" yaml:"inAppPurchases"`
+	SubscriptionGroups []CatalogGroup   `json:"subscriptionGroups" yaml:"subscriptionGroups"`
+}
+
+// CatalogLocalization 多语言 CustomAppName 仅订阅组使用
+type CatalogLocalization struct {
+	Name          string `json:"name" yaml:"name"`
+	Description   string `json:"description,omitempty" yaml:"description,omitempty"`
+	CustomAppName string `json:"customAppName,omitempty" yaml:"customAppName,omitempty"`
+}
+
+// CatalogPrice 某个国家的价格 CustomerPrice 为用户支付的价格 如 0.99
+type CatalogPrice struct {
+	Territory     string `json:"territory" yaml:"territory"`
+	CustomerPrice string `json:"customerPrice" yaml:"customerPrice"`
+}
+
+// CatalogProduct 内购项目
+type CatalogProduct struct {
+	ProductID      string                         `json:"productId" yaml:"productId"`
+	Name           string                         `json:"name" yaml:"name"`
+	Type           string                         `json:"type" yaml:"type"` // CONSUMABLE NON_CONSUMABLE NON_RENEWING_SUBSCRIPTION
+	ReviewNote     string                         `json:"reviewNote,omitempty" yaml:"reviewNote,omitempty"`
+	FamilySharable bool                           `json:"familySharable,omitempty" yaml:"familySharable,omitempty"`
+	Localizations  map[string]CatalogLocalization `json:"localizations,omitempty" yaml:"localizations,omitempty"`
+	Price          *CatalogPrice                  `json:"price,omitempty" yaml:"price,omitempty"` // 基准国家价格
+	Territories    []string                       `json:"territories,omitempty" yaml:"territories,omitempty"`
+}
+
+// CatalogGroup 订阅组
+type CatalogGroup struct {
+	ReferenceName string                         `json:"referenceName" yaml:"referenceName"`
+	Localizations map[string]CatalogLocalization `json:"localizations,omitempty" yaml:"localizations,omitempty"`
+	Subscriptions []CatalogSubscription          `json:"subscriptions" yaml:"subscriptions"`
+}
+
+// CatalogSubscription 订阅
+type CatalogSubscription struct {
+	ProductID      string                         `json:"productId" yaml:"productId"`
+	Name           string                         `json:"name" yaml:"name"`
+	Period         string                         `json:"period" yaml:"period"` // ONE_WEEK ONE_MONTH ... ONE_YEAR
+	GroupLevel     int                            `json:"groupLevel,omitempty" yaml:"groupLevel,omitempty"`
+	ReviewNote     string                         `json:"reviewNote,omitempty" yaml:"reviewNote,omitempty"`
+	FamilySharable bool                           `json:"familySharable,omitempty" yaml:"familySharable,omitempty"`
+	Localizations  map[string]CatalogLocalization `json:"localizations,omitempty" yaml:"localizations,omitempty"`
+	Prices         []CatalogPrice                 `json:"prices,omitempty" yaml:"prices,omitempty"`
+	Territories    []string                       `json:"territories,omitempty" yaml:"territories,omitempty"`
+}
+
+// LoadIapCatalog 读取目录文件 .yaml .yml 按 YAML 解析 其他按 JSON 解析
+func LoadIapCatalog(file string) (*IapCatalog, error) {
+	buf, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	c := &IapCatalog{}
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(buf, c)
+	default:
+		err = json.Unmarshal(buf, c)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("目录文件解析失败 %s", err)
+	}
+	return c, nil
+}
+
+// CatalogSyncOptions 同步参数
+type CatalogSyncOptions struct {
+	DryRun bool // 只列出需要的操作
+	Prices bool // 已存在的项目也重新设置价格和销售范围 默认只在创建时设置
+}
+
+// CatalogSync 目录同步
+type CatalogSync struct {
+	api     *Api
+	opt     CatalogSyncOptions
+	Actions []string // 已执行(或 DryRun 时需要执行)的操作
+}
+
+// SyncIapCatalog 使应用的内购和订阅与目录一致 只创建和修改 不删除目录外的项目
+func (a *Api) SyncIapCatalog(appID string, catalog *IapCatalog, opt CatalogSyncOptions) (*CatalogSync, error) {
+	s := &CatalogSync{api: a, opt: opt}
+	ctx := context.Background()
+	iaps, err := NewApiIterator[InAppPurchaseAttributes](a, "apps/"+appID+"/inAppPurchasesV2", NewApiQuery().Limit(200).Values()).All(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取内购列表失败 %w", err)
+	}
+	byProduct := make(map[string]InAppPurchase)
+	for _, p := range iaps {
+		byProduct[p.Attributes.ProductID] = p
+	}
+	for _, p := range catalog.InAppPurchases {
+		if err = s.syncProduct(appID, p, byProduct); err != nil {
+			return s, fmt.Errorf("%s %w", p.ProductID, err)
+		}
+	}
+	groups, err := NewApiIterator[SubscriptionGroupAttributes](a, "apps/"+appID+"/subscriptionGroups", NewApiQuery().Limit(200).Values()).All(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取订阅组失败 %w", err)
+	}
+	byName := make(map[string]SubscriptionGroup)
+	for _, g := range groups {
+		byName[g.Attributes.ReferenceName] = g
+	}
+	for _, g := range catalog.SubscriptionGroups {
+		if err = s.syncGroup(appID, g, byName); err != nil {
+			return s, fmt.Errorf("%s %w", g.ReferenceName, err)
+		}
+	}
+	return s, nil
+}
+
+// do 记录操作 DryRun 时不执行
+func (s *CatalogSync) do(action string, fn func() error) error {
+	s.Actions = append(s.Actions, action)
+	if s.opt.DryRun {
+		return nil
+	}
+	return fn()
+}
+
+func (s *CatalogSync) syncProduct(appID string, p CatalogProduct, existing map[string]InAppPurchase) error {
+	a := s.api
+	attr := InAppPurchaseAttributes{Name: p.Name, ProductID: p.ProductID, InAppPurchaseType: p.Type, ReviewNote: p.ReviewNote, FamilySharable: &p.FamilySharable}
+	cur, ok := existing[p.ProductID]
+	id := cur.ID
+	if !ok {
+		err := s.do("创建内购 "+p.ProductID, func() error {
+			res, err := a.CreateInAppPurchase(appID, attr)
+			if err == nil {
+				id = res.ID
+			}
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	} else if cur.Attributes.Name != attr.Name || cur.Attributes.ReviewNote != attr.ReviewNote || isTrue(cur.Attributes.FamilySharable) != p.FamilySharable {
+		if err := s.do("修改内购 "+p.ProductID, func() error {
+			_, err := a.UpdateInAppPurchase(id, attr)
+			return err
+		}); err != nil {
+			return err
+		}
+	}
+	err := s.syncLocalizations("内购 "+p.ProductID, id, p.Localizations,
+		func() (*ApiDocument[[]IapLocalization], error) { return a.ListInAppPurchaseLocalizations(id, nil) },
+		func(attr IapLocalizationAttributes) error {
+			_, err := a.CreateInAppPurchaseLocalization(id, attr)
+			return err
+		},
+		func(lid string, attr IapLocalizationAttributes) error {
+			_, err := a.UpdateInAppPurchaseLocalization(lid, attr)
+			return err
+		})
+	if err != nil {
+		return err
+	}
+	if ok && !s.opt.Prices {
+		return nil
+	}
+	if p.Price != nil {
+		if err = s.do(fmt.Sprintf("设置内购价格 %s %s %s", p.ProductID, p.Price.Territory, p.Price.CustomerPrice), func() error {
+			point, err := findPricePoint(a, "/v2/inAppPurchases/"+id+"/pricePoints", *p.Price)
+			if err != nil {
+				return err
+			}
+			return a.SetInAppPurchasePrice(id, p.Price.Territory, point)
+		}); err != nil {
+			return err
+		}
+	}
+	if len(p.Territories) > 0 {
+		return s.do("设置内购销售范围 "+p.ProductID, func() error {
+			return a.SetInAppPurchaseAvailability(id, p.Territories, false)
+		})
+	}
+	return nil
+}
+
+func (s *CatalogSync) syncGroup(appID string, g CatalogGroup, existing map[string]SubscriptionGroup) error {
+	a := s.api
+	cur, ok := existing[g.ReferenceName]
+	id := cur.ID
+	if !ok {
+		if err := s.do("创建订阅组 "+g.ReferenceName, func() error {
+			res, err := a.CreateSubscriptionGroup(appID, g.ReferenceName)
+			if err == nil {
+				id = res.ID
+			}
+			return err
+		}); err != nil {
+			return err
+		}
+	}
+	err := s.syncLocalizations("订阅组 "+g.ReferenceName, id, g.Localizations,
+		func() (*ApiDocument[[]IapLocalization], error) { return a.ListSubscriptionGroupLocalizations(id, nil) },
+		func(attr IapLocalizationAttributes) error {
+			_, err := a.CreateSubscriptionGroupLocalization(id, attr)
+			return err
+		},
+		func(lid string, attr IapLocalizationAttributes) error {
+			_, err := a.UpdateSubscriptionGroupLocalization(lid, attr)
+			return err
+		})
+	if err != nil {
+		return err
+	}
+	subs := make(map[string]Subscription)
+	if id != "" {
+		list, err := NewApiIterator[SubscriptionAttributes](a, "subscriptionGroups/"+id+"/subscriptions", NewApiQuery().Limit(200).Values()).All(context.Background())
+		if err != nil {
+			return err
+		}
+		for _, sub := range list {
+			subs[sub.Attributes.ProductID] = sub
+		}
+	}
+	for _, sub := range g.Subscriptions {
+		if err = s.syncSubscription(id, sub, subs); err != nil {
+			return fmt.Errorf("%s %w", sub.ProductID, err)
+		}
+	}
+	return nil
+}
+
+func (s *CatalogSync) syncSubscription(groupID string, p CatalogSubscription, existing map[string]Subscription) error {
+	a := s.api
+	attr := SubscriptionAttributes{Name: p.Name, ProductID: p.ProductID, SubscriptionPeriod: p.Period, GroupLevel: p.GroupLevel,
+		ReviewNote: p.ReviewNote, FamilySharable: &p.FamilySharable}
+	cur, ok := existing[p.ProductID]
+	id := cur.ID
+	if !ok {
+		if err := s.do("创建订阅 "+p.ProductID, func() error {
+			res, err := a.CreateSubscription(groupID, attr)
+			if err == nil {
+				id = res.ID
+			}
+			return err
+		}); err != nil {
+			return err
+		}
+	} else if c := cur.Attributes; c.Name != attr.Name || c.SubscriptionPeriod != attr.SubscriptionPeriod || c.ReviewNote != attr.ReviewNote ||
+		isTrue(c.FamilySharable) != p.FamilySharable || (attr.GroupLevel > 0 && c.GroupLevel != attr.GroupLevel) {
+		if err := s.do("修改订阅 "+p.ProductID, func() error {
+			_, err := a.UpdateSubscription(id, attr)
+			return err
+		}); err != nil {
+			return err
+		}
+	}
+	err := s.syncLocalizations("订阅 "+p.ProductID, id, p.Localizations,
+		func() (*ApiDocument[[]IapLocalization], error) { return a.ListSubscriptionLocalizations(id, nil) },
+		func(attr IapLocalizationAttributes) error {
+			_, err := a.CreateSubscriptionLocalization(id, attr)
+			return err
+		},
+		func(lid string, attr IapLocalizationAttributes) error {
+			_, err := a.UpdateSubscriptionLocalization(lid, attr)
+			return err
+		})
+	if err != nil {
+		return err
+	}
+	if ok && !s.opt.Prices {
+		return nil
+	}
+	for _, price := range p.Prices {
+		price := price
+		if err = s.do(fmt.Sprintf("设置订阅价格 %s %s %s", p.ProductID, price.Territory, price.CustomerPrice), func() error {
+			point, err := findPricePoint(a, "subscriptions/"+id+"/pricePoints", price)
+			if err != nil {
+				return err
+			}
+			return a.SetSubscriptionPrice(id, price.Territory, point, "")
+		}); err != nil {
+			return err
+		}
+	}
+	if len(p.Territories) > 0 {
+		return s.do("设置订阅销售范围 "+p.ProductID, func() error {
+			return a.SetSubscriptionAvailability(id, p.Territories, false)
+		})
+	}
+	return nil
+}
+
+// syncLocalizations 新增缺少的语言 修改内容不一致的语言 id 为空表示父资源尚未创建
+func (s *CatalogSync) syncLocalizations(name, id string, want map[string]CatalogLocalization,
+	list func() (*ApiDocument[[]IapLocalization], error),
+	create func(IapLocalizationAttributes) error,
+	update func(string, IapLocalizationAttributes) error) error {
+	if len(want) == 0 {
+		return nil
+	}
+	existing := make(map[string]IapLocalization)
+	if id != "" {
+		doc, err := list()
+		if err != nil {
+			return err
+		}
+		for _, l := range doc.Data {
+			existing[l.Attributes.Locale] = l
+		}
+	}
+	for _, locale := range sortedKeys(want) {
+		w := want[locale]
+		attr := IapLocalizationAttributes{Locale: locale, Name: w.Name, Description: w.Description, CustomAppName: w.CustomAppName}
+		cur, ok := existing[locale]
+		var err error
+		switch {
+		case !ok:
+			err = s.do(fmt.Sprintf("添加%s语言 %s", name, locale), func() error { return create(attr) })
+		case cur.Attributes.Name != w.Name || cur.Attributes.Description != w.Description || cur.Attributes.CustomAppName != w.CustomAppName:
+			err = s.do(fmt.Sprintf("修改%s语言 %s", name, locale), func() error { return update(cur.ID, attr) })
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findPricePoint 在指定国家的价格点中查找用户价格相同的价格点
+func findPricePoint(a *Api, path string, price CatalogPrice) (string, error) {
+	want, err := strconv.ParseFloat(price.CustomerPrice, 64)
+	if err != nil {
+		return "", fmt.Errorf("价格 %s 格式错误", price.CustomerPrice)
+	}
+	it := NewApiIterator[PricePointAttributes](a, path, NewApiQuery().Filter("territory", price.Territory).Limit(200).Values())
+	for it.Next(context.Background()) {
+		if v, err := strconv.ParseFloat(it.Item().Attributes.CustomerPrice, 64); err == nil && v == want {
+			return it.Item().ID, nil
+		}
+	}
+	if err = it.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("%s 没有价格为 %s 的价格点", price.Territory, price.CustomerPrice)
+}
+
+// isTrue 未返回时按 false 处理
+func isTrue(b *bool) bool {
+	return b != nil && *b
+}
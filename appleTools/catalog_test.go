@@ -0,0 +1,135 @@
+package appleTools
+
+import (
+	"github.com/tidwall/gjson"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestLoadIapCatalog(t *testing.T) {
+	c, err := LoadIapCatalog("testdata/iap_catalog.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.InAppPurchases) != 2 || c.InAppPurchases[0].Price.CustomerPrice != "0.99" || c.InAppPurchases[0].Localizations["zh-Hans"].Name != "100金币" ||
+		len(c.SubscriptionGroups[0].Subscriptions[0].Prices) != 2 || c.SubscriptionGroups[0].Localizations["en-US"].CustomAppName != "Demo" {
+		t.Fatalf("目录解析错误 %+v", c)
+	}
+	if c, err = LoadIapCatalog("testdata/iap_catalog.json"); err != nil || c.InAppPurchases[0].Type != "CONSUMABLE" {
+		t.Fatalf("JSON 目录解析失败 %v", err)
+	}
+	if _, err = LoadIapCatalog("testdata/account_replay.json"); err == nil {
+		t.Fatal("格式错误应返回错误")
+	}
+}
+
+func TestApi_SyncIapCatalog(t *testing.T) {
+	var writes []string
+	noads := `{"productId":"noads","name":"Remove Ads","inAppPurchaseType":"NON_CONSUMABLE","familySharable":true}`
+	api := newTestApi(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Method != "GET" {
+			writes = append(writes, r.Method+" "+r.URL.Path)
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "GET /v1/apps/app1/inAppPurchasesV2":
+			writeTestJson(w, 200, `{"data":[{"type":"inAppPurchases","id":"iap2","attributes":`+noads+`}]}`)
+		case "POST /v2/inAppPurchases":
+			if gjson.GetBytes(body, "data.attributes.inAppPurchaseType").String() != "CONSUMABLE" {
+				t.Errorf("内购类型错误 %s", body)
+			}
+			writeTestJson(w, 201, `{"data":{"type":"inAppPurchases","id":"iap1"}}`)
+		case "PATCH /v2/inAppPurchases/iap2":
+			if gjson.GetBytes(body, "data.attributes.productId").Exists() || gjson.GetBytes(body, "data.attributes.reviewNote").String() != "unlocks ad free mode" ||
+				gjson.GetBytes(body, "data.attributes.familySharable").Raw != "false" {
+				t.Errorf("修改内购请求错误 %s", body)
+			}
+			writeTestJson(w, 200, `{"data":{"type":"inAppPurchases","id":"iap2"}}`)
+		case "GET /v2/inAppPurchases/iap1/inAppPurchaseLocalizations":
+			writeTestJson(w, 200, `{"data":[]}`)
+		case "GET /v2/inAppPurchases/iap2/inAppPurchaseLocalizations":
+			writeTestJson(w, 200, `{"data":[{"type":"inAppPurchaseLocalizations","id":"l2","attributes":{"locale":"en-US","name":"Remove Ads","description":"No more ads"}}]}`)
+		case "POST /v1/inAppPurchaseLocalizations", "POST /v1/subscriptionGroupLocalizations", "POST /v1/subscriptionLocalizations":
+			writeTestJson(w, 201, `{"data":{"type":"x","id":"x"}}`)
+		case "GET /v2/inAppPurchases/iap1/pricePoints":
+			if r.URL.Query().Get("filter[territory]") != "USA" {
+				t.Errorf("价格点筛选错误 %s", r.URL.RawQuery)
+			}
+			writeTestJson(w, 200, `{"data":[{"type":"inAppPurchasePricePoints","id":"pp0","attributes":{"customerPrice":"0.0"}},
+				{"type":"inAppPurchasePricePoints","id":"pp99","attributes":{"customerPrice":"0.99"}}]}`)
+		case "POST /v1/inAppPurchasePriceSchedules":
+			if gjson.GetBytes(body, "included.0.relationships.inAppPurchasePricePoint.data.id").String() != "pp99" ||
+				gjson.GetBytes(body, "data.relationships.manualPrices.data.0.id").String() != gjson.GetBytes(body, "included.0.id").String() ||
+				gjson.GetBytes(body, "included.0.attributes.startDate").Type != gjson.Null {
+				t.Errorf("价格请求错误 %s", body)
+			}
+			writeTestJson(w, 201, `{"data":{"type":"inAppPurchasePriceSchedules","id":"iap1"}}`)
+		case "POST /v1/inAppPurchaseAvailabilities", "POST /v1/subscriptionAvailabilities":
+			if gjson.GetBytes(body, "data.relationships.availableTerritories.data.#").Int() != 2 {
+				t.Errorf("销售范围错误 %s", body)
+			}
+			writeTestJson(w, 201, `{"data":{"type":"x","id":"x"}}`)
+		case "GET /v1/apps/app1/subscriptionGroups":
+			writeTestJson(w, 200, `{"data":[]}`)
+		case "POST /v1/subscriptionGroups":
+			writeTestJson(w, 201, `{"data":{"type":"subscriptionGroups","id":"g1"}}`)
+		case "GET /v1/subscriptionGroups/g1/subscriptionGroupLocalizations", "GET /v1/subscriptionGroups/g1/subscriptions", "GET /v1/subscriptions/s1/subscriptionLocalizations":
+			writeTestJson(w, 200, `{"data":[]}`)
+		case "POST /v1/subscriptions":
+			if gjson.GetBytes(body, "data.relationships.group.data.id").String() != "g1" || gjson.GetBytes(body, "data.attributes.subscriptionPeriod").String() != "ONE_MONTH" {
+				t.Errorf("订阅请求错误 %s", body)
+			}
+			writeTestJson(w, 201, `{"data":{"type":"subscriptions","id":"s1"}}`)
+		case "GET /v1/subscriptions/s1/pricePoints":
+			writeTestJson(w, 200, `{"data":[{"type":"subscriptionPricePoints","id":"`+r.URL.Query().Get("filter[territory]")+`-1","attributes":{"customerPrice":"4.99"}},
+				{"type":"subscriptionPricePoints","id":"`+r.URL.Query().Get("filter[territory]")+`-2","attributes":{"customerPrice":"30.00"}}]}`)
+		case "POST /v1/subscriptionPrices":
+			territory := gjson.GetBytes(body, "data.relationships.territory.data.id").String()
+			point := gjson.GetBytes(body, "data.relationships.subscriptionPricePoint.data.id").String()
+			if territory == "USA" && point != "USA-1" || territory == "CHN" && point != "CHN-2" {
+				t.Errorf("订阅价格错误 %s", body)
+			}
+			writeTestJson(w, 201, `{"data":{"type":"subscriptionPrices","id":"x"}}`)
+		default:
+			t.Errorf("未知请求 %s %s", r.Method, r.URL.Path)
+		}
+	})
+	catalog, err := LoadIapCatalog("testdata/iap_catalog.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := api.SyncIapCatalog("app1", catalog, CatalogSyncOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(writes) != 0 {
+		t.Fatalf("预览不应提交 %v", writes)
+	}
+	res, err := api.SyncIapCatalog("app1", catalog, CatalogSyncOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(plan.Actions, "\n") != strings.Join(res.Actions, "\n") || len(writes) != len(res.Actions) {
+		t.Fatalf("预览与执行不一致\n%s\n---\n%s\n%v", strings.Join(plan.Actions, "\n"), strings.Join(res.Actions, "\n"), writes)
+	}
+	want := []string{
+		"创建内购 coins100", "添加内购 coins100语言 en-US", "添加内购 coins100语言 zh-Hans", "设置内购价格 coins100 USA 0.99", "设置内购销售范围 coins100",
+		"修改内购 noads",
+		"创建订阅组 Premium", "添加订阅组 Premium语言 en-US", "创建订阅 premium.monthly", "添加订阅 premium.monthly语言 en-US",
+		"设置订阅价格 premium.monthly USA 4.99", "设置订阅价格 premium.monthly CHN 30", "设置订阅销售范围 premium.monthly",
+	}
+	if strings.Join(res.Actions, "|") != strings.Join(want, "|") {
+		t.Fatalf("操作错误 %v", res.Actions)
+	}
+
+	// 关闭家人共享后不再有差异
+	noads = `{"productId":"noads","name":"Remove Ads","inAppPurchaseType":"NON_CONSUMABLE","reviewNote":"unlocks ad free mode","familySharable":false}`
+	if plan, err = api.SyncIapCatalog("app1", catalog, CatalogSyncOptions{DryRun: true}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(strings.Join(plan.Actions, "|"), "修改内购 noads") {
+		t.Fatalf("已一致的内购不应修改 %v", plan.Actions)
+	}
+}
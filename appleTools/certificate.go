@@ -0,0 +1,121 @@
+package appleTools
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	CertificateTypeDistribution    = "DISTRIBUTION"
+	CertificateTypeDevelopment     = "DEVELOPMENT"
+	CertificateTypeIOSDistribution = "IOS_DISTRIBUTION"
+	CertificateTypeIOSDevelopment  = "IOS_DEVELOPMENT"
+)
+
+// certificateTimeLayouts App Store Connect 返回的过期时间格式
+var certificateTimeLayouts = []string{
+	"2006-01-02T15:04:05.000-0700",
+	"2006-01-02T15:04:05-0700",
+	time.RFC3339,
+}
+
+// ExpiresAt 证书过期时间
+func (c CertificateAttributes) ExpiresAt() (time.Time, error) {
+	for _, layout := range certificateTimeLayouts {
+		if t, err := time.Parse(layout, c.ExpirationDate); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("无法解析证书过期时间 %s", c.ExpirationDate)
+}
+
+// ExpiresAt 从证书内容中读取过期时间
+func (c *Cert) ExpiresAt() (time.Time, error) {
+	buf, err := base64.StdEncoding.DecodeString(c.CertContent)
+	if err != nil {
+		return time.Time{}, err
+	}
+	crt, err := x509.ParseCertificate(buf)
+	if err != nil {
+		return time.Time{}, errors.New("证书解析异常 :" + err.Error())
+	}
+	return crt.NotAfter, nil
+}
+
+// IssueCert 生成私钥和CSR 提交到 App Store Connect 创建证书并转换为 P12
+func (a *Api) IssueCert(certificateType, email, password string) (*Cert, error) {
+	if certificateType == "" {
+		certificateType = CertificateTypeDistribution
+	}
+	c := &Cert{}
+	csr, key := c.CreateCsr(email)
+	if key == nil || csr == "" {
+		return nil, errors.New("生成CSR失败")
+	}
+	res, err := a.CreateCertificate(certificateType, csr)
+	if err != nil {
+		return nil, fmt.Errorf("创建证书失败 %w", err)
+	}
+	c.CertID = res.ID
+	c.CertContent = res.Attributes.CertificateContent
+	if c.CertContent == "" {
+		// 部分情况下创建接口不返回内容 重新获取一次
+		doc, err := a.GetCertificate(res.ID, nil)
+		if err != nil {
+			return a.revokeIssued(c, fmt.Errorf("获取证书内容失败 %w", err))
+		}
+		c.CertContent = doc.Data.Attributes.CertificateContent
+	}
+	if err = c.ToP12(key, password); err != nil {
+		return a.revokeIssued(c, err)
+	}
+	return c, nil
+}
+
+// revokeIssued 私钥只在本次调用中存在 转换失败时撤销证书 避免占用证书数量
+// 撤销失败时返回带 CertID 的证书 由调用方处理
+func (a *Api) revokeIssued(c *Cert, err error) (*Cert, error) {
+	if rerr := a.RevokeCertificate(c.CertID); rerr != nil {
+		return c, fmt.Errorf("%w 撤销证书 %s 失败 %s", err, c.CertID, rerr)
+	}
+	return nil, err
+}
+
+// RevokeCert 撤销证书
+func (a *Api) RevokeCert(c *Cert) error {
+	if c.CertID == "" {
+		return errors.New("证书ID不能为空")
+	}
+	return a.RevokeCertificate(c.CertID)
+}
+
+// AllCertificates 全部证书 types 为空时不按类型过滤
+func (a *Api) AllCertificates(types ...string) ([]Certificate, error) {
+	q := NewApiQuery().Limit(200)
+	if len(types) > 0 {
+		q.Filter("certificateType", types...)
+	}
+	return NewApiIterator[CertificateAttributes](a, "certificates", q.Values()).All(context.Background())
+}
+
+// ExpiringCertificates 在 within 时间内过期(含已过期)的证书 无法解析过期时间的证书会跳过
+func (a *Api) ExpiringCertificates(within time.Duration, types ...string) ([]Certificate, error) {
+	list, err := a.AllCertificates(types...)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		result   []Certificate
+		deadline = time.Now().Add(within)
+	)
+	for _, c := range list {
+		if t, err := c.Attributes.ExpiresAt(); err == nil && t.Before(deadline) {
+			result = append(result, c)
+		}
+	}
+	return result, nil
+}
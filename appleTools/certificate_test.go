@@ -0,0 +1,97 @@
+package appleTools
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"github.com/tidwall/gjson"
+	"io"
+	"math/big"
+	"net/http"
+	gopkcs12 "software.sslmate.com/src/go-pkcs12"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApi_IssueCert(t *testing.T) {
+	caKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "Test CA"}, NotAfter: time.Now().AddDate(1, 0, 0), IsCA: true, BasicConstraintsValid: true}
+	var (
+		revoked   string
+		expiresAt = time.Now().Add(365 * 24 * time.Hour).Truncate(time.Second)
+	)
+	api := newTestApi(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		switch r.Method + " " + r.URL.Path {
+		case "POST /v1/certificates":
+			if gjson.GetBytes(body, "data.attributes.certificateType").String() == CertificateTypeDevelopment {
+				writeTestJson(w, 201, `{"data":{"type":"certificates","id":"cert2","attributes":{"certificateContent":"YmFk"}}}`)
+				return
+			}
+			if gjson.GetBytes(body, "data.attributes.certificateType").String() != CertificateTypeIOSDistribution {
+				t.Errorf("证书类型错误 %s", body)
+			}
+			der, _ := base64.StdEncoding.DecodeString(gjson.GetBytes(body, "data.attributes.csrContent").String())
+			csr, err := x509.ParseCertificateRequest(der)
+			if err != nil {
+				writeTestJson(w, 409, `{"errors":[{"status":"409","code":"ENTITY_ERROR","detail":"invalid csr"}]}`)
+				return
+			}
+			crt, _ := x509.CreateCertificate(rand.Reader, &x509.Certificate{SerialNumber: big.NewInt(2), Subject: csr.Subject, NotAfter: expiresAt}, ca, csr.PublicKey, caKey)
+			writeTestJson(w, 201, `{"data":{"type":"certificates","id":"cert1","attributes":{"certificateContent":"`+base64.StdEncoding.EncodeToString(crt)+`"}}}`)
+		case "DELETE /v1/certificates/cert1", "DELETE /v1/certificates/cert2":
+			revoked = strings.TrimPrefix(r.URL.Path, "/v1/certificates/")
+			w.WriteHeader(204)
+		case "GET /v1/certificates":
+			if r.URL.Query().Get("filter[certificateType]") != "DISTRIBUTION,IOS_DISTRIBUTION" {
+				t.Errorf("证书筛选错误 %s", r.URL.RawQuery)
+			}
+			writeTestJson(w, 200, `{"data":[
+				{"type":"certificates","id":"a","attributes":{"expirationDate":"`+time.Now().Add(10*24*time.Hour).UTC().Format("2006-01-02T15:04:05.000-0700")+`"}},
+				{"type":"certificates","id":"b","attributes":{"expirationDate":"`+time.Now().Add(100*24*time.Hour).UTC().Format("2006-01-02T15:04:05.000-0700")+`"}},
+				{"type":"certificates","id":"c","attributes":{"expirationDate":"2020-01-01T00:00:00.000+0000"}},
+				{"type":"certificates","id":"d","attributes":{"expirationDate":"unknown"}}]}`)
+		default:
+			t.Errorf("未知请求 %s %s", r.Method, r.URL.Path)
+		}
+	})
+	c, err := api.IssueCert(CertificateTypeIOSDistribution, "demo@test.com", "123456")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.CertID != "cert1" || c.CertContent == "" || c.P12Password != "123456" {
+		t.Fatalf("证书信息错误 %+v", c)
+	}
+	pfx, _ := base64.StdEncoding.DecodeString(c.P12Content)
+	if _, crt, err := gopkcs12.Decode(pfx, "123456"); err != nil || crt.SerialNumber.Int64() != 2 {
+		t.Fatalf("P12 无效 %v", err)
+	}
+	if exp, err := c.ExpiresAt(); err != nil || !exp.Equal(expiresAt) {
+		t.Fatalf("过期时间错误 %v %v", exp, err)
+	}
+	if err = api.RevokeCert(c); err != nil || revoked != "cert1" {
+		t.Fatalf("撤销证书失败 %v", err)
+	}
+	if err = api.RevokeCert(&Cert{}); err == nil {
+		t.Fatal("证书ID为空应返回错误")
+	}
+
+	// 转换 P12 失败时撤销已创建的证书
+	if c, err = api.IssueCert(CertificateTypeDevelopment, "demo@test.com", "123456"); err == nil || c != nil || revoked != "cert2" {
+		t.Fatalf("转换失败应撤销证书 %+v %v %s", c, err, revoked)
+	}
+
+	list, err := api.ExpiringCertificates(30*24*time.Hour, CertificateTypeDistribution, CertificateTypeIOSDistribution)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].ID != "a" || list[1].ID != "c" {
+		t.Fatalf("即将过期证书错误 %+v", list)
+	}
+}
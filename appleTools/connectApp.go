@@ -0,0 +1,170 @@
+package appleTools
+
+import "net/url"
+
+// AppAttributes 应用
+type AppAttributes struct {
+	Name          string `json:"name,omitempty"`
+	BundleID      string `json:"bundleId,omitempty"`
+	Sku           string `json:"sku,omitempty"`
+	PrimaryLocale string `json:"primaryLocale,omitempty"`
+}
+
+// BuildAttributes 构建版本
+type BuildAttributes struct {
+	Version                 string `json:"version,omitempty"`
+	UploadedDate            string `json:"uploadedDate,omitempty"`
+	ExpirationDate          string `json:"expirationDate,omitempty"`
+	Expired                 bool   `json:"expired,omitempty"`
+	MinOsVersion            string `json:"minOsVersion,omitempty"`
+	ProcessingState         string `json:"processingState,omitempty"`
+	UsesNonExemptEncryption *bool  `json:"usesNonExemptEncryption,omitempty"`
+}
+
+type (
+	App   = ApiResource[AppAttributes]
+	Build = ApiResource[BuildAttributes]
+)
+
+// ListApps 应用列表
+func (a *Api) ListApps(query url.Values) (*ApiDocument[[]App], error) {
+	return apiList[AppAttributes](a, "apps", query)
+}
+
+// GetApp 应用详情
+func (a *Api) GetApp(id string, query url.Values) (*ApiDocument[App], error) {
+	return apiGet[AppAttributes](a, "apps/"+id, query)
+}
+
+// ListBuilds 构建版本列表 可用 filter[app] filter[version] 等筛选
+func (a *Api) ListBuilds(query url.Values) (*ApiDocument[[]Build], error) {
+	return apiList[BuildAttributes](a, "builds", query)
+}
+
+// GetBuild 构建版本详情
+func (a *Api) GetBuild(id string, query url.Values) (*ApiDocument[Build], error) {
+	return apiGet[BuildAttributes](a, "builds/"+id, query)
+}
+
+// ExpireBuild 使构建版本过期 不再提供测试
+func (a *Api) ExpireBuild(id string) (*Build, error) {
+	return apiSave[BuildAttributes](a, "PATCH", "builds/"+id, ApiResource[map[string]bool]{
+		Type:       "builds",
+		ID:         id,
+		Attributes: map[string]bool{"expired": true},
+	})
+}
+
+// AppStoreVersionAttributes App Store 版本
+type AppStoreVersionAttributes struct {
+	Platform            string `json:"platform,omitempty"` // IOS MAC_OS TV_OS
+	VersionString       string `json:"versionString,omitempty"`
+	AppStoreState       string `json:"appStoreState,omitempty"`
+	ReleaseType         string `json:"releaseType,omitempty"` // MANUAL AFTER_APPROVAL SCHEDULED
+	EarliestReleaseDate string `json:"earliestReleaseDate,omitempty"`
+	Copyright           string `json:"copyright,omitempty"`
+	Downloadable        bool   `json:"downloadable,omitempty"`
+	UsesIdfa            *bool  `json:"usesIdfa,omitempty"`
+	CreatedDate         string `json:"createdDate,omitempty"`
+}
+
+// AppStoreVersionLocalizationAttributes 版本多语言信息
+type AppStoreVersionLocalizationAttributes struct {
+	Locale          string `json:"locale,omitempty"`
+	Description     string `json:"description,omitempty"`
+	Keywords        string `json:"keywords,omitempty"`
+	WhatsNew        string `json:"whatsNew,omitempty"`
+	PromotionalText string `json:"promotionalText,omitempty"`
+	MarketingUrl    string `json:"marketingUrl,omitempty"`
+	SupportUrl      string `json:"supportUrl,omitempty"`
+}
+
+// AppInfoAttributes 应用信息 每个应用有线上和编辑中两份
+type AppInfoAttributes struct {
+	AppStoreState     string `json:"appStoreState,omitempty"`
+	AppStoreAgeRating string `json:"appStoreAgeRating,omitempty"`
+}
+
+// AppInfoLocalizationAttributes 应用信息多语言
+type AppInfoLocalizationAttributes struct {
+	Locale            string `json:"locale,omitempty"`
+	Name              string `json:"name,omitempty"`
+	Subtitle          string `json:"subtitle,omitempty"`
+	PrivacyPolicyUrl  string `json:"privacyPolicyUrl,omitempty"`
+	PrivacyPolicyText string `json:"privacyPolicyText,omitempty"`
+}
+
+type (
+	AppStoreVersion             = ApiResource[AppStoreVersionAttributes]
+	AppStoreVersionLocalization = ApiResource[AppStoreVersionLocalizationAttributes]
+	AppInfo                     = ApiResource[AppInfoAttributes]
+	AppInfoLocalization         = ApiResource[AppInfoLocalizationAttributes]
+)
+
+// ListAppStoreVersions 应用的 App Store 版本 可用 filter[versionString] filter[appStoreState] 筛选
+func (a *Api) ListAppStoreVersions(appID string, query url.Values) (*ApiDocument[[]AppStoreVersion], error) {
+	return apiList[AppStoreVersionAttributes](a, "apps/"+appID+"/appStoreVersions", query)
+}
+
+// CreateAppStoreVersion 创建新版本
+func (a *Api) CreateAppStoreVersion(appID string, attr AppStoreVersionAttributes) (*AppStoreVersion, error) {
+	return apiSave[AppStoreVersionAttributes](a, "POST", "appStoreVersions", AppStoreVersion{
+		Type:          "appStoreVersions",
+		Attributes:    attr,
+		Relationships: map[string]*ApiRelationship{"app": ToOne("apps", appID)},
+	})
+}
+
+// UpdateAppStoreVersion 修改版本号 发布方式 版权等
+func (a *Api) UpdateAppStoreVersion(id string, attr AppStoreVersionAttributes) (*AppStoreVersion, error) {
+	return apiSave[AppStoreVersionAttributes](a, "PATCH", "appStoreVersions/"+id, AppStoreVersion{Type: "appStoreVersions", ID: id, Attributes: attr})
+}
+
+// ListAppStoreVersionLocalizations 版本多语言列表
+func (a *Api) ListAppStoreVersionLocalizations(versionID string, query url.Values) (*ApiDocument[[]AppStoreVersionLocalization], error) {
+	return apiList[AppStoreVersionLocalizationAttributes](a, "appStoreVersions/"+versionID+"/appStoreVersionLocalizations", query)
+}
+
+// CreateAppStoreVersionLocalization 添加版本语言
+func (a *Api) CreateAppStoreVersionLocalization(versionID string, attr AppStoreVersionLocalizationAttributes) (*AppStoreVersionLocalization, error) {
+	return apiSave[AppStoreVersionLocalizationAttributes](a, "POST", "appStoreVersionLocalizations", AppStoreVersionLocalization{
+		Type:          "appStoreVersionLocalizations",
+		Attributes:    attr,
+		Relationships: map[string]*ApiRelationship{"appStoreVersion": ToOne("appStoreVersions", versionID)},
+	})
+}
+
+// UpdateAppStoreVersionLocalization 修改版本语言 空字段不修改
+func (a *Api) UpdateAppStoreVersionLocalization(id string, attr AppStoreVersionLocalizationAttributes) (*AppStoreVersionLocalization, error) {
+	attr.Locale = ""
+	return apiSave[AppStoreVersionLocalizationAttributes](a, "PATCH", "appStoreVersionLocalizations/"+id, AppStoreVersionLocalization{
+		Type: "appStoreVersionLocalizations", ID: id, Attributes: attr,
+	})
+}
+
+// ListAppInfos 应用信息
+func (a *Api) ListAppInfos(appID string, query url.Values) (*ApiDocument[[]AppInfo], error) {
+	return apiList[AppInfoAttributes](a, "apps/"+appID+"/appInfos", query)
+}
+
+// ListAppInfoLocalizations 应用信息多语言列表
+func (a *Api) ListAppInfoLocalizations(appInfoID string, query url.Values) (*ApiDocument[[]AppInfoLocalization], error) {
+	return apiList[AppInfoLocalizationAttributes](a, "appInfos/"+appInfoID+"/appInfoLocalizations", query)
+}
+
+// CreateAppInfoLocalization 添加应用信息语言
+func (a *Api) CreateAppInfoLocalization(appInfoID string, attr AppInfoLocalizationAttributes) (*AppInfoLocalization, error) {
+	return apiSave[AppInfoLocalizationAttributes](a, "POST", "appInfoLocalizations", AppInfoLocalization{
+		Type:          "appInfoLocalizations",
+		Attributes:    attr,
+		Relationships: map[string]*ApiRelationship{"appInfo": ToOne("appInfos", appInfoID)},
+	})
+}
+
+// UpdateAppInfoLocalization 修改应用信息语言 空字段不修改
+func (a *Api) UpdateAppInfoLocalization(id string, attr AppInfoLocalizationAttributes) (*AppInfoLocalization, error) {
+	attr.Locale = ""
+	return apiSave[AppInfoLocalizationAttributes](a, "PATCH", "appInfoLocalizations/"+id, AppInfoLocalization{
+		Type: "appInfoLocalizations", ID: id, Attributes: attr,
+	})
+}
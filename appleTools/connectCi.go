@@ -0,0 +1,201 @@
+package appleTools
+
+import "net/url"
+
+// CiProductAttributes Xcode Cloud 产品
+type CiProductAttributes struct {
+	Name        string `json:"name,omitempty"`
+	CreatedDate string `json:"createdDate,omitempty"`
+	ProductType string `json:"productType,omitempty"` // APP FRAMEWORK
+}
+
+// CiWorkflowAttributes Xcode Cloud 工作流
+type CiWorkflowAttributes struct {
+	Name               string `json:"name,omitempty"`
+	Description        string `json:"description,omitempty"`
+	IsEnabled          *bool  `json:"isEnabled,omitempty"`
+	IsLockedForEditing bool   `json:"isLockedForEditing,omitempty"`
+	Clean              bool   `json:"clean,omitempty"`
+	ContainerFilePath  string `json:"containerFilePath,omitempty"`
+	LastModifiedDate   string `json:"lastModifiedDate,omitempty"`
+}
+
+// ScmRepositoryAttributes 代码仓库
+type ScmRepositoryAttributes struct {
+	OwnerName      string `json:"ownerName,omitempty"`
+	RepositoryName string `json:"repositoryName,omitempty"`
+	HttpCloneUrl   string `json:"httpCloneUrl,omitempty"`
+	SshCloneUrl    string `json:"sshCloneUrl,omitempty"`
+}
+
+// ScmGitReferenceAttributes 分支或标签
+type ScmGitReferenceAttributes struct {
+	Name          string `json:"name,omitempty"`
+	CanonicalName string `json:"canonicalName,omitempty"` // refs/heads/main refs/tags/v1.0
+	IsDeleted     bool   `json:"isDeleted,omitempty"`
+	Kind          string `json:"kind,omitempty"` // BRANCH TAG
+}
+
+// CiIssueCounts 问题数量
+type CiIssueCounts struct {
+	AnalyzerWarnings int `json:"analyzerWarnings"`
+	Errors           int `json:"errors"`
+	TestFailures     int `json:"testFailures"`
+	Warnings         int `json:"warnings"`
+}
+
+// CiCommit 构建使用的提交
+type CiCommit struct {
+	CommitSha string `json:"commitSha,omitempty"`
+	Message   string `json:"message,omitempty"`
+	WebUrl    string `json:"webUrl,omitempty"`
+	Author    *struct {
+		DisplayName string `json:"displayName,omitempty"`
+	} `json:"author,omitempty"`
+}
+
+// CiBuildRunAttributes 构建记录
+type CiBuildRunAttributes struct {
+	Number             int            `json:"number,omitempty"`
+	CreatedDate        string         `json:"createdDate,omitempty"`
+	StartedDate        string         `json:"startedDate,omitempty"`
+	FinishedDate       string         `json:"finishedDate,omitempty"`
+	SourceCommit       *CiCommit      `json:"sourceCommit,omitempty"`
+	DestinationCommit  *CiCommit      `json:"destinationCommit,omitempty"`
+	IsPullRequestBuild bool           `json:"isPullRequestBuild,omitempty"`
+	IssueCounts        *CiIssueCounts `json:"issueCounts,omitempty"`
+	ExecutionProgress  string         `json:"executionProgress,omitempty"` // PENDING RUNNING COMPLETE
+	CompletionStatus   string         `json:"completionStatus,omitempty"`  // SUCCEEDED FAILED ERRORED CANCELED SKIPPED
+	StartReason        string         `json:"startReason,omitempty"`
+	CancelReason       string         `json:"cancelReason,omitempty"`
+	Clean              *bool          `json:"clean,omitempty"` // 仅创建时提交
+}
+
+// CiBuildActionAttributes 构建中的单个动作
+type CiBuildActionAttributes struct {
+	Name              string         `json:"name,omitempty"`
+	ActionType        string         `json:"actionType,omitempty"` // BUILD ANALYZE TEST ARCHIVE
+	StartedDate       string         `json:"startedDate,omitempty"`
+	FinishedDate      string         `json:"finishedDate,omitempty"`
+	IssueCounts       *CiIssueCounts `json:"issueCounts,omitempty"`
+	ExecutionProgress string         `json:"executionProgress,omitempty"`
+	CompletionStatus  string         `json:"completionStatus,omitempty"`
+	IsRequiredToPass  bool           `json:"isRequiredToPass,omitempty"`
+}
+
+// CiIssueAttributes 构建问题
+type CiIssueAttributes struct {
+	IssueType  string `json:"issueType,omitempty"` // ANALYZER_WARNING ERROR TEST_FAILURE WARNING
+	Message    string `json:"message,omitempty"`
+	Category   string `json:"category,omitempty"`
+	FileSource *struct {
+		Path       string `json:"path,omitempty"`
+		LineNumber int    `json:"lineNumber,omitempty"`
+	} `json:"fileSource,omitempty"`
+}
+
+// CiArtifactAttributes 构建产物 downloadUrl 为临时地址
+type CiArtifactAttributes struct {
+	FileType    string `json:"fileType,omitempty"` // ARCHIVE ARCHIVE_EXPORT LOG_BUNDLE RESULT_BUNDLE ...
+	FileName    string `json:"fileName,omitempty"`
+	FileSize    int64  `json:"fileSize,omitempty"`
+	DownloadUrl string `json:"downloadUrl,omitempty"`
+}
+
+type (
+	CiProduct       = ApiResource[CiProductAttributes]
+	CiWorkflow      = ApiResource[CiWorkflowAttributes]
+	ScmRepository   = ApiResource[ScmRepositoryAttributes]
+	ScmGitReference = ApiResource[ScmGitReferenceAttributes]
+	CiBuildRun      = ApiResource[CiBuildRunAttributes]
+	CiBuildAction   = ApiResource[CiBuildActionAttributes]
+	CiIssue         = ApiResource[CiIssueAttributes]
+	CiArtifact      = ApiResource[CiArtifactAttributes]
+)
+
+// ListCiProducts Xcode Cloud 产品列表
+func (a *Api) ListCiProducts(query url.Values) (*ApiDocument[[]CiProduct], error) {
+	return apiList[CiProductAttributes](a, "ciProducts", query)
+}
+
+// GetCiProduct 产品详情
+func (a *Api) GetCiProduct(id string, query url.Values) (*ApiDocument[CiProduct], error) {
+	return apiGet[CiProductAttributes](a, "ciProducts/"+id, query)
+}
+
+// ListCiProductWorkflows 产品的工作流
+func (a *Api) ListCiProductWorkflows(productID string, query url.Values) (*ApiDocument[[]CiWorkflow], error) {
+	return apiList[CiWorkflowAttributes](a, "ciProducts/"+productID+"/workflows", query)
+}
+
+// ListCiProductBuildRuns 产品的构建记录
+func (a *Api) ListCiProductBuildRuns(productID string, query url.Values) (*ApiDocument[[]CiBuildRun], error) {
+	return apiList[CiBuildRunAttributes](a, "ciProducts/"+productID+"/buildRuns", query)
+}
+
+// GetCiWorkflow 工作流详情
+func (a *Api) GetCiWorkflow(id string, query url.Values) (*ApiDocument[CiWorkflow], error) {
+	return apiGet[CiWorkflowAttributes](a, "ciWorkflows/"+id, query)
+}
+
+// SetCiWorkflowEnabled 启用或停用工作流
+func (a *Api) SetCiWorkflowEnabled(id string, enabled bool) (*CiWorkflow, error) {
+	return apiSave[CiWorkflowAttributes](a, "PATCH", "ciWorkflows/"+id, CiWorkflow{
+		Type:       "ciWorkflows",
+		ID:         id,
+		Attributes: CiWorkflowAttributes{IsEnabled: &enabled},
+	})
+}
+
+// ListCiWorkflowBuildRuns 工作流的构建记录
+func (a *Api) ListCiWorkflowBuildRuns(workflowID string, query url.Values) (*ApiDocument[[]CiBuildRun], error) {
+	return apiList[CiBuildRunAttributes](a, "ciWorkflows/"+workflowID+"/buildRuns", query)
+}
+
+// GetCiWorkflowRepository 工作流使用的代码仓库
+func (a *Api) GetCiWorkflowRepository(workflowID string) (*ApiDocument[ScmRepository], error) {
+	return apiGet[ScmRepositoryAttributes](a, "ciWorkflows/"+workflowID+"/repository", nil)
+}
+
+// ListScmGitReferences 仓库的分支和标签
+func (a *Api) ListScmGitReferences(repositoryID string, query url.Values) (*ApiDocument[[]ScmGitReference], error) {
+	return apiList[ScmGitReferenceAttributes](a, "scmRepositories/"+repositoryID+"/gitReferences", query)
+}
+
+// CreateCiBuildRun 使用指定分支或标签开始构建 gitReferenceID 为空时使用工作流的默认配置
+func (a *Api) CreateCiBuildRun(workflowID, gitReferenceID string, clean bool) (*CiBuildRun, error) {
+	run := CiBuildRun{
+		Type:          "ciBuildRuns",
+		Attributes:    CiBuildRunAttributes{Clean: &clean},
+		Relationships: map[string]*ApiRelationship{"workflow": ToOne("ciWorkflows", workflowID)},
+	}
+	if gitReferenceID != "" {
+		run.Relationships["sourceBranchOrTag"] = ToOne("scmGitReferences", gitReferenceID)
+	}
+	return apiSave[CiBuildRunAttributes](a, "POST", "ciBuildRuns", run)
+}
+
+// GetCiBuildRun 构建记录详情
+func (a *Api) GetCiBuildRun(id string, query url.Values) (*ApiDocument[CiBuildRun], error) {
+	return apiGet[CiBuildRunAttributes](a, "ciBuildRuns/"+id, query)
+}
+
+// ListCiBuildRunActions 构建记录的动作
+func (a *Api) ListCiBuildRunActions(runID string, query url.Values) (*ApiDocument[[]CiBuildAction], error) {
+	return apiList[CiBuildActionAttributes](a, "ciBuildRuns/"+runID+"/actions", query)
+}
+
+// ListCiBuildActionIssues 动作的问题
+func (a *Api) ListCiBuildActionIssues(actionID string, query url.Values) (*ApiDocument[[]CiIssue], error) {
+	return apiList[CiIssueAttributes](a, "ciBuildActions/"+actionID+"/issues", query)
+}
+
+// ListCiBuildActionArtifacts 动作的产物
+func (a *Api) ListCiBuildActionArtifacts(actionID string, query url.Values) (*ApiDocument[[]CiArtifact], error) {
+	return apiList[CiArtifactAttributes](a, "ciBuildActions/"+actionID+"/artifacts", query)
+}
+
+// GetCiArtifact 产物详情 downloadUrl 过期后重新获取
+func (a *Api) GetCiArtifact(id string) (*ApiDocument[CiArtifact], error) {
+	return apiGet[CiArtifactAttributes](a, "ciArtifacts/"+id, nil)
+}
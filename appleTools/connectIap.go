@@ -0,0 +1,260 @@
+package appleTools
+
+import (
+	"context"
+	"net/url"
+)
+
+// InAppPurchaseAttributes 内购项目
+type InAppPurchaseAttributes struct {
+	Name              string `json:"name,omitempty"`
+	ProductID         string `json:"productId,omitempty"`
+	InAppPurchaseType string `json:"inAppPurchaseType,omitempty"` // CONSUMABLE NON_CONSUMABLE NON_RENEWING_SUBSCRIPTION
+	State             string `json:"state,omitempty"`
+	ReviewNote        string `json:"reviewNote,omitempty"`
+	FamilySharable    *bool  `json:"familySharable,omitempty"`
+}
+
+// IapLocalizationAttributes 内购和订阅的多语言 内购 订阅 订阅组共用
+type IapLocalizationAttributes struct {
+	Locale        string `json:"locale,omitempty"`
+	Name          string `json:"name,omitempty"`
+	Description   string `json:"description,omitempty"`
+	CustomAppName string `json:"customAppName,omitempty"` // 仅订阅组
+	State         string `json:"state,omitempty"`
+}
+
+// PricePointAttributes 价格点
+type PricePointAttributes struct {
+	CustomerPrice string `json:"customerPrice"`
+	Proceeds      string `json:"proceeds"`
+}
+
+// SubscriptionGroupAttributes 订阅组
+type SubscriptionGroupAttributes struct {
+	ReferenceName string `json:"referenceName,omitempty"`
+}
+
+// SubscriptionAttributes 自动续期订阅
+type SubscriptionAttributes struct {
+	Name               string `json:"name,omitempty"`
+	ProductID          string `json:"productId,omitempty"`
+	SubscriptionPeriod string `json:"subscriptionPeriod,omitempty"` // ONE_WEEK ONE_MONTH TWO_MONTHS THREE_MONTHS SIX_MONTHS ONE_YEAR
+	GroupLevel         int    `json:"groupLevel,omitempty"`
+	State              string `json:"state,omitempty"`
+	ReviewNote         string `json:"reviewNote,omitempty"`
+	FamilySharable     *bool  `json:"familySharable,omitempty"`
+}
+
+// AvailabilityAttributes 销售范围
+type AvailabilityAttributes struct {
+	AvailableInNewTerritories bool `json:"availableInNewTerritories"`
+}
+
+type (
+	InAppPurchase     = ApiResource[InAppPurchaseAttributes]
+	IapLocalization   = ApiResource[IapLocalizationAttributes]
+	PricePoint        = ApiResource[PricePointAttributes]
+	SubscriptionGroup = ApiResource[SubscriptionGroupAttributes]
+	Subscription      = ApiResource[SubscriptionAttributes]
+)
+
+// ListInAppPurchases 应用的内购项目
+func (a *Api) ListInAppPurchases(appID string, query url.Values) (*ApiDocument[[]InAppPurchase], error) {
+	return apiList[InAppPurchaseAttributes](a, "apps/"+appID+"/inAppPurchasesV2", query)
+}
+
+// CreateInAppPurchase 创建内购项目
+func (a *Api) CreateInAppPurchase(appID string, attr InAppPurchaseAttributes) (*InAppPurchase, error) {
+	return apiSave[InAppPurchaseAttributes](a, "POST", "/v2/inAppPurchases", InAppPurchase{
+		Type:          "inAppPurchases",
+		Attributes:    attr,
+		Relationships: map[string]*ApiRelationship{"app": ToOne("apps", appID)},
+	})
+}
+
+// UpdateInAppPurchase 修改内购项目 产品ID和类型不能修改
+func (a *Api) UpdateInAppPurchase(id string, attr InAppPurchaseAttributes) (*InAppPurchase, error) {
+	attr.ProductID, attr.InAppPurchaseType, attr.State = "", "", ""
+	return apiSave[InAppPurchaseAttributes](a, "PATCH", "/v2/inAppPurchases/"+id, InAppPurchase{Type: "inAppPurchases", ID: id, Attributes: attr})
+}
+
+// DeleteInAppPurchase 删除内购项目
+func (a *Api) DeleteInAppPurchase(id string) error {
+	return apiDelete(a, "/v2/inAppPurchases/"+id)
+}
+
+// ListInAppPurchaseLocalizations 内购多语言
+func (a *Api) ListInAppPurchaseLocalizations(id string, query url.Values) (*ApiDocument[[]IapLocalization], error) {
+	return apiList[IapLocalizationAttributes](a, "/v2/inAppPurchases/"+id+"/inAppPurchaseLocalizations", query)
+}
+
+// CreateInAppPurchaseLocalization 添加内购语言
+func (a *Api) CreateInAppPurchaseLocalization(id string, attr IapLocalizationAttributes) (*IapLocalization, error) {
+	return apiSave[IapLocalizationAttributes](a, "POST", "inAppPurchaseLocalizations", IapLocalization{
+		Type:          "inAppPurchaseLocalizations",
+		Attributes:    attr,
+		Relationships: map[string]*ApiRelationship{"inAppPurchaseV2": ToOne("inAppPurchases", id)},
+	})
+}
+
+// UpdateInAppPurchaseLocalization 修改内购语言
+func (a *Api) UpdateInAppPurchaseLocalization(id string, attr IapLocalizationAttributes) (*IapLocalization, error) {
+	attr.Locale, attr.State = "", ""
+	return apiSave[IapLocalizationAttributes](a, "PATCH", "inAppPurchaseLocalizations/"+id, IapLocalization{Type: "inAppPurchaseLocalizations", ID: id, Attributes: attr})
+}
+
+// ListInAppPurchasePricePoints 内购价格点 通常配合 filter[territory] 使用
+func (a *Api) ListInAppPurchasePricePoints(id string, query url.Values) (*ApiDocument[[]PricePoint], error) {
+	return apiList[PricePointAttributes](a, "/v2/inAppPurchases/"+id+"/pricePoints", query)
+}
+
+// SetInAppPurchasePrice 以 baseTerritory 的价格点设置内购价格 其他国家按苹果汇率自动换算
+func (a *Api) SetInAppPurchasePrice(id, baseTerritory, pricePointID string) error {
+	body := struct {
+		Data     ApiResource[struct{}] `json:"data"`
+		Included []ApiResource[any]    `json:"included"`
+	}{
+		Data: ApiResource[struct{}]{
+			Type: "inAppPurchasePriceSchedules",
+			Relationships: map[string]*ApiRelationship{
+				"inAppPurchase": ToOne("inAppPurchases", id),
+				"baseTerritory": ToOne("territories", baseTerritory),
+				"manualPrices":  ToMany("inAppPurchasePrices", "${price}"),
+			},
+		},
+		Included: []ApiResource[any]{{
+			Type:       "inAppPurchasePrices",
+			ID:         "${price}",
+			Attributes: map[string]any{"startDate": nil},
+			Relationships: map[string]*ApiRelationship{
+				"inAppPurchaseV2":         ToOne("inAppPurchases", id),
+				"inAppPurchasePricePoint": ToOne("inAppPurchasePricePoints", pricePointID),
+			},
+		}},
+	}
+	return a.request(context.Background(), "POST", "inAppPurchasePriceSchedules", nil, body, nil)
+}
+
+// SetInAppPurchaseAvailability 设置内购销售范围
+func (a *Api) SetInAppPurchaseAvailability(id string, territories []string, availableInNew bool) error {
+	_, err := apiSave[AvailabilityAttributes](a, "POST", "inAppPurchaseAvailabilities", ApiResource[AvailabilityAttributes]{
+		Type:       "inAppPurchaseAvailabilities",
+		Attributes: AvailabilityAttributes{AvailableInNewTerritories: availableInNew},
+		Relationships: map[string]*ApiRelationship{
+			"inAppPurchase":        ToOne("inAppPurchases", id),
+			"availableTerritories": ToMany("territories", territories...),
+		},
+	})
+	return err
+}
+
+// ListSubscriptionGroups 应用的订阅组
+func (a *Api) ListSubscriptionGroups(appID string, query url.Values) (*ApiDocument[[]SubscriptionGroup], error) {
+	return apiList[SubscriptionGroupAttributes](a, "apps/"+appID+"/subscriptionGroups", query)
+}
+
+// CreateSubscriptionGroup 创建订阅组
+func (a *Api) CreateSubscriptionGroup(appID, referenceName string) (*SubscriptionGroup, error) {
+	return apiSave[SubscriptionGroupAttributes](a, "POST", "subscriptionGroups", SubscriptionGroup{
+		Type:          "subscriptionGroups",
+		Attributes:    SubscriptionGroupAttributes{ReferenceName: referenceName},
+		Relationships: map[string]*ApiRelationship{"app": ToOne("apps", appID)},
+	})
+}
+
+// ListSubscriptionGroupLocalizations 订阅组多语言
+func (a *Api) ListSubscriptionGroupLocalizations(groupID string, query url.Values) (*ApiDocument[[]IapLocalization], error) {
+	return apiList[IapLocalizationAttributes](a, "subscriptionGroups/"+groupID+"/subscriptionGroupLocalizations", query)
+}
+
+// CreateSubscriptionGroupLocalization 添加订阅组语言
+func (a *Api) CreateSubscriptionGroupLocalization(groupID string, attr IapLocalizationAttributes) (*IapLocalization, error) {
+	return apiSave[IapLocalizationAttributes](a, "POST", "subscriptionGroupLocalizations", IapLocalization{
+		Type:          "subscriptionGroupLocalizations",
+		Attributes:    attr,
+		Relationships: map[string]*ApiRelationship{"subscriptionGroup": ToOne("subscriptionGroups", groupID)},
+	})
+}
+
+// UpdateSubscriptionGroupLocalization 修改订阅组语言
+func (a *Api) UpdateSubscriptionGroupLocalization(id string, attr IapLocalizationAttributes) (*IapLocalization, error) {
+	attr.Locale, attr.State = "", ""
+	return apiSave[IapLocalizationAttributes](a, "PATCH", "subscriptionGroupLocalizations/"+id, IapLocalization{Type: "subscriptionGroupLocalizations", ID: id, Attributes: attr})
+}
+
+// ListSubscriptions 订阅组内的订阅
+func (a *Api) ListSubscriptions(groupID string, query url.Values) (*ApiDocument[[]Subscription], error) {
+	return apiList[SubscriptionAttributes](a, "subscriptionGroups/"+groupID+"/subscriptions", query)
+}
+
+// CreateSubscription 创建订阅
+func (a *Api) CreateSubscription(groupID string, attr SubscriptionAttributes) (*Subscription, error) {
+	return apiSave[SubscriptionAttributes](a, "POST", "subscriptions", Subscription{
+		Type:          "subscriptions",
+		Attributes:    attr,
+		Relationships: map[string]*ApiRelationship{"group": ToOne("subscriptionGroups", groupID)},
+	})
+}
+
+// UpdateSubscription 修改订阅 产品ID不能修改
+func (a *Api) UpdateSubscription(id string, attr SubscriptionAttributes) (*Subscription, error) {
+	attr.ProductID, attr.State = "", ""
+	return apiSave[SubscriptionAttributes](a, "PATCH", "subscriptions/"+id, Subscription{Type: "subscriptions", ID: id, Attributes: attr})
+}
+
+// ListSubscriptionLocalizations 订阅多语言
+func (a *Api) ListSubscriptionLocalizations(id string, query url.Values) (*ApiDocument[[]IapLocalization], error) {
+	return apiList[IapLocalizationAttributes](a, "subscriptions/"+id+"/subscriptionLocalizations", query)
+}
+
+// CreateSubscriptionLocalization 添加订阅语言
+func (a *Api) CreateSubscriptionLocalization(id string, attr IapLocalizationAttributes) (*IapLocalization, error) {
+	return apiSave[IapLocalizationAttributes](a, "POST", "subscriptionLocalizations", IapLocalization{
+		Type:          "subscriptionLocalizations",
+		Attributes:    attr,
+		Relationships: map[string]*ApiRelationship{"subscription": ToOne("subscriptions", id)},
+	})
+}
+
+// UpdateSubscriptionLocalization 修改订阅语言
+func (a *Api) UpdateSubscriptionLocalization(id string, attr IapLocalizationAttributes) (*IapLocalization, error) {
+	attr.Locale, attr.State = "", ""
+	return apiSave[IapLocalizationAttributes](a, "PATCH", "subscriptionLocalizations/"+id, IapLocalization{Type: "subscriptionLocalizations", ID: id, Attributes: attr})
+}
+
+// ListSubscriptionPricePoints 订阅价格点 通常配合 filter[territory] 使用
+func (a *Api) ListSubscriptionPricePoints(id string, query url.Values) (*ApiDocument[[]PricePoint], error) {
+	return apiList[PricePointAttributes](a, "subscriptions/"+id+"/pricePoints", query)
+}
+
+// SetSubscriptionPrice 设置订阅在某个国家的价格 startDate 为空时立即生效
+func (a *Api) SetSubscriptionPrice(id, territory, pricePointID, startDate string) error {
+	attr := map[string]any{"preserveCurrentPrice": false}
+	if startDate != "" {
+		attr["startDate"] = startDate
+	}
+	_, err := apiSave[map[string]any](a, "POST", "subscriptionPrices", ApiResource[map[string]any]{
+		Type:       "subscriptionPrices",
+		Attributes: attr,
+		Relationships: map[string]*ApiRelationship{
+			"subscription":           ToOne("subscriptions", id),
+			"subscriptionPricePoint": ToOne("subscriptionPricePoints", pricePointID),
+			"territory":              ToOne("territories", territory),
+		},
+	})
+	return err
+}
+
+// SetSubscriptionAvailability 设置订阅销售范围
+func (a *Api) SetSubscriptionAvailability(id string, territories []string, availableInNew bool) error {
+	_, err := apiSave[AvailabilityAttributes](a, "POST", "subscriptionAvailabilities", ApiResource[AvailabilityAttributes]{
+		Type:       "subscriptionAvailabilities",
+		Attributes: AvailabilityAttributes{AvailableInNewTerritories: availableInNew},
+		Relationships: map[string]*ApiRelationship{
+			"subscription":         ToOne("subscriptions", id),
+			"availableTerritories": ToMany("territories", territories...),
+		},
+	})
+	return err
+}
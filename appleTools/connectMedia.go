@@ -0,0 +1,166 @@
+package appleTools
+
+import "net/url"
+
+// UploadOperation 资源预留后返回的分片上传操作
+type UploadOperation struct {
+	Method         string             `json:"method"`
+	Url            string             `json:"url"`
+	Length         int64              `json:"length"`
+	Offset         int64              `json:"offset"`
+	RequestHeaders []UploadHttpHeader `json:"requestHeaders"`
+}
+
+// UploadHttpHeader 分片上传需要携带的请求头
+type UploadHttpHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// AssetError 资源处理错误
+type AssetError struct {
+	Code        string `json:"code"`
+	Description string `json:"description"`
+}
+
+// AssetDeliveryState 资源处理状态 AWAITING_UPLOAD UPLOAD_COMPLETE COMPLETE FAILED
+type AssetDeliveryState struct {
+	State    string       `json:"state"`
+	Errors   []AssetError `json:"errors,omitempty"`
+	Warnings []AssetError `json:"warnings,omitempty"`
+}
+
+// AppScreenshotSetAttributes 截图组 每种显示类型一组
+type AppScreenshotSetAttributes struct {
+	ScreenshotDisplayType string `json:"screenshotDisplayType,omitempty"` // APP_IPHONE_67 APP_IPAD_PRO_3GEN_129 ...
+}
+
+// AppScreenshotAttributes 截图
+type AppScreenshotAttributes struct {
+	FileSize           int64               `json:"fileSize,omitempty"`
+	FileName           string              `json:"fileName,omitempty"`
+	SourceFileChecksum string              `json:"sourceFileChecksum,omitempty"`
+	Uploaded           *bool               `json:"uploaded,omitempty"`
+	UploadOperations   []UploadOperation   `json:"uploadOperations,omitempty"`
+	AssetDeliveryState *AssetDeliveryState `json:"assetDeliveryState,omitempty"`
+}
+
+// AppPreviewSetAttributes 预览视频组
+type AppPreviewSetAttributes struct {
+	PreviewType string `json:"previewType,omitempty"` // IPHONE_67 IPAD_PRO_3GEN_129 ...
+}
+
+// AppPreviewAttributes 预览视频
+type AppPreviewAttributes struct {
+	FileSize             int64               `json:"fileSize,omitempty"`
+	FileName             string              `json:"fileName,omitempty"`
+	SourceFileChecksum   string              `json:"sourceFileChecksum,omitempty"`
+	PreviewFrameTimeCode string              `json:"previewFrameTimeCode,omitempty"`
+	MimeType             string              `json:"mimeType,omitempty"`
+	Uploaded             *bool               `json:"uploaded,omitempty"`
+	UploadOperations     []UploadOperation   `json:"uploadOperations,omitempty"`
+	AssetDeliveryState   *AssetDeliveryState `json:"assetDeliveryState,omitempty"`
+}
+
+type (
+	AppScreenshotSet = ApiResource[AppScreenshotSetAttributes]
+	AppScreenshot    = ApiResource[AppScreenshotAttributes]
+	AppPreviewSet    = ApiResource[AppPreviewSetAttributes]
+	AppPreview       = ApiResource[AppPreviewAttributes]
+)
+
+// ListAppScreenshotSets 版本语言下的截图组
+func (a *Api) ListAppScreenshotSets(localizationID string, query url.Values) (*ApiDocument[[]AppScreenshotSet], error) {
+	return apiList[AppScreenshotSetAttributes](a, "appStoreVersionLocalizations/"+localizationID+"/appScreenshotSets", query)
+}
+
+// CreateAppScreenshotSet 创建截图组
+func (a *Api) CreateAppScreenshotSet(localizationID, displayType string) (*AppScreenshotSet, error) {
+	return apiSave[AppScreenshotSetAttributes](a, "POST", "appScreenshotSets", AppScreenshotSet{
+		Type:          "appScreenshotSets",
+		Attributes:    AppScreenshotSetAttributes{ScreenshotDisplayType: displayType},
+		Relationships: map[string]*ApiRelationship{"appStoreVersionLocalization": ToOne("appStoreVersionLocalizations", localizationID)},
+	})
+}
+
+// ListAppScreenshots 截图组中的截图
+func (a *Api) ListAppScreenshots(setID string, query url.Values) (*ApiDocument[[]AppScreenshot], error) {
+	return apiList[AppScreenshotAttributes](a, "appScreenshotSets/"+setID+"/appScreenshots", query)
+}
+
+// ReserveAppScreenshot 预留截图 返回分片上传操作
+func (a *Api) ReserveAppScreenshot(setID, fileName string, fileSize int64) (*AppScreenshot, error) {
+	return apiSave[AppScreenshotAttributes](a, "POST", "appScreenshots", AppScreenshot{
+		Type:          "appScreenshots",
+		Attributes:    AppScreenshotAttributes{FileName: fileName, FileSize: fileSize},
+		Relationships: map[string]*ApiRelationship{"appScreenshotSet": ToOne("appScreenshotSets", setID)},
+	})
+}
+
+// CommitAppScreenshot 上传完成后提交 checksum 为文件MD5
+func (a *Api) CommitAppScreenshot(id, checksum string) (*AppScreenshot, error) {
+	uploaded := true
+	return apiSave[AppScreenshotAttributes](a, "PATCH", "appScreenshots/"+id, AppScreenshot{
+		Type:       "appScreenshots",
+		ID:         id,
+		Attributes: AppScreenshotAttributes{Uploaded: &uploaded, SourceFileChecksum: checksum},
+	})
+}
+
+// GetAppScreenshot 截图详情
+func (a *Api) GetAppScreenshot(id string, query url.Values) (*ApiDocument[AppScreenshot], error) {
+	return apiGet[AppScreenshotAttributes](a, "appScreenshots/"+id, query)
+}
+
+// DeleteAppScreenshot 删除截图
+func (a *Api) DeleteAppScreenshot(id string) error {
+	return apiDelete(a, "appScreenshots/"+id)
+}
+
+// ListAppPreviewSets 版本语言下的预览视频组
+func (a *Api) ListAppPreviewSets(localizationID string, query url.Values) (*ApiDocument[[]AppPreviewSet], error) {
+	return apiList[AppPreviewSetAttributes](a, "appStoreVersionLocalizations/"+localizationID+"/appPreviewSets", query)
+}
+
+// CreateAppPreviewSet 创建预览视频组
+func (a *Api) CreateAppPreviewSet(localizationID, previewType string) (*AppPreviewSet, error) {
+	return apiSave[AppPreviewSetAttributes](a, "POST", "appPreviewSets", AppPreviewSet{
+		Type:          "appPreviewSets",
+		Attributes:    AppPreviewSetAttributes{PreviewType: previewType},
+		Relationships: map[string]*ApiRelationship{"appStoreVersionLocalization": ToOne("appStoreVersionLocalizations", localizationID)},
+	})
+}
+
+// ListAppPreviews 预览视频组中的视频
+func (a *Api) ListAppPreviews(setID string, query url.Values) (*ApiDocument[[]AppPreview], error) {
+	return apiList[AppPreviewAttributes](a, "appPreviewSets/"+setID+"/appPreviews", query)
+}
+
+// ReserveAppPreview 预留预览视频 返回分片上传操作
+func (a *Api) ReserveAppPreview(setID, fileName string, fileSize int64, mimeType string) (*AppPreview, error) {
+	return apiSave[AppPreviewAttributes](a, "POST", "appPreviews", AppPreview{
+		Type:          "appPreviews",
+		Attributes:    AppPreviewAttributes{FileName: fileName, FileSize: fileSize, MimeType: mimeType},
+		Relationships: map[string]*ApiRelationship{"appPreviewSet": ToOne("appPreviewSets", setID)},
+	})
+}
+
+// CommitAppPreview 上传完成后提交 checksum 为文件MD5
+func (a *Api) CommitAppPreview(id, checksum string) (*AppPreview, error) {
+	uploaded := true
+	return apiSave[AppPreviewAttributes](a, "PATCH", "appPreviews/"+id, AppPreview{
+		Type:       "appPreviews",
+		ID:         id,
+		Attributes: AppPreviewAttributes{Uploaded: &uploaded, SourceFileChecksum: checksum},
+	})
+}
+
+// GetAppPreview 预览视频详情
+func (a *Api) GetAppPreview(id string, query url.Values) (*ApiDocument[AppPreview], error) {
+	return apiGet[AppPreviewAttributes](a, "appPreviews/"+id, query)
+}
+
+// DeleteAppPreview 删除预览视频
+func (a *Api) DeleteAppPreview(id string) error {
+	return apiDelete(a, "appPreviews/"+id)
+}
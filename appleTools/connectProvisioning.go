@@ -0,0 +1,200 @@
+package appleTools
+
+import "net/url"
+
+// BundleIDAttributes 套装ID
+type BundleIDAttributes struct {
+	Name       string `json:"name,omitempty"`
+	Identifier string `json:"identifier,omitempty"`
+	Platform   string `json:"platform,omitempty"` // IOS MAC_OS UNIVERSAL
+	SeedID     string `json:"seedId,omitempty"`
+}
+
+// CertificateAttributes 证书
+type CertificateAttributes struct {
+	Name               string `json:"name,omitempty"`
+	DisplayName        string `json:"displayName,omitempty"`
+	CertificateType    string `json:"certificateType,omitempty"` // DISTRIBUTION DEVELOPMENT IOS_DISTRIBUTION ...
+	SerialNumber       string `json:"serialNumber,omitempty"`
+	Platform           string `json:"platform,omitempty"`
+	ExpirationDate     string `json:"expirationDate,omitempty"`
+	CertificateContent string `json:"certificateContent,omitempty"`
+	CsrContent         string `json:"csrContent,omitempty"` // 仅创建时提交
+}
+
+// DeviceAttributes 设备
+type DeviceAttributes struct {
+	Name        string `json:"name,omitempty"`
+	Platform    string `json:"platform,omitempty"`
+	Udid        string `json:"udid,omitempty"`
+	DeviceClass string `json:"deviceClass,omitempty"`
+	Status      string `json:"status,omitempty"` // ENABLED DISABLED
+	Model       string `json:"model,omitempty"`
+	AddedDate   string `json:"addedDate,omitempty"`
+}
+
+// ProfileAttributes 描述文件
+type ProfileAttributes struct {
+	Name           string `json:"name,omitempty"`
+	Platform       string `json:"platform,omitempty"`
+	ProfileType    string `json:"profileType,omitempty"` // IOS_APP_ADHOC IOS_APP_DEVELOPMENT IOS_APP_STORE ...
+	ProfileState   string `json:"profileState,omitempty"`
+	ProfileContent string `json:"profileContent,omitempty"`
+	Uuid           string `json:"uuid,omitempty"`
+	CreatedDate    string `json:"createdDate,omitempty"`
+	ExpirationDate string `json:"expirationDate,omitempty"`
+}
+
+type (
+	BundleID    = ApiResource[BundleIDAttributes]
+	Certificate = ApiResource[CertificateAttributes]
+	Device      = ApiResource[DeviceAttributes]
+	Profile     = ApiResource[ProfileAttributes]
+)
+
+// ListBundleIDs 套装ID列表
+func (a *Api) ListBundleIDs(query url.Values) (*ApiDocument[[]BundleID], error) {
+	return apiList[BundleIDAttributes](a, "bundleIds", query)
+}
+
+// GetBundleID 套装ID详情
+func (a *Api) GetBundleID(id string, query url.Values) (*ApiDocument[BundleID], error) {
+	return apiGet[BundleIDAttributes](a, "bundleIds/"+id, query)
+}
+
+// CreateBundleID 注册套装ID
+func (a *Api) CreateBundleID(attr BundleIDAttributes) (*BundleID, error) {
+	return apiSave[BundleIDAttributes](a, "POST", "bundleIds", BundleID{Type: "bundleIds", Attributes: attr})
+}
+
+// DeleteBundleID 删除套装ID
+func (a *Api) DeleteBundleID(id string) error {
+	return apiDelete(a, "bundleIds/"+id)
+}
+
+// ListCertificates 证书列表
+func (a *Api) ListCertificates(query url.Values) (*ApiDocument[[]Certificate], error) {
+	return apiList[CertificateAttributes](a, "certificates", query)
+}
+
+// GetCertificate 证书详情
+func (a *Api) GetCertificate(id string, query url.Values) (*ApiDocument[Certificate], error) {
+	return apiGet[CertificateAttributes](a, "certificates/"+id, query)
+}
+
+// CreateCertificate 提交CSR创建证书
+func (a *Api) CreateCertificate(certificateType, csrContent string) (*Certificate, error) {
+	return apiSave[CertificateAttributes](a, "POST", "certificates", Certificate{
+		Type:       "certificates",
+		Attributes: CertificateAttributes{CertificateType: certificateType, CsrContent: csrContent},
+	})
+}
+
+// RevokeCertificate 撤销证书
+func (a *Api) RevokeCertificate(id string) error {
+	return apiDelete(a, "certificates/"+id)
+}
+
+// ListDevices 设备列表
+func (a *Api) ListDevices(query url.Values) (*ApiDocument[[]Device], error) {
+	return apiList[DeviceAttributes](a, "devices", query)
+}
+
+// RegisterDevice 注册设备
+func (a *Api) RegisterDevice(name, udid, platform string) (*Device, error) {
+	return apiSave[DeviceAttributes](a, "POST", "devices", Device{
+		Type:       "devices",
+		Attributes: DeviceAttributes{Name: name, Udid: udid, Platform: platform},
+	})
+}
+
+// UpdateDevice 修改设备名称或状态
+func (a *Api) UpdateDevice(id, name, status string) (*Device, error) {
+	return apiSave[DeviceAttributes](a, "PATCH", "devices/"+id, Device{
+		Type:       "devices",
+		ID:         id,
+		Attributes: DeviceAttributes{Name: name, Status: status},
+	})
+}
+
+// ListProfiles 描述文件列表
+func (a *Api) ListProfiles(query url.Values) (*ApiDocument[[]Profile], error) {
+	return apiList[ProfileAttributes](a, "profiles", query)
+}
+
+// GetProfile 描述文件详情
+func (a *Api) GetProfile(id string, query url.Values) (*ApiDocument[Profile], error) {
+	return apiGet[ProfileAttributes](a, "profiles/"+id, query)
+}
+
+// CreateProfile 创建描述文件 devices 为空时不关联设备(如 App Store 类型)
+func (a *Api) CreateProfile(name, profileType, bundleID string, certificates []string, devices []string) (*Profile, error) {
+	p := Profile{
+		Type:       "profiles",
+		Attributes: ProfileAttributes{Name: name, ProfileType: profileType},
+		Relationships: map[string]*ApiRelationship{
+			"bundleId":     ToOne("bundleIds", bundleID),
+			"certificates": ToMany("certificates", certificates...),
+		},
+	}
+	if len(devices) > 0 {
+		p.Relationships["devices"] = ToMany("devices", devices...)
+	}
+	return apiSave[ProfileAttributes](a, "POST", "profiles", p)
+}
+
+// DeleteProfile 删除描述文件
+func (a *Api) DeleteProfile(id string) error {
+	return apiDelete(a, "profiles/"+id)
+}
+
+// CapabilityOption 功能设置项的选项
+type CapabilityOption struct {
+	Key         string `json:"key"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	Enabled     bool   `json:"enabled"`
+}
+
+// CapabilitySetting 功能设置 如 ICLOUD_VERSION DATA_PROTECTION_PERMISSION_LEVEL
+type CapabilitySetting struct {
+	Key     string             `json:"key"`
+	Name    string             `json:"name,omitempty"`
+	Options []CapabilityOption `json:"options,omitempty"`
+}
+
+// BundleIDCapabilityAttributes 套装ID功能
+type BundleIDCapabilityAttributes struct {
+	CapabilityType string              `json:"capabilityType,omitempty"` // PUSH_NOTIFICATIONS APP_GROUPS ...
+	Settings       []CapabilitySetting `json:"settings,omitempty"`
+}
+
+type BundleIDCapability = ApiResource[BundleIDCapabilityAttributes]
+
+// ListBundleIDCapabilities 套装ID已开启的功能
+func (a *Api) ListBundleIDCapabilities(bundleID string, query url.Values) (*ApiDocument[[]BundleIDCapability], error) {
+	return apiList[BundleIDCapabilityAttributes](a, "bundleIds/"+bundleID+"/bundleIdCapabilities", query)
+}
+
+// EnableCapability 开启套装ID功能
+func (a *Api) EnableCapability(bundleID, capabilityType string, settings ...CapabilitySetting) (*BundleIDCapability, error) {
+	return apiSave[BundleIDCapabilityAttributes](a, "POST", "bundleIdCapabilities", BundleIDCapability{
+		Type:          "bundleIdCapabilities",
+		Attributes:    BundleIDCapabilityAttributes{CapabilityType: capabilityType, Settings: settings},
+		Relationships: map[string]*ApiRelationship{"bundleId": ToOne("bundleIds", bundleID)},
+	})
+}
+
+// UpdateCapability 修改功能设置
+func (a *Api) UpdateCapability(id, capabilityType string, settings ...CapabilitySetting) (*BundleIDCapability, error) {
+	return apiSave[BundleIDCapabilityAttributes](a, "PATCH", "bundleIdCapabilities/"+id, BundleIDCapability{
+		Type:       "bundleIdCapabilities",
+		ID:         id,
+		Attributes: BundleIDCapabilityAttributes{CapabilityType: capabilityType, Settings: settings},
+	})
+}
+
+// DisableCapability 关闭功能
+func (a *Api) DisableCapability(id string) error {
+	return apiDelete(a, "bundleIdCapabilities/"+id)
+}
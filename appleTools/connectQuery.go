@@ -0,0 +1,184 @@
+package appleTools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ApiQuery 列表接口参数 filter[...] fields[...] include sort limit
+type ApiQuery struct {
+	values url.Values
+}
+
+// NewApiQuery 创建查询参数
+func NewApiQuery() *ApiQuery {
+	return &ApiQuery{values: url.Values{}}
+}
+
+// Filter filter[field]=v1,v2
+func (q *ApiQuery) Filter(field string, values ...string) *ApiQuery {
+	return q.Set("filter["+field+"]", strings.Join(values, ","))
+}
+
+// Fields fields[type]=f1,f2 只返回指定属性
+func (q *ApiQuery) Fields(typ string, fields ...string) *ApiQuery {
+	return q.Set("fields["+typ+"]", strings.Join(fields, ","))
+}
+
+// Include 同时返回关联资源 可多次调用
+func (q *ApiQuery) Include(relationships ...string) *ApiQuery {
+	return q.appendList("include", relationships...)
+}
+
+// Sort 排序 字段前加 - 为倒序
+func (q *ApiQuery) Sort(fields ...string) *ApiQuery {
+	return q.appendList("sort", fields...)
+}
+
+// Limit 每页数量 最大200
+func (q *ApiQuery) Limit(n int) *ApiQuery {
+	return q.Set("limit", strconv.Itoa(n))
+}
+
+// LimitRelated limit[relationship] include 关联资源的数量
+func (q *ApiQuery) LimitRelated(relationship string, n int) *ApiQuery {
+	return q.Set("limit["+relationship+"]", strconv.Itoa(n))
+}
+
+// Set 设置任意参数
+func (q *ApiQuery) Set(key, value string) *ApiQuery {
+	if q.values == nil {
+		q.values = url.Values{}
+	}
+	q.values.Set(key, value)
+	return q
+}
+
+// Values 转为 url.Values 传给 List 系列方法
+func (q *ApiQuery) Values() url.Values {
+	if q == nil {
+		return nil
+	}
+	return q.values
+}
+func (q *ApiQuery) appendList(key string, items ...string) *ApiQuery {
+	if old := q.values.Get(key); old != "" {
+		items = append([]string{old}, items...)
+	}
+	return q.Set(key, strings.Join(items, ","))
+}
+
+// ApiIncludedIndex 按 type/id 索引的 included 资源
+type ApiIncludedIndex map[ApiLinkage]ApiIncluded
+
+func (idx ApiIncludedIndex) add(list []ApiIncluded) {
+	for _, inc := range list {
+		idx[ApiLinkage{Type: inc.Type, ID: inc.ID}] = inc
+	}
+}
+
+// LookupIncluded 从索引中取资源并解析属性
+func LookupIncluded[T any](idx ApiIncludedIndex, typ, id string) (*ApiResource[T], error) {
+	inc, ok := idx[ApiLinkage{Type: typ, ID: id}]
+	if !ok {
+		return nil, errors.New("included 中不存在 " + typ + " " + id)
+	}
+	return decodeIncluded[T](inc)
+}
+
+// ApiIterator 按 links.next 逐页读取列表 只在需要时请求下一页
+type ApiIterator[T any] struct {
+	api      *Api
+	next     string
+	query    url.Values
+	page     []ApiResource[T]
+	index    int
+	included ApiIncludedIndex
+	total    int
+	err      error
+}
+
+// NewApiIterator 创建列表迭代器 path 同 List 系列方法
+//
+//	it := NewApiIterator[AppAttributes](api, "apps", NewApiQuery().Limit(200).Values())
+//	for it.Next(ctx) {
+//		app := it.Item()
+//	}
+//	err := it.Err()
+func NewApiIterator[T any](a *Api, path string, query url.Values) *ApiIterator[T] {
+	return &ApiIterator[T]{api: a, next: path, query: query, index: -1, included: ApiIncludedIndex{}}
+}
+
+// Next 移动到下一项 没有更多数据或出错时返回 false
+func (it *ApiIterator[T]) Next(ctx context.Context) bool {
+	if it.err != nil {
+		return false
+	}
+	if err := ctx.Err(); err != nil {
+		it.err = err
+		return false
+	}
+	for it.index+1 >= len(it.page) {
+		if it.next == "" {
+			return false
+		}
+		var doc ApiDocument[[]ApiResource[T]]
+		if it.err = it.api.request(ctx, "GET", it.next, it.query, nil, &doc); it.err != nil {
+			return false
+		}
+		// next 链接已包含全部参数
+		it.query = nil
+		it.next = doc.Links.Next
+		it.page, it.index = doc.Data, -1
+		it.total = doc.Meta.Paging.Total
+		it.included.add(doc.Included)
+	}
+	it.index++
+	return true
+}
+
+// Item 当前项
+func (it *ApiIterator[T]) Item() *ApiResource[T] {
+	if it.index < 0 || it.index >= len(it.page) {
+		return nil
+	}
+	return &it.page[it.index]
+}
+
+// Included 已读取页面中的全部 included 资源
+func (it *ApiIterator[T]) Included() ApiIncludedIndex {
+	return it.included
+}
+
+// Total meta.paging.total 总数
+func (it *ApiIterator[T]) Total() int {
+	return it.total
+}
+
+// Err 迭代过程中的错误
+func (it *ApiIterator[T]) Err() error {
+	return it.err
+}
+
+// All 读取剩余全部数据
+func (it *ApiIterator[T]) All(ctx context.Context) ([]ApiResource[T], error) {
+	var list []ApiResource[T]
+	for it.Next(ctx) {
+		list = append(list, *it.Item())
+	}
+	return list, it.Err()
+}
+
+func decodeIncluded[T any](inc ApiIncluded) (*ApiResource[T], error) {
+	res := &ApiResource[T]{Type: inc.Type, ID: inc.ID, Relationships: inc.Relationships, Links: inc.Links}
+	if len(inc.Attributes) > 0 {
+		if err := json.Unmarshal(inc.Attributes, &res.Attributes); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
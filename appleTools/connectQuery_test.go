@@ -0,0 +1,87 @@
+package appleTools
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestApiQuery(t *testing.T) {
+	q := NewApiQuery().Filter("app", "1", "2").Fields("apps", "name", "bundleId").Include("builds").Include("betaGroups").Sort("-uploadedDate").Limit(200).LimitRelated("builds", 5)
+	v := q.Values()
+	want := map[string]string{
+		"filter[app]":   "1,2",
+		"fields[apps]":  "name,bundleId",
+		"include":       "builds,betaGroups",
+		"sort":          "-uploadedDate",
+		"limit":         "200",
+		"limit[builds]": "5",
+	}
+	for k, s := range want {
+		if v.Get(k) != s {
+			t.Errorf("%s 期望 %s 实际 %s", k, s, v.Get(k))
+		}
+	}
+}
+
+func TestApiIterator(t *testing.T) {
+	var requests int
+	var api *Api
+	api = newTestApi(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		page := r.URL.Query().Get("cursor")
+		next := ""
+		switch page {
+		case "":
+			if r.URL.Query().Get("filter[app]") != "9" || r.URL.Query().Get("include") != "app" {
+				t.Errorf("首页参数错误 %s", r.URL.RawQuery)
+			}
+			next = api.url("builds?cursor=2&limit=2")
+		case "2":
+			if r.URL.Query().Get("filter[app]") != "" {
+				t.Errorf("next 链接不应再拼接参数 %s", r.URL.RawQuery)
+			}
+			next = api.url("builds?cursor=3&limit=2")
+		case "3":
+		default:
+			t.Errorf("未知页 %s", page)
+		}
+		n := requests
+		writeTestJson(w, 200, fmt.Sprintf(`{"data":[{"type":"builds","id":"%d-a","attributes":{"version":"%d"}},{"type":"builds","id":"%d-b"}],
+			"included":[{"type":"apps","id":"app%d","attributes":{"name":"app%d"}}],
+			"links":{"next":"%s"},"meta":{"paging":{"total":6,"limit":2}}}`, n, n, n, n, n, next))
+	})
+
+	it := NewApiIterator[BuildAttributes](api, "builds", NewApiQuery().Filter("app", "9").Include("app").Values())
+	if !it.Next(context.Background()) || it.Item().ID != "1-a" || requests != 1 {
+		t.Fatalf("首项错误 %+v", it.Item())
+	}
+	// 只读一项时不应请求下一页
+	if requests != 1 || it.Total() != 6 {
+		t.Fatalf("不应提前请求下一页 %d", requests)
+	}
+	list, err := it.All(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 5 || list[4].ID != "3-b" || requests != 3 {
+		t.Fatalf("分页读取错误 %d %d", len(list), requests)
+	}
+	app, err := LookupIncluded[AppAttributes](it.Included(), "apps", "app2")
+	if err != nil || app.Attributes.Name != "app2" {
+		t.Fatalf("included 索引错误 %+v %v", app, err)
+	}
+	if _, err = LookupIncluded[AppAttributes](it.Included(), "apps", "none"); err == nil {
+		t.Fatal("不存在的资源应返回错误")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	it = NewApiIterator[BuildAttributes](api, "builds", NewApiQuery().Filter("app", "9").Include("app").Values())
+	it.Next(ctx)
+	it.Next(ctx)
+	cancel()
+	if it.Next(ctx) || it.Err() != context.Canceled {
+		t.Fatalf("取消后应停止 %v", it.Err())
+	}
+}
@@ -0,0 +1,121 @@
+package appleTools
+
+import (
+	"context"
+	"net/url"
+)
+
+// AppStoreReviewDetailAttributes 审核联系人和演示账号
+type AppStoreReviewDetailAttributes struct {
+	ContactFirstName    string `json:"contactFirstName,omitempty"`
+	ContactLastName     string `json:"contactLastName,omitempty"`
+	ContactPhone        string `json:"contactPhone,omitempty"`
+	ContactEmail        string `json:"contactEmail,omitempty"`
+	DemoAccountName     string `json:"demoAccountName,omitempty"`
+	DemoAccountPassword string `json:"demoAccountPassword,omitempty"`
+	DemoAccountRequired bool   `json:"demoAccountRequired"`
+	Notes               string `json:"notes,omitempty"`
+}
+
+// ReviewSubmissionAttributes 审核提交
+type ReviewSubmissionAttributes struct {
+	Platform      string `json:"platform,omitempty"`
+	SubmittedDate string `json:"submittedDate,omitempty"`
+	State         string `json:"state,omitempty"` // READY_FOR_REVIEW WAITING_FOR_REVIEW IN_REVIEW UNRESOLVED_ISSUES CANCELING COMPLETING COMPLETE
+	Submitted     *bool  `json:"submitted,omitempty"`
+	Canceled      *bool  `json:"canceled,omitempty"`
+}
+
+// ReviewSubmissionItemAttributes 审核提交项
+type ReviewSubmissionItemAttributes struct {
+	State string `json:"state,omitempty"` // READY_FOR_REVIEW ACCEPTED APPROVED REJECTED REMOVED
+}
+
+type (
+	AppStoreReviewDetail = ApiResource[AppStoreReviewDetailAttributes]
+	ReviewSubmission     = ApiResource[ReviewSubmissionAttributes]
+	ReviewSubmissionItem = ApiResource[ReviewSubmissionItemAttributes]
+)
+
+// SetVersionBuild 为版本选择构建版本
+func (a *Api) SetVersionBuild(versionID, buildID string) error {
+	return a.request(context.Background(), "PATCH", "appStoreVersions/"+versionID+"/relationships/build", nil,
+		apiRequest[ApiLinkage]{Data: ApiLinkage{Type: "builds", ID: buildID}}, nil)
+}
+
+// GetVersionBuild 版本已选择的构建版本 未选择时 Data.ID 为空
+func (a *Api) GetVersionBuild(versionID string) (*ApiDocument[Build], error) {
+	return apiGet[BuildAttributes](a, "appStoreVersions/"+versionID+"/build", nil)
+}
+
+// SetBuildEncryption 设置出口合规 是否使用非豁免加密
+func (a *Api) SetBuildEncryption(buildID string, usesNonExemptEncryption bool) (*Build, error) {
+	return apiSave[BuildAttributes](a, "PATCH", "builds/"+buildID, Build{
+		Type:       "builds",
+		ID:         buildID,
+		Attributes: BuildAttributes{UsesNonExemptEncryption: &usesNonExemptEncryption},
+	})
+}
+
+// GetAppStoreReviewDetail 版本的审核信息 未填写过时返回 404
+func (a *Api) GetAppStoreReviewDetail(versionID string) (*ApiDocument[AppStoreReviewDetail], error) {
+	return apiGet[AppStoreReviewDetailAttributes](a, "appStoreVersions/"+versionID+"/appStoreReviewDetail", nil)
+}
+
+// CreateAppStoreReviewDetail 填写审核信息
+func (a *Api) CreateAppStoreReviewDetail(versionID string, attr AppStoreReviewDetailAttributes) (*AppStoreReviewDetail, error) {
+	return apiSave[AppStoreReviewDetailAttributes](a, "POST", "appStoreReviewDetails", AppStoreReviewDetail{
+		Type:          "appStoreReviewDetails",
+		Attributes:    attr,
+		Relationships: map[string]*ApiRelationship{"appStoreVersion": ToOne("appStoreVersions", versionID)},
+	})
+}
+
+// UpdateAppStoreReviewDetail 修改审核信息
+func (a *Api) UpdateAppStoreReviewDetail(id string, attr AppStoreReviewDetailAttributes) (*AppStoreReviewDetail, error) {
+	return apiSave[AppStoreReviewDetailAttributes](a, "PATCH", "appStoreReviewDetails/"+id, AppStoreReviewDetail{
+		Type: "appStoreReviewDetails", ID: id, Attributes: attr,
+	})
+}
+
+// ListReviewSubmissions 审核提交列表 需要 filter[app]
+func (a *Api) ListReviewSubmissions(query url.Values) (*ApiDocument[[]ReviewSubmission], error) {
+	return apiList[ReviewSubmissionAttributes](a, "reviewSubmissions", query)
+}
+
+// GetReviewSubmission 审核提交详情
+func (a *Api) GetReviewSubmission(id string, query url.Values) (*ApiDocument[ReviewSubmission], error) {
+	return apiGet[ReviewSubmissionAttributes](a, "reviewSubmissions/"+id, query)
+}
+
+// CreateReviewSubmission 创建审核提交
+func (a *Api) CreateReviewSubmission(appID, platform string) (*ReviewSubmission, error) {
+	return apiSave[ReviewSubmissionAttributes](a, "POST", "reviewSubmissions", ReviewSubmission{
+		Type:          "reviewSubmissions",
+		Attributes:    ReviewSubmissionAttributes{Platform: platform},
+		Relationships: map[string]*ApiRelationship{"app": ToOne("apps", appID)},
+	})
+}
+
+// UpdateReviewSubmission 提交审核(submitted) 或取消(canceled)
+func (a *Api) UpdateReviewSubmission(id string, attr ReviewSubmissionAttributes) (*ReviewSubmission, error) {
+	return apiSave[ReviewSubmissionAttributes](a, "PATCH", "reviewSubmissions/"+id, ReviewSubmission{
+		Type: "reviewSubmissions", ID: id, Attributes: attr,
+	})
+}
+
+// ListReviewSubmissionItems 审核提交项
+func (a *Api) ListReviewSubmissionItems(submissionID string, query url.Values) (*ApiDocument[[]ReviewSubmissionItem], error) {
+	return apiList[ReviewSubmissionItemAttributes](a, "reviewSubmissions/"+submissionID+"/items", query)
+}
+
+// AddReviewSubmissionVersion 把版本加入审核提交
+func (a *Api) AddReviewSubmissionVersion(submissionID, versionID string) (*ReviewSubmissionItem, error) {
+	return apiSave[ReviewSubmissionItemAttributes](a, "POST", "reviewSubmissionItems", ReviewSubmissionItem{
+		Type: "reviewSubmissionItems",
+		Relationships: map[string]*ApiRelationship{
+			"reviewSubmission": ToOne("reviewSubmissions", submissionID),
+			"appStoreVersion":  ToOne("appStoreVersions", versionID),
+		},
+	})
+}
@@ -0,0 +1,159 @@
+package appleTools
+
+import "net/url"
+
+// BetaTesterAttributes TestFlight 测试员
+type BetaTesterAttributes struct {
+	FirstName  string `json:"firstName,omitempty"`
+	LastName   string `json:"lastName,omitempty"`
+	Email      string `json:"email,omitempty"`
+	InviteType string `json:"inviteType,omitempty"` // EMAIL PUBLIC_LINK
+}
+
+// BetaGroupAttributes TestFlight 测试组
+type BetaGroupAttributes struct {
+	Name                   string `json:"name,omitempty"`
+	CreatedDate            string `json:"createdDate,omitempty"`
+	IsInternalGroup        bool   `json:"isInternalGroup,omitempty"`
+	HasAccessToAllBuilds   bool   `json:"hasAccessToAllBuilds,omitempty"`
+	PublicLinkEnabled      bool   `json:"publicLinkEnabled,omitempty"`
+	PublicLinkID           string `json:"publicLinkId,omitempty"`
+	PublicLinkLimitEnabled bool   `json:"publicLinkLimitEnabled,omitempty"`
+	PublicLinkLimit        int    `json:"publicLinkLimit,omitempty"`
+	PublicLink             string `json:"publicLink,omitempty"`
+	FeedbackEnabled        bool   `json:"feedbackEnabled,omitempty"`
+}
+
+type (
+	BetaTester = ApiResource[BetaTesterAttributes]
+	BetaGroup  = ApiResource[BetaGroupAttributes]
+)
+
+// ListBetaTesters 测试员列表
+func (a *Api) ListBetaTesters(query url.Values) (*ApiDocument[[]BetaTester], error) {
+	return apiList[BetaTesterAttributes](a, "betaTesters", query)
+}
+
+// CreateBetaTester 添加测试员 并加入 groups 指定的测试组
+func (a *Api) CreateBetaTester(attr BetaTesterAttributes, groups ...string) (*BetaTester, error) {
+	t := BetaTester{Type: "betaTesters", Attributes: attr}
+	if len(groups) > 0 {
+		t.Relationships = map[string]*ApiRelationship{"betaGroups": ToMany("betaGroups", groups...)}
+	}
+	return apiSave[BetaTesterAttributes](a, "POST", "betaTesters", t)
+}
+
+// DeleteBetaTester 删除测试员
+func (a *Api) DeleteBetaTester(id string) error {
+	return apiDelete(a, "betaTesters/"+id)
+}
+
+// ListBetaGroups 测试组列表
+func (a *Api) ListBetaGroups(query url.Values) (*ApiDocument[[]BetaGroup], error) {
+	return apiList[BetaGroupAttributes](a, "betaGroups", query)
+}
+
+// GetBetaGroup 测试组详情
+func (a *Api) GetBetaGroup(id string, query url.Values) (*ApiDocument[BetaGroup], error) {
+	return apiGet[BetaGroupAttributes](a, "betaGroups/"+id, query)
+}
+
+// CreateBetaGroup 创建测试组
+func (a *Api) CreateBetaGroup(appID string, attr BetaGroupAttributes) (*BetaGroup, error) {
+	return apiSave[BetaGroupAttributes](a, "POST", "betaGroups", BetaGroup{
+		Type:          "betaGroups",
+		Attributes:    attr,
+		Relationships: map[string]*ApiRelationship{"app": ToOne("apps", appID)},
+	})
+}
+
+// DeleteBetaGroup 删除测试组
+func (a *Api) DeleteBetaGroup(id string) error {
+	return apiDelete(a, "betaGroups/"+id)
+}
+
+// BetaGroupUpdate 修改测试组 nil 字段不修改
+type BetaGroupUpdate struct {
+	Name                   string `json:"name,omitempty"`
+	PublicLinkEnabled      *bool  `json:"publicLinkEnabled,omitempty"`
+	PublicLinkLimitEnabled *bool  `json:"publicLinkLimitEnabled,omitempty"`
+	PublicLinkLimit        *int   `json:"publicLinkLimit,omitempty"`
+	FeedbackEnabled        *bool  `json:"feedbackEnabled,omitempty"`
+}
+
+// BetaBuildLocalizationAttributes 构建版本测试说明
+type BetaBuildLocalizationAttributes struct {
+	WhatsNew string `json:"whatsNew,omitempty"`
+	Locale   string `json:"locale,omitempty"`
+}
+
+// BetaAppReviewSubmissionAttributes 外部测试审核提交
+type BetaAppReviewSubmissionAttributes struct {
+	BetaReviewState string `json:"betaReviewState,omitempty"` // WAITING_FOR_REVIEW IN_REVIEW REJECTED APPROVED
+	SubmittedDate   string `json:"submittedDate,omitempty"`
+}
+
+type (
+	BetaBuildLocalization   = ApiResource[BetaBuildLocalizationAttributes]
+	BetaAppReviewSubmission = ApiResource[BetaAppReviewSubmissionAttributes]
+)
+
+// UpdateBetaGroup 修改测试组
+func (a *Api) UpdateBetaGroup(id string, attr BetaGroupUpdate) (*BetaGroup, error) {
+	return apiSave[BetaGroupAttributes](a, "PATCH", "betaGroups/"+id, ApiResource[BetaGroupUpdate]{
+		Type:       "betaGroups",
+		ID:         id,
+		Attributes: attr,
+	})
+}
+
+// AddBetaTestersToGroup 把已有测试员加入测试组
+func (a *Api) AddBetaTestersToGroup(groupID string, testers ...string) error {
+	return apiLinkage(a, "POST", "betaGroups/"+groupID+"/relationships/betaTesters", "betaTesters", testers...)
+}
+
+// RemoveBetaTestersFromGroup 把测试员移出测试组
+func (a *Api) RemoveBetaTestersFromGroup(groupID string, testers ...string) error {
+	return apiLinkage(a, "DELETE", "betaGroups/"+groupID+"/relationships/betaTesters", "betaTesters", testers...)
+}
+
+// AddBuildsToGroup 向测试组分发构建版本
+func (a *Api) AddBuildsToGroup(groupID string, builds ...string) error {
+	return apiLinkage(a, "POST", "betaGroups/"+groupID+"/relationships/builds", "builds", builds...)
+}
+
+// RemoveBuildsFromGroup 从测试组移除构建版本
+func (a *Api) RemoveBuildsFromGroup(groupID string, builds ...string) error {
+	return apiLinkage(a, "DELETE", "betaGroups/"+groupID+"/relationships/builds", "builds", builds...)
+}
+
+// ListBetaBuildLocalizations 构建版本的测试说明
+func (a *Api) ListBetaBuildLocalizations(buildID string, query url.Values) (*ApiDocument[[]BetaBuildLocalization], error) {
+	return apiList[BetaBuildLocalizationAttributes](a, "builds/"+buildID+"/betaBuildLocalizations", query)
+}
+
+// CreateBetaBuildLocalization 添加测试说明
+func (a *Api) CreateBetaBuildLocalization(buildID, locale, whatsNew string) (*BetaBuildLocalization, error) {
+	return apiSave[BetaBuildLocalizationAttributes](a, "POST", "betaBuildLocalizations", BetaBuildLocalization{
+		Type:          "betaBuildLocalizations",
+		Attributes:    BetaBuildLocalizationAttributes{Locale: locale, WhatsNew: whatsNew},
+		Relationships: map[string]*ApiRelationship{"build": ToOne("builds", buildID)},
+	})
+}
+
+// UpdateBetaBuildLocalization 修改测试说明
+func (a *Api) UpdateBetaBuildLocalization(id, whatsNew string) (*BetaBuildLocalization, error) {
+	return apiSave[BetaBuildLocalizationAttributes](a, "PATCH", "betaBuildLocalizations/"+id, BetaBuildLocalization{
+		Type:       "betaBuildLocalizations",
+		ID:         id,
+		Attributes: BetaBuildLocalizationAttributes{WhatsNew: whatsNew},
+	})
+}
+
+// SubmitForBetaReview 提交外部测试审核
+func (a *Api) SubmitForBetaReview(buildID string) (*BetaAppReviewSubmission, error) {
+	return apiSave[BetaAppReviewSubmissionAttributes](a, "POST", "betaAppReviewSubmissions", BetaAppReviewSubmission{
+		Type:          "betaAppReviewSubmissions",
+		Relationships: map[string]*ApiRelationship{"build": ToOne("builds", buildID)},
+	})
+}
@@ -0,0 +1,100 @@
+package appleTools
+
+import "net/url"
+
+// UserAttributes 团队成员
+type UserAttributes struct {
+	Username            string   `json:"username,omitempty"`
+	FirstName           string   `json:"firstName,omitempty"`
+	LastName            string   `json:"lastName,omitempty"`
+	Roles               []string `json:"roles,omitempty"` // ADMIN APP_MANAGER DEVELOPER ...
+	AllAppsVisible      bool     `json:"allAppsVisible"`
+	ProvisioningAllowed bool     `json:"provisioningAllowed"`
+}
+
+// UserInvitationAttributes 团队邀请
+type UserInvitationAttributes struct {
+	Email               string   `json:"email,omitempty"`
+	FirstName           string   `json:"firstName,omitempty"`
+	LastName            string   `json:"lastName,omitempty"`
+	ExpirationDate      string   `json:"expirationDate,omitempty"`
+	Roles               []string `json:"roles,omitempty"`
+	AllAppsVisible      bool     `json:"allAppsVisible"`
+	ProvisioningAllowed bool     `json:"provisioningAllowed"`
+}
+
+type (
+	User           = ApiResource[UserAttributes]
+	UserInvitation = ApiResource[UserInvitationAttributes]
+)
+
+// ListUsers 团队成员列表
+func (a *Api) ListUsers(query url.Values) (*ApiDocument[[]User], error) {
+	return apiList[UserAttributes](a, "users", query)
+}
+
+// GetUser 团队成员详情
+func (a *Api) GetUser(id string, query url.Values) (*ApiDocument[User], error) {
+	return apiGet[UserAttributes](a, "users/"+id, query)
+}
+
+// RemoveUser 移除团队成员
+func (a *Api) RemoveUser(id string) error {
+	return apiDelete(a, "users/"+id)
+}
+
+// ListUserInvitations 待接受的邀请
+func (a *Api) ListUserInvitations(query url.Values) (*ApiDocument[[]UserInvitation], error) {
+	return apiList[UserInvitationAttributes](a, "userInvitations", query)
+}
+
+// InviteUser 邀请成员 visibleApps 仅在 AllAppsVisible 为 false 时生效
+func (a *Api) InviteUser(attr UserInvitationAttributes, visibleApps ...string) (*UserInvitation, error) {
+	inv := UserInvitation{Type: "userInvitations", Attributes: attr}
+	if !attr.AllAppsVisible && len(visibleApps) > 0 {
+		inv.Relationships = map[string]*ApiRelationship{"visibleApps": ToMany("apps", visibleApps...)}
+	}
+	return apiSave[UserInvitationAttributes](a, "POST", "userInvitations", inv)
+}
+
+// CancelUserInvitation 取消邀请
+func (a *Api) CancelUserInvitation(id string) error {
+	return apiDelete(a, "userInvitations/"+id)
+}
+
+const (
+	UserRoleAccountHolder   = "ACCOUNT_HOLDER"
+	UserRoleAdmin           = "ADMIN"
+	UserRoleFinance         = "FINANCE"
+	UserRoleAppManager      = "APP_MANAGER"
+	UserRoleDeveloper       = "DEVELOPER"
+	UserRoleMarketing       = "MARKETING"
+	UserRoleSales           = "SALES"
+	UserRoleCustomerSupport = "CUSTOMER_SUPPORT"
+)
+
+// UserUpdate 修改成员 nil 字段不修改
+type UserUpdate struct {
+	Roles               []string `json:"roles,omitempty"`
+	AllAppsVisible      *bool    `json:"allAppsVisible,omitempty"`
+	ProvisioningAllowed *bool    `json:"provisioningAllowed,omitempty"`
+}
+
+// UpdateUser 修改成员角色和权限 visibleApps 不为空时同时替换可见应用
+func (a *Api) UpdateUser(id string, attr UserUpdate, visibleApps ...string) (*User, error) {
+	u := ApiResource[UserUpdate]{Type: "users", ID: id, Attributes: attr}
+	if len(visibleApps) > 0 {
+		u.Relationships = map[string]*ApiRelationship{"visibleApps": ToMany("apps", visibleApps...)}
+	}
+	return apiSave[UserAttributes](a, "PATCH", "users/"+id, u)
+}
+
+// ListUserVisibleApps 成员可见的应用
+func (a *Api) ListUserVisibleApps(id string, query url.Values) (*ApiDocument[[]App], error) {
+	return apiList[AppAttributes](a, "users/"+id+"/visibleApps", query)
+}
+
+// SetUserVisibleApps 替换成员可见的应用
+func (a *Api) SetUserVisibleApps(id string, apps ...string) error {
+	return apiLinkage(a, "PATCH", "users/"+id+"/relationships/visibleApps", "apps", apps...)
+}
@@ -0,0 +1,159 @@
+package appleTools
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// CustomerReviewAttributes 用户评论
+type CustomerReviewAttributes struct {
+	Rating           int    `json:"rating"`
+	Title            string `json:"title"`
+	Body             string `json:"body"`
+	ReviewerNickname string `json:"reviewerNickname"`
+	CreatedDate      string `json:"createdDate"`
+	Territory        string `json:"territory"` // 三位国家代码 如 USA CHN
+}
+
+// Created 评论时间
+func (c CustomerReviewAttributes) Created() time.Time {
+	t, _ := time.Parse(time.RFC3339, c.CreatedDate)
+	return t
+}
+
+// CustomerReviewResponseAttributes 开发者回复
+type CustomerReviewResponseAttributes struct {
+	ResponseBody     string `json:"responseBody,omitempty"`
+	LastModifiedDate string `json:"lastModifiedDate,omitempty"`
+	State            string `json:"state,omitempty"` // PENDING_PUBLISH PUBLISHED
+}
+
+type (
+	CustomerReview         = ApiResource[CustomerReviewAttributes]
+	CustomerReviewResponse = ApiResource[CustomerReviewResponseAttributes]
+)
+
+// CustomerReviewFilter 评论筛选 日期在本地按 createdDate 过滤
+type CustomerReviewFilter struct {
+	Territories []string  // 国家 为空时全部
+	Ratings     []int     // 星级 为空时全部
+	Since       time.Time // 不早于该时间 零值不限制
+	Until       time.Time // 不晚于该时间 零值不限制
+	Unanswered  bool      // 只返回未回复的
+	Oldest      bool      // 从旧到新 默认从新到旧
+}
+
+// CustomerReviewPager 按页读取评论 回复通过 include=response 一起返回
+type CustomerReviewPager struct {
+	it     *ApiIterator[CustomerReviewAttributes]
+	filter CustomerReviewFilter
+}
+
+// CustomerReviews 应用评论分页读取
+//
+//	p := api.CustomerReviews("appID", CustomerReviewFilter{Ratings: []int{1, 2}})
+//	for p.Next(ctx) {
+//		review, response := p.Review(), p.Response()
+//	}
+//	err := p.Err()
+func (a *Api) CustomerReviews(appID string, filter CustomerReviewFilter) *CustomerReviewPager {
+	q := NewApiQuery().Include("response").Limit(200)
+	if filter.Oldest {
+		q.Sort("createdDate")
+	} else {
+		q.Sort("-createdDate")
+	}
+	if len(filter.Territories) > 0 {
+		q.Filter("territory", filter.Territories...)
+	}
+	if len(filter.Ratings) > 0 {
+		var ratings []string
+		for _, r := range filter.Ratings {
+			ratings = append(ratings, strconv.Itoa(r))
+		}
+		q.Filter("rating", ratings...)
+	}
+	if filter.Unanswered {
+		q.Set("exists[publishedResponse]", "false")
+	}
+	return &CustomerReviewPager{it: NewApiIterator[CustomerReviewAttributes](a, "apps/"+appID+"/customerReviews", q.Values()), filter: filter}
+}
+
+// Next 移动到下一条符合条件的评论 超出日期范围后不再请求下一页
+func (p *CustomerReviewPager) Next(ctx context.Context) bool {
+	for p.it.Next(ctx) {
+		created := p.it.Item().Attributes.Created()
+		before := !p.filter.Since.IsZero() && created.Before(p.filter.Since)
+		after := !p.filter.Until.IsZero() && created.After(p.filter.Until)
+		switch {
+		case before && !p.filter.Oldest, after && p.filter.Oldest:
+			return false
+		case before || after:
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// Review 当前评论
+func (p *CustomerReviewPager) Review() *CustomerReview {
+	return p.it.Item()
+}
+
+// Response 当前评论的回复 没有回复时为 nil
+func (p *CustomerReviewPager) Response() *CustomerReviewResponse {
+	l := p.it.Item().Related("response").One()
+	if l == nil {
+		return nil
+	}
+	r, err := LookupIncluded[CustomerReviewResponseAttributes](p.it.Included(), l.Type, l.ID)
+	if err != nil {
+		return nil
+	}
+	return r
+}
+
+// Err 读取过程中的错误
+func (p *CustomerReviewPager) Err() error {
+	return p.it.Err()
+}
+
+// All 读取全部符合条件的评论
+func (p *CustomerReviewPager) All(ctx context.Context) ([]CustomerReview, error) {
+	var list []CustomerReview
+	for p.Next(ctx) {
+		list = append(list, *p.Review())
+	}
+	return list, p.Err()
+}
+
+// GetCustomerReviewResponse 评论的回复 未回复时返回 404
+func (a *Api) GetCustomerReviewResponse(reviewID string) (*ApiDocument[CustomerReviewResponse], error) {
+	return apiGet[CustomerReviewResponseAttributes](a, "customerReviews/"+reviewID+"/response", nil)
+}
+
+// RespondCustomerReview 回复评论 已有回复时会被替换
+func (a *Api) RespondCustomerReview(reviewID, body string) (*CustomerReviewResponse, error) {
+	if body == "" {
+		return nil, errors.New("回复内容不能为空")
+	}
+	return apiSave[CustomerReviewResponseAttributes](a, "POST", "customerReviewResponses", CustomerReviewResponse{
+		Type:          "customerReviewResponses",
+		Attributes:    CustomerReviewResponseAttributes{ResponseBody: body},
+		Relationships: map[string]*ApiRelationship{"review": ToOne("customerReviews", reviewID)},
+	})
+}
+
+// DeleteCustomerReviewResponse 删除回复
+func (a *Api) DeleteCustomerReviewResponse(id string) error {
+	return apiDelete(a, "customerReviewResponses/"+id)
+}
+
+// ListCustomerReviews 单页评论 query 需自行设置
+func (a *Api) ListCustomerReviews(appID string, query url.Values) (*ApiDocument[[]CustomerReview], error) {
+	return apiList[CustomerReviewAttributes](a, "apps/"+appID+"/customerReviews", query)
+}
@@ -0,0 +1,93 @@
+package appleTools
+
+import (
+	"context"
+	"github.com/tidwall/gjson"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestApi_CustomerReviews(t *testing.T) {
+	var pages int
+	api := newTestApi(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		switch r.Method + " " + r.URL.Path {
+		case "GET /v1/apps/app1/customerReviews":
+			pages++
+			q := r.URL.Query()
+			if q.Get("page") == "2" {
+				writeTestJson(w, 200, `{"data":[
+					{"type":"customerReviews","id":"r3","attributes":{"rating":2,"createdDate":"2026-10-01T08:00:00-07:00","territory":"USA"}},
+					{"type":"customerReviews","id":"r4","attributes":{"rating":1,"createdDate":"2026-09-01T08:00:00-07:00","territory":"USA"}}],
+					"links":{"next":"/v1/apps/app1/customerReviews?page=3"}}`)
+				return
+			}
+			if q.Get("filter[territory]") != "USA,CHN" || q.Get("filter[rating]") != "1,2" || q.Get("sort") != "-createdDate" || q.Get("include") != "response" {
+				t.Errorf("评论筛选错误 %s", r.URL.RawQuery)
+			}
+			writeTestJson(w, 200, `{"data":[
+				{"type":"customerReviews","id":"r1","attributes":{"rating":1,"title":"差","body":"闪退","reviewerNickname":"u1","createdDate":"2026-10-18T08:00:00-07:00","territory":"CHN"},
+					"relationships":{"response":{"data":{"type":"customerReviewResponses","id":"resp1"}}}},
+				{"type":"customerReviews","id":"r2","attributes":{"rating":2,"createdDate":"2026-10-10T08:00:00-07:00","territory":"USA"},
+					"relationships":{"response":{"data":null}}}],
+				"included":[{"type":"customerReviewResponses","id":"resp1","attributes":{"responseBody":"已修复","state":"PUBLISHED"}}],
+				"links":{"next":"/v1/apps/app1/customerReviews?page=2"}}`)
+		case "POST /v1/customerReviewResponses":
+			if gjson.GetBytes(body, "data.relationships.review.data.id").String() != "r2" {
+				t.Errorf("回复请求错误 %s", body)
+			}
+			writeTestJson(w, 201, `{"data":{"type":"customerReviewResponses","id":"resp2","attributes":{"responseBody":"`+gjson.GetBytes(body, "data.attributes.responseBody").String()+`","state":"PENDING_PUBLISH"}}}`)
+		case "DELETE /v1/customerReviewResponses/resp1":
+			w.WriteHeader(204)
+		default:
+			t.Errorf("未知请求 %s %s", r.Method, r.URL.Path)
+		}
+	})
+	p := api.CustomerReviews("app1", CustomerReviewFilter{
+		Territories: []string{"USA", "CHN"},
+		Ratings:     []int{1, 2},
+		Since:       time.Date(2026, 9, 15, 0, 0, 0, 0, time.UTC),
+		Until:       time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC),
+	})
+	var ids []string
+	ctx := context.Background()
+	for p.Next(ctx) {
+		ids = append(ids, p.Review().ID)
+		if resp := p.Response(); resp != nil {
+			t.Errorf("r1 应被日期过滤 %+v", resp)
+		}
+	}
+	if err := p.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 || ids[0] != "r2" || ids[1] != "r3" || pages != 2 {
+		t.Fatalf("评论筛选结果错误 %v 请求 %d 页", ids, pages)
+	}
+
+	p = api.CustomerReviews("app1", CustomerReviewFilter{Territories: []string{"USA", "CHN"}, Ratings: []int{1, 2}, Until: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)})
+	pages = 0
+	if !p.Next(ctx) || p.Review().ID != "r4" || pages != 2 {
+		t.Fatalf("应跳过晚于 Until 的评论 %v", p.Err())
+	}
+
+	p = api.CustomerReviews("app1", CustomerReviewFilter{Territories: []string{"USA", "CHN"}, Ratings: []int{1, 2}})
+	if !p.Next(ctx) || p.Response() == nil || p.Response().Attributes.ResponseBody != "已修复" || p.Review().Attributes.Created().Day() != 18 {
+		t.Fatalf("回复解析错误 %v", p.Err())
+	}
+	if !p.Next(ctx) || p.Response() != nil {
+		t.Fatal("未回复的评论 Response 应为 nil")
+	}
+
+	resp, err := api.RespondCustomerReview("r2", "感谢反馈")
+	if err != nil || resp.ID != "resp2" || resp.Attributes.ResponseBody != "感谢反馈" {
+		t.Fatalf("回复失败 %+v %v", resp, err)
+	}
+	if _, err = api.RespondCustomerReview("r2", ""); err == nil {
+		t.Fatal("回复内容为空应返回错误")
+	}
+	if err = api.DeleteCustomerReviewResponse("resp1"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,81 @@
+package appleTools
+
+import (
+	"context"
+	"crypto/sha1"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	hashcashVersion = 1
+	hashcashMaxBits = 32               // 超过该难度直接放弃 防止占满CPU
+	hashcashTimeout = 30 * time.Second // 登录时计算证明的最长时间
+)
+
+// Hashcash 计算苹果登录要求的 hashcash 证明 (X-Apple-HC)
+// 格式为 1:bits:date:challenge::counter 其 SHA-1 的前 bits 位全为0
+func Hashcash(ctx context.Context, bits int, challenge string, date time.Time) (string, error) {
+	if bits < 0 || bits > hashcashMaxBits {
+		return "", fmt.Errorf("hashcash 难度 %d 超出范围", bits)
+	}
+	prefix := fmt.Sprintf("%d:%d:%s:%s::", hashcashVersion, bits, date.Format("20060102150405"), challenge)
+	buf := []byte(prefix)
+	for counter := uint64(0); ; counter++ {
+		if counter&0xffff == 0 {
+			if err := ctx.Err(); err != nil {
+				return "", err
+			}
+		}
+		buf = strconv.AppendUint(buf[:len(prefix)], counter, 10)
+		if leadingZeroBits(sha1.Sum(buf), bits) {
+			return string(buf), nil
+		}
+	}
+}
+
+func leadingZeroBits(sum [sha1.Size]byte, bits int) bool {
+	for i := 0; bits > 0; i++ {
+		if bits >= 8 {
+			if sum[i] != 0 {
+				return false
+			}
+			bits -= 8
+			continue
+		}
+		return sum[i]>>(8-bits) == 0
+	}
+	return true
+}
+
+// fetchHashcash 获取防机器人挑战并把证明写入会话头 没有挑战或获取失败时不做处理
+func (a *AuthSession) fetchHashcash() error {
+	res, err := a.http().Get(a.Auth.authUrl("/signin?widgetKey=" + appleAuthXAppleWidgetKeyAppStore))
+	if res != nil && res.Response != nil {
+		defer res.Body.Close()
+	}
+	if err != nil {
+		// 预取失败不影响登录 不带 X-Apple-HC 继续 真正的错误由登录请求返回
+		return nil
+	}
+	bits, challenge := res.Header.Get("X-Apple-HC-Bits"), res.Header.Get("X-Apple-HC-Challenge")
+	if bits == "" || challenge == "" {
+		return nil
+	}
+	n, err := strconv.Atoi(bits)
+	if err != nil {
+		return fmt.Errorf("无法解析 X-Apple-HC-Bits %s", bits)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), hashcashTimeout)
+	defer cancel()
+	hc, err := Hashcash(ctx, n, challenge, time.Now())
+	if err != nil {
+		return err
+	}
+	if a.Header == nil {
+		a.Header = make(map[string]string)
+	}
+	a.Header["X-Apple-HC"] = hc
+	return nil
+}
@@ -0,0 +1,42 @@
+package appleTools
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestHashcash(t *testing.T) {
+	var vectors = []struct {
+		bits      int
+		challenge string
+		date      string
+		want      string
+	}{
+		{11, "4d74fb15eb23f465f1f6fcbf534e5877", "20230223170600", "1:11:20230223170600:4d74fb15eb23f465f1f6fcbf534e5877::6373"},
+		{10, "f3a5b1e0c7d94e2a8b6c1d0e9f8a7b6c", "20240101000000", "1:10:20240101000000:f3a5b1e0c7d94e2a8b6c1d0e9f8a7b6c::845"},
+		{16, "apple", "20221019120000", "1:16:20221019120000:apple::79856"},
+		{0, "apple", "20221019120000", "1:0:20221019120000:apple::0"},
+	}
+	for _, v := range vectors {
+		date, _ := time.Parse("20060102150405", v.date)
+		got, err := Hashcash(context.Background(), v.bits, v.challenge, date)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != v.want {
+			t.Errorf("bits=%d 期望 %s 实际 %s", v.bits, v.want, got)
+		}
+	}
+}
+
+func TestHashcash_Limit(t *testing.T) {
+	if _, err := Hashcash(context.Background(), hashcashMaxBits+1, "apple", time.Now()); err == nil {
+		t.Fatal("超出难度上限应返回错误")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Hashcash(ctx, hashcashMaxBits, "apple", time.Now()); err != context.Canceled {
+		t.Fatalf("期望 context.Canceled 实际 %v", err)
+	}
+}
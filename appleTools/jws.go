@@ -0,0 +1,289 @@
+package appleTools
+
+import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+	"strings"
+	"time"
+)
+
+var (
+	// 苹果签名证书的扩展 OID 叶子证书和中间证书必须包含
+	oidAppleLeaf         = asn1.ObjectIdentifier{1, 2, 840, 113635, 100, 6, 11, 1}
+	oidAppleIntermediate = asn1.ObjectIdentifier{1, 2, 840, 113635, 100, 6, 2, 1}
+)
+
+// JWSVerifier 校验苹果签名的 JWS (signedTransactionInfo signedRenewalInfo signedPayload)
+// 使用 x5c 证书链校验到配置的根证书 通常为 Apple Root CA - G3
+type JWSVerifier struct {
+	roots    *x509.CertPool
+	bundleID string
+	now      func() time.Time
+}
+
+// NewJWSVerifier 创建校验器 roots 为根证书 支持 PEM 或 DER bundleID 不为空时校验载荷中的 bundleId
+func NewJWSVerifier(bundleID string, roots ...[]byte) (*JWSVerifier, error) {
+	if len(roots) == 0 {
+		return nil, errors.New("至少需要一个根证书")
+	}
+	pool := x509.NewCertPool()
+	for _, raw := range roots {
+		if block, _ := pem.Decode(raw); block != nil {
+			raw = block.Bytes
+		}
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return nil, fmt.Errorf("根证书解析失败 %s", err)
+		}
+		pool.AddCert(cert)
+	}
+	return &JWSVerifier{roots: pool, bundleID: bundleID, now: time.Now}, nil
+}
+
+// SetTime 设置校验证书有效期使用的时间 为 nil 时使用当前时间
+func (v *JWSVerifier) SetTime(now func() time.Time) {
+	if now == nil {
+		now = time.Now
+	}
+	v.now = now
+}
+
+// Verify 校验签名和证书链 并把载荷解析到 out 设置了 bundleID 时载荷必须包含一致的 bundleId
+func (v *JWSVerifier) Verify(signed string, out any) error {
+	return v.verify(signed, out, v.bundleID != "")
+}
+
+// verify checkBundle 为 true 时校验载荷中的 bundleId 续期信息不含 bundleId 不校验
+func (v *JWSVerifier) verify(signed string, out any, checkBundle bool) error {
+	parts := strings.Split(signed, ".")
+	if len(parts) != 3 {
+		return errors.New("JWS 格式错误")
+	}
+	buf, err := jwt.DecodeSegment(parts[0])
+	if err != nil {
+		return fmt.Errorf("JWS 头解析失败 %s", err)
+	}
+	var header struct {
+		Alg string   `json:"alg"`
+		X5c []string `json:"x5c"`
+	}
+	if err = json.Unmarshal(buf, &header); err != nil {
+		return fmt.Errorf("JWS 头解析失败 %s", err)
+	}
+	if header.Alg != "ES256" {
+		return fmt.Errorf("不支持的签名算法 %s", header.Alg)
+	}
+	leaf, err := v.verifyChain(header.X5c)
+	if err != nil {
+		return err
+	}
+	key, ok := leaf.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return errors.New("签名证书不是 ECDSA 公钥")
+	}
+	if err = jwt.SigningMethodES256.Verify(parts[0]+"."+parts[1], parts[2], key); err != nil {
+		return fmt.Errorf("JWS 签名无效 %s", err)
+	}
+	payload, err := jwt.DecodeSegment(parts[1])
+	if err != nil {
+		return fmt.Errorf("JWS 载荷解析失败 %s", err)
+	}
+	if checkBundle {
+		var p struct {
+			BundleID string `json:"bundleId"`
+			Data     struct {
+				BundleID string `json:"bundleId"`
+			} `json:"data"`
+		}
+		if err = json.Unmarshal(payload, &p); err != nil {
+			return fmt.Errorf("JWS 载荷解析失败 %s", err)
+		}
+		if p.BundleID == "" && p.Data.BundleID == "" {
+			return errors.New("JWS 载荷缺少 bundleId")
+		}
+		for _, bid := range []string{p.BundleID, p.Data.BundleID} {
+			if bid != "" && bid != v.bundleID {
+				return fmt.Errorf("bundleId 不匹配 %s", bid)
+			}
+		}
+	}
+	return json.Unmarshal(payload, out)
+}
+
+// verifyChain x5c 依次为叶子证书 中间证书 根证书
+func (v *JWSVerifier) verifyChain(x5c []string) (*x509.Certificate, error) {
+	if len(x5c) < 2 {
+		return nil, errors.New("x5c 证书链不完整")
+	}
+	var certs []*x509.Certificate
+	for _, s := range x5c {
+		der, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("x5c 解码失败 %s", err)
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("x5c 证书解析失败 %s", err)
+		}
+		certs = append(certs, cert)
+	}
+	intermediates := x509.NewCertPool()
+	for _, c := range certs[1:] {
+		intermediates.AddCert(c)
+	}
+	if _, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         v.roots,
+		Intermediates: intermediates,
+		CurrentTime:   v.now(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return nil, fmt.Errorf("证书链校验失败 %s", err)
+	}
+	if !hasExtension(certs[0], oidAppleLeaf) || !hasExtension(certs[1], oidAppleIntermediate) {
+		return nil, errors.New("证书不是苹果签名证书")
+	}
+	return certs[0], nil
+}
+func hasExtension(cert *x509.Certificate, oid asn1.ObjectIdentifier) bool {
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oid) {
+			return true
+		}
+	}
+	return false
+}
+
+// JWSTransaction 交易信息 时间均为毫秒时间戳
+type JWSTransaction struct {
+	TransactionID               string `json:"transactionId"`
+	OriginalTransactionID       string `json:"originalTransactionId"`
+	WebOrderLineItemID          string `json:"webOrderLineItemId"`
+	BundleID                    string `json:"bundleId"`
+	ProductID                   string `json:"productId"`
+	SubscriptionGroupIdentifier string `json:"subscriptionGroupIdentifier"`
+	PurchaseDate                int64  `json:"purchaseDate"`
+	OriginalPurchaseDate        int64  `json:"originalPurchaseDate"`
+	ExpiresDate                 int64  `json:"expiresDate"`
+	Quantity                    int    `json:"quantity"`
+	Type                        string `json:"type"` // Auto-Renewable Subscription Consumable ...
+	AppAccountToken             string `json:"appAccountToken"`
+	InAppOwnershipType          string `json:"inAppOwnershipType"`
+	SignedDate                  int64  `json:"signedDate"`
+	RevocationReason            *int   `json:"revocationReason"`
+	RevocationDate              int64  `json:"revocationDate"`
+	IsUpgraded                  bool   `json:"isUpgraded"`
+	OfferType                   int    `json:"offerType"`
+	OfferIdentifier             string `json:"offerIdentifier"`
+	Environment                 string `json:"environment"` // Sandbox Production
+	Storefront                  string `json:"storefront"`
+	StorefrontID                string `json:"storefrontId"`
+	TransactionReason           string `json:"transactionReason"`
+	Currency                    string `json:"currency"`
+	Price                       int64  `json:"price"` // 价格乘以1000
+}
+
+// Revoked 是否已退款或被撤销
+func (t *JWSTransaction) Revoked() bool {
+	return t.RevocationDate > 0
+}
+
+// JWSRenewalInfo 订阅续期信息
+type JWSRenewalInfo struct {
+	OriginalTransactionID       string `json:"originalTransactionId"`
+	AutoRenewProductID          string `json:"autoRenewProductId"`
+	ProductID                   string `json:"productId"`
+	AutoRenewStatus             int    `json:"autoRenewStatus"`
+	ExpirationIntent            int    `json:"expirationIntent"`
+	IsInBillingRetryPeriod      bool   `json:"isInBillingRetryPeriod"`
+	PriceIncreaseStatus         *int   `json:"priceIncreaseStatus"`
+	GracePeriodExpiresDate      int64  `json:"gracePeriodExpiresDate"`
+	OfferType                   int    `json:"offerType"`
+	OfferIdentifier             string `json:"offerIdentifier"`
+	SignedDate                  int64  `json:"signedDate"`
+	Environment                 string `json:"environment"`
+	RecentSubscriptionStartDate int64  `json:"recentSubscriptionStartDate"`
+	RenewalDate                 int64  `json:"renewalDate"`
+}
+
+// NotificationPayload App Store Server Notifications V2 载荷
+type NotificationPayload struct {
+	NotificationType string `json:"notificationType"` // SUBSCRIBED DID_RENEW REFUND ...
+	Subtype          string `json:"subtype"`
+	NotificationUUID string `json:"notificationUUID"`
+	Version          string `json:"version"`
+	SignedDate       int64  `json:"signedDate"`
+	Data             struct {
+		AppAppleID            int64  `json:"appAppleId"`
+		BundleID              string `json:"bundleId"`
+		BundleVersion         string `json:"bundleVersion"`
+		Environment           string `json:"environment"`
+		SignedTransactionInfo string `json:"signedTransactionInfo"`
+		SignedRenewalInfo     string `json:"signedRenewalInfo"`
+		Status                int    `json:"status"`
+	} `json:"data"`
+}
+
+// Notification 校验并解析后的通知
+type Notification struct {
+	Payload     NotificationPayload
+	Transaction *JWSTransaction // 通知不含交易时为 nil
+	RenewalInfo *JWSRenewalInfo // 非订阅通知为 nil
+}
+
+// VerifyTransaction 校验并解析 signedTransactionInfo
+func (v *JWSVerifier) VerifyTransaction(signed string) (*JWSTransaction, error) {
+	t := &JWSTransaction{}
+	if err := v.Verify(signed, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// VerifyTransactions 批量校验 signedTransactions
+func (v *JWSVerifier) VerifyTransactions(signed []string) ([]JWSTransaction, error) {
+	list := make([]JWSTransaction, 0, len(signed))
+	for _, s := range signed {
+		t, err := v.VerifyTransaction(s)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *t)
+	}
+	return list, nil
+}
+
+// VerifyRenewalInfo 校验并解析 signedRenewalInfo
+func (v *JWSVerifier) VerifyRenewalInfo(signed string) (*JWSRenewalInfo, error) {
+	r := &JWSRenewalInfo{}
+	if err := v.verify(signed, r, false); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// VerifyNotification 校验通知 signedPayload 以及其中的交易和续期信息
+func (v *JWSVerifier) VerifyNotification(signedPayload string) (*Notification, error) {
+	n := &Notification{}
+	if err := v.Verify(signedPayload, &n.Payload); err != nil {
+		return nil, err
+	}
+	var err error
+	if s := n.Payload.Data.SignedTransactionInfo; s != "" {
+		if n.Transaction, err = v.VerifyTransaction(s); err != nil {
+			return nil, fmt.Errorf("signedTransactionInfo %w", err)
+		}
+	}
+	if s := n.Payload.Data.SignedRenewalInfo; s != "" {
+		if n.RenewalInfo, err = v.VerifyRenewalInfo(s); err != nil {
+			return nil, fmt.Errorf("signedRenewalInfo %w", err)
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,166 @@
+package appleTools
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"github.com/dgrijalva/jwt-go"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testJWSSigner 模拟苹果的签名证书链 根证书 中间证书 叶子证书
+type testJWSSigner struct {
+	rootPem []byte
+	x5c     []string
+	key     *ecdsa.PrivateKey
+}
+
+func newTestJWSSigner(t *testing.T) *testJWSSigner {
+	t.Helper()
+	newCert := func(serial int64, tmpl *x509.Certificate, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tmpl.SerialNumber = big.NewInt(serial)
+		tmpl.NotBefore = time.Now().Add(-time.Hour)
+		tmpl.NotAfter = time.Now().Add(time.Hour)
+		if parent == nil {
+			parent, parentKey = tmpl, key
+		}
+		der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cert, key
+	}
+	ca := func(name string, ext ...pkix.Extension) *x509.Certificate {
+		return &x509.Certificate{
+			Subject:               pkix.Name{CommonName: name},
+			IsCA:                  true,
+			BasicConstraintsValid: true,
+			KeyUsage:              x509.KeyUsageCertSign,
+			ExtraExtensions:       ext,
+		}
+	}
+	marker := func(oid asn1.ObjectIdentifier) pkix.Extension {
+		return pkix.Extension{Id: oid, Value: []byte{5, 0}}
+	}
+	root, rootKey := newCert(1, ca("Test Root"), nil, nil)
+	inter, interKey := newCert(2, ca("Test WWDR", marker(oidAppleIntermediate)), root, rootKey)
+	leaf, leafKey := newCert(3, &x509.Certificate{
+		Subject:         pkix.Name{CommonName: "Test StoreKit"},
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtraExtensions: []pkix.Extension{marker(oidAppleLeaf)},
+	}, inter, interKey)
+	return &testJWSSigner{
+		rootPem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: root.Raw}),
+		x5c: []string{
+			base64.StdEncoding.EncodeToString(leaf.Raw),
+			base64.StdEncoding.EncodeToString(inter.Raw),
+			base64.StdEncoding.EncodeToString(root.Raw),
+		},
+		key: leafKey,
+	}
+}
+
+func (s *testJWSSigner) sign(t *testing.T, payload any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]any{"alg": "ES256", "x5c": s.x5c})
+	body, _ := json.Marshal(payload)
+	signing := jwt.EncodeSegment(header) + "." + jwt.EncodeSegment(body)
+	sig, err := jwt.SigningMethodES256.Sign(signing, s.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signing + "." + sig
+}
+
+func TestJWSVerifier_Notification(t *testing.T) {
+	signer := newTestJWSSigner(t)
+	v, err := NewJWSVerifier("com.demo", signer.rootPem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := signer.sign(t, map[string]any{
+		"notificationType": "DID_RENEW",
+		"notificationUUID": "uuid-1",
+		"version":          "2.0",
+		"data": map[string]any{
+			"bundleId":    "com.demo",
+			"environment": "Sandbox",
+			"signedTransactionInfo": signer.sign(t, map[string]any{
+				"transactionId": "t2", "originalTransactionId": "t1", "bundleId": "com.demo",
+				"productId": "vip.month", "expiresDate": 1700000000000,
+			}),
+			"signedRenewalInfo": signer.sign(t, map[string]any{
+				"originalTransactionId": "t1", "autoRenewProductId": "vip.year", "autoRenewStatus": 1,
+			}),
+		},
+	})
+	n, err := v.VerifyNotification(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.Payload.NotificationType != "DID_RENEW" || n.Payload.Data.Environment != "Sandbox" {
+		t.Errorf("通知解析错误 %+v", n.Payload)
+	}
+	if n.Transaction == nil || n.Transaction.TransactionID != "t2" || n.Transaction.ExpiresDate != 1700000000000 || n.Transaction.Revoked() {
+		t.Errorf("交易解析错误 %+v", n.Transaction)
+	}
+	if n.RenewalInfo == nil || n.RenewalInfo.AutoRenewProductID != "vip.year" {
+		t.Errorf("续期信息解析错误 %+v", n.RenewalInfo)
+	}
+}
+
+func TestJWSVerifier_Reject(t *testing.T) {
+	signer := newTestJWSSigner(t)
+	other := newTestJWSSigner(t)
+	v, err := NewJWSVerifier("com.demo", signer.rootPem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	good := signer.sign(t, map[string]any{"transactionId": "t1", "bundleId": "com.demo"})
+	if _, err = v.VerifyTransaction(good); err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(good, ".")
+	tampered := parts[0] + "." + jwt.EncodeSegment([]byte(`{"transactionId":"t9","bundleId":"com.demo"}`)) + "." + parts[2]
+	cases := map[string]string{
+		"根证书不匹配":     other.sign(t, map[string]any{"transactionId": "t1", "bundleId": "com.demo"}),
+		"签名无效":       tampered,
+		"bundleId":   signer.sign(t, map[string]any{"transactionId": "t1", "bundleId": "com.other"}),
+		"缺少bundleId": signer.sign(t, map[string]any{"transactionId": "t1"}),
+		"bundleId冲突": signer.sign(t, map[string]any{"transactionId": "t1", "bundleId": "com.demo", "data": map[string]any{"bundleId": "com.other"}}),
+		"格式错误":       "abc",
+	}
+	for name, s := range cases {
+		if _, err := v.VerifyTransaction(s); err == nil {
+			t.Errorf("%s 应校验失败", name)
+		}
+	}
+	// 证书过期
+	v.SetTime(func() time.Time { return time.Now().Add(48 * time.Hour) })
+	if _, err := v.VerifyTransaction(good); err == nil {
+		t.Error("证书过期应校验失败")
+	}
+	// 嵌套交易的签名错误也要拒绝
+	v.SetTime(nil)
+	bad := signer.sign(t, map[string]any{"notificationType": "REFUND", "data": map[string]any{"bundleId": "com.demo", "signedTransactionInfo": tampered}})
+	if _, err := v.VerifyNotification(bad); err == nil {
+		t.Error("嵌套交易签名无效应校验失败")
+	}
+}
@@ -0,0 +1,307 @@
+package appleTools
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/xml520/wqutils/httpclient"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	AssetStateAwaitingUpload = "AWAITING_UPLOAD"
+	AssetStateUploadComplete = "UPLOAD_COMPLETE"
+	AssetStateComplete       = "COMPLETE"
+	AssetStateFailed         = "FAILED"
+)
+
+// AssetFailed 截图或预览视频处理失败
+type AssetFailed struct {
+	FileName string
+	State    *AssetDeliveryState
+}
+
+func (e *AssetFailed) Error() string {
+	var s []string
+	for _, item := range e.State.Errors {
+		s = append(s, item.Code+" "+item.Description)
+	}
+	return fmt.Sprintf("%s 处理失败 %s", e.FileName, strings.Join(s, ";"))
+}
+
+// AssetUploader 通过资源预留上传截图和预览视频
+type AssetUploader struct {
+	Api          *Api
+	Concurrency  int           // 分片并发上传数 默认4
+	Replace      bool          // 新文件全部上传完成后删除组内原有的截图或视频 失败时保留原有的
+	PollInterval time.Duration // 处理状态轮询间隔 默认2秒
+	PollTimeout  time.Duration // 等待处理完成的最长时间 默认5分钟
+}
+
+// UploadScreenshots 上传同一显示类型的截图 截图组不存在时自动创建 按 files 顺序上传
+// 上传失败的预留会被删除 Replace 时本次已上传的也会删除 组内保持原样
+func (u *AssetUploader) UploadScreenshots(ctx context.Context, localizationID, displayType string, files ...string) ([]AppScreenshot, error) {
+	sets, err := u.Api.ListAppScreenshotSets(localizationID, NewApiQuery().Filter("screenshotDisplayType", displayType).Values())
+	if err != nil {
+		return nil, err
+	}
+	var set *AppScreenshotSet
+	for i := range sets.Data {
+		if sets.Data[i].Attributes.ScreenshotDisplayType == displayType {
+			set = &sets.Data[i]
+		}
+	}
+	if set == nil {
+		if set, err = u.Api.CreateAppScreenshotSet(localizationID, displayType); err != nil {
+			return nil, fmt.Errorf("创建截图组失败 %w", err)
+		}
+	}
+	var old []AppScreenshot
+	if u.Replace {
+		list, err := u.Api.ListAppScreenshots(set.ID, nil)
+		if err != nil {
+			return nil, err
+		}
+		old = list.Data
+	}
+	var result []AppScreenshot
+	for _, file := range files {
+		var shot AppScreenshot
+		err = u.upload(ctx, file, func(name string, size int64) (string, []UploadOperation, error) {
+			s, err := u.Api.ReserveAppScreenshot(set.ID, name, size)
+			if err != nil {
+				return "", nil, err
+			}
+			return s.ID, s.Attributes.UploadOperations, nil
+		}, func(id, checksum string) error {
+			_, err := u.Api.CommitAppScreenshot(id, checksum)
+			return err
+		}, func(id string) (*AssetDeliveryState, error) {
+			doc, err := u.Api.GetAppScreenshot(id, nil)
+			if err != nil {
+				return nil, err
+			}
+			shot = doc.Data
+			return doc.Data.Attributes.AssetDeliveryState, nil
+		}, u.Api.DeleteAppScreenshot)
+		if err != nil {
+			if !u.Replace {
+				return result, err
+			}
+			for _, s := range result {
+				_ = u.Api.DeleteAppScreenshot(s.ID)
+			}
+			return nil, err
+		}
+		result = append(result, shot)
+	}
+	for _, s := range old {
+		if err = u.Api.DeleteAppScreenshot(s.ID); err != nil {
+			return result, fmt.Errorf("删除旧截图失败 %w", err)
+		}
+	}
+	return result, nil
+}
+
+// UploadPreview 上传预览视频 预览视频组不存在时自动创建 上传失败时删除预留并保留原有视频
+func (u *AssetUploader) UploadPreview(ctx context.Context, localizationID, previewType, file string) (*AppPreview, error) {
+	sets, err := u.Api.ListAppPreviewSets(localizationID, NewApiQuery().Filter("previewType", previewType).Values())
+	if err != nil {
+		return nil, err
+	}
+	var set *AppPreviewSet
+	for i := range sets.Data {
+		if sets.Data[i].Attributes.PreviewType == previewType {
+			set = &sets.Data[i]
+		}
+	}
+	if set == nil {
+		if set, err = u.Api.CreateAppPreviewSet(localizationID, previewType); err != nil {
+			return nil, fmt.Errorf("创建预览视频组失败 %w", err)
+		}
+	}
+	var old []AppPreview
+	if u.Replace {
+		list, err := u.Api.ListAppPreviews(set.ID, nil)
+		if err != nil {
+			return nil, err
+		}
+		old = list.Data
+	}
+	var preview *AppPreview
+	err = u.upload(ctx, file, func(name string, size int64) (string, []UploadOperation, error) {
+		p, err := u.Api.ReserveAppPreview(set.ID, name, size, mime.TypeByExtension(filepath.Ext(name)))
+		if err != nil {
+			return "", nil, err
+		}
+		return p.ID, p.Attributes.UploadOperations, nil
+	}, func(id, checksum string) error {
+		_, err := u.Api.CommitAppPreview(id, checksum)
+		return err
+	}, func(id string) (*AssetDeliveryState, error) {
+		doc, err := u.Api.GetAppPreview(id, nil)
+		if err != nil {
+			return nil, err
+		}
+		preview = &doc.Data
+		return doc.Data.Attributes.AssetDeliveryState, nil
+	}, u.Api.DeleteAppPreview)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range old {
+		if err = u.Api.DeleteAppPreview(p.ID); err != nil {
+			return preview, fmt.Errorf("删除旧预览视频失败 %w", err)
+		}
+	}
+	return preview, nil
+}
+
+// upload 预留 分片上传 提交MD5 轮询处理状态 预留后失败时调用 remove 删除预留
+func (u *AssetUploader) upload(ctx context.Context, file string,
+	reserve func(name string, size int64) (string, []UploadOperation, error),
+	commit func(id, checksum string) error,
+	state func(id string) (*AssetDeliveryState, error),
+	remove func(id string) error) (err error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("无法读取文件 %s", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	hash := md5.New()
+	if _, err = io.Copy(hash, f); err != nil {
+		return err
+	}
+	name := filepath.Base(file)
+	id, ops, err := reserve(name, info.Size())
+	if err != nil {
+		return fmt.Errorf("%s 预留失败 %w", name, err)
+	}
+	defer func() {
+		if err != nil {
+			_ = remove(id)
+		}
+	}()
+	if err = u.uploadOperations(ctx, f, ops); err != nil {
+		return fmt.Errorf("%s 上传失败 %w", name, err)
+	}
+	if err = commit(id, hex.EncodeToString(hash.Sum(nil))); err != nil {
+		return fmt.Errorf("%s 提交失败 %w", name, err)
+	}
+	return u.wait(ctx, name, func() (*AssetDeliveryState, error) { return state(id) })
+}
+
+// uploadOperations 并发执行分片上传
+func (u *AssetUploader) uploadOperations(ctx context.Context, at io.ReaderAt, ops []UploadOperation) error {
+	if len(ops) == 0 {
+		return errors.New("没有上传操作")
+	}
+	concurrency := u.Concurrency
+	if concurrency <= 0 {
+		concurrency = 4
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		wg    sync.WaitGroup
+		once  sync.Once
+		first error
+		limit = make(chan struct{}, concurrency)
+	)
+	for _, op := range ops {
+		select {
+		case limit <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(op UploadOperation) {
+			defer func() {
+				<-limit
+				wg.Done()
+			}()
+			if err := uploadOperation(ctx, at, op); err != nil {
+				once.Do(func() {
+					first = err
+					cancel()
+				})
+			}
+		}(op)
+	}
+	wg.Wait()
+	if first != nil {
+		return first
+	}
+	return ctx.Err()
+}
+func uploadOperation(ctx context.Context, at io.ReaderAt, op UploadOperation) error {
+	data := make([]byte, op.Length)
+	n, err := at.ReadAt(data, op.Offset)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	headers := make(map[string]string, len(op.RequestHeaders))
+	for _, h := range op.RequestHeaders {
+		headers[h.Name] = h.Value
+	}
+	method := op.Method
+	if method == "" {
+		method = "PUT"
+	}
+	res, err := httpclient.NewHttpClient().WithOption(httpclient.OPT_CONTEXT, ctx).Do(method, op.Url, headers, bytes.NewReader(data[:n]))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("分片 %d 上传失败 状态码 %v", op.Offset, res.StatusCode)
+	}
+	return nil
+}
+
+// wait 轮询直到 COMPLETE 或 FAILED
+func (u *AssetUploader) wait(ctx context.Context, name string, state func() (*AssetDeliveryState, error)) error {
+	interval, timeout := u.PollInterval, u.PollTimeout
+	if interval <= 0 {
+		interval = 2 * time.Second
+	}
+	if timeout <= 0 {
+		timeout = 5 * time.Minute
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for {
+		s, err := state()
+		if err != nil {
+			return err
+		}
+		if s != nil {
+			switch s.State {
+			case AssetStateComplete:
+				return nil
+			case AssetStateFailed:
+				return &AssetFailed{FileName: name, State: s}
+			}
+		}
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return fmt.Errorf("%s 等待处理超时 %w", name, ctx.Err())
+		}
+	}
+}
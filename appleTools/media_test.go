@@ -0,0 +1,113 @@
+package appleTools
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/tidwall/gjson"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAssetUploader_Screenshots(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		content  = []byte("0123456789")
+		received = make([]byte, len(content))
+		polls    = make(map[string]int)
+		deleted  []string
+	)
+	dir := t.TempDir()
+	good, bad := filepath.Join(dir, "1.png"), filepath.Join(dir, "2.png")
+	os.WriteFile(good, content, 0644)
+	os.WriteFile(bad, content, 0644)
+	sum := md5.Sum(content)
+
+	api := newTestApi(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method + " " + r.URL.Path {
+		case "GET /v1/appStoreVersionLocalizations/loc1/appScreenshotSets":
+			writeTestJson(w, 200, `{"data":[{"type":"appScreenshotSets","id":"set1","attributes":{"screenshotDisplayType":"APP_IPHONE_67"}}]}`)
+		case "GET /v1/appScreenshotSets/set1/appScreenshots":
+			writeTestJson(w, 200, `{"data":[{"type":"appScreenshots","id":"old"}]}`)
+		case "DELETE /v1/appScreenshots/old", "DELETE /v1/appScreenshots/1.png", "DELETE /v1/appScreenshots/2.png":
+			deleted = append(deleted, filepath.Base(r.URL.Path))
+			w.WriteHeader(204)
+		case "POST /v1/appScreenshots":
+			name := gjson.GetBytes(body, "data.attributes.fileName").String()
+			if gjson.GetBytes(body, "data.attributes.fileSize").Int() != 10 {
+				t.Errorf("文件大小错误 %s", body)
+			}
+			ops := ""
+			for i, off := range []int{0, 4, 8} {
+				length := 4
+				if off == 8 {
+					length = 2
+				}
+				if i > 0 {
+					ops += ","
+				}
+				ops += fmt.Sprintf(`{"method":"PUT","url":"http://%s/upload/%d","offset":%d,"length":%d,"requestHeaders":[{"name":"Content-Type","value":"image/png"}]}`, r.Host, off, off, length)
+			}
+			writeTestJson(w, 201, `{"data":{"type":"appScreenshots","id":"`+name+`","attributes":{"uploadOperations":[`+ops+`]}}}`)
+		case "PATCH /v1/appScreenshots/1.png", "PATCH /v1/appScreenshots/2.png":
+			if !gjson.GetBytes(body, "data.attributes.uploaded").Bool() || gjson.GetBytes(body, "data.attributes.sourceFileChecksum").String() != hex.EncodeToString(sum[:]) {
+				t.Errorf("提交内容错误 %s", body)
+			}
+			writeTestJson(w, 200, `{"data":{"type":"appScreenshots","id":"x"}}`)
+		case "GET /v1/appScreenshots/1.png":
+			polls["1.png"]++
+			state := "UPLOAD_COMPLETE"
+			if polls["1.png"] > 1 {
+				state = "COMPLETE"
+			}
+			writeTestJson(w, 200, `{"data":{"type":"appScreenshots","id":"1.png","attributes":{"assetDeliveryState":{"state":"`+state+`"}}}}`)
+		case "GET /v1/appScreenshots/2.png":
+			writeTestJson(w, 200, `{"data":{"type":"appScreenshots","id":"2.png","attributes":{"assetDeliveryState":{"state":"FAILED","errors":[{"code":"IMAGE_INCORRECT_DIMENSIONS","description":"尺寸错误"}]}}}}`)
+		default:
+			if r.Method == "PUT" && r.Header.Get("Content-Type") == "image/png" {
+				off, _ := strconv.Atoi(filepath.Base(r.URL.Path))
+				copy(received[off:], body)
+				w.WriteHeader(200)
+				return
+			}
+			t.Errorf("未知请求 %s %s", r.Method, r.URL.Path)
+		}
+	})
+	u := &AssetUploader{Api: api, Replace: true, PollInterval: time.Millisecond}
+	shots, err := u.UploadScreenshots(context.Background(), "loc1", "APP_IPHONE_67", good, bad)
+	var failed *AssetFailed
+	if !errors.As(err, &failed) || failed.FileName != "2.png" || failed.State.Errors[0].Code != "IMAGE_INCORRECT_DIMENSIONS" {
+		t.Fatalf("应返回处理失败 %v", err)
+	}
+	// 失败时删除本次上传的 保留原有截图
+	if shots != nil || polls["1.png"] != 2 || strings.Join(deleted, ",") != "2.png,1.png" {
+		t.Fatalf("失败回滚错误 %+v %v %v", shots, polls, deleted)
+	}
+	if string(received) != string(content) {
+		t.Fatalf("分片内容错误 %q", received)
+	}
+
+	deleted = nil
+	shots, err = u.UploadScreenshots(context.Background(), "loc1", "APP_IPHONE_67", good)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shots) != 1 || shots[0].ID != "1.png" || shots[0].Attributes.AssetDeliveryState.State != AssetStateComplete {
+		t.Fatalf("上传结果错误 %+v", shots)
+	}
+	if strings.Join(deleted, ",") != "old" {
+		t.Fatalf("上传完成后应删除旧截图 %v", deleted)
+	}
+}
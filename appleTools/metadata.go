@@ -0,0 +1,279 @@
+package appleTools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	MetadataTargetVersion = "appStoreVersionLocalizations"
+	MetadataTargetAppInfo = "appInfoLocalizations"
+)
+
+// LocalizedMetadata 单个语言的商店信息 空字段表示不修改
+type LocalizedMetadata struct {
+	Name             string `json:"name,omitempty" yaml:"name,omitempty"`
+	Subtitle         string `json:"subtitle,omitempty" yaml:"subtitle,omitempty"`
+	PrivacyPolicyUrl string `json:"privacyPolicyUrl,omitempty" yaml:"privacyPolicyUrl,omitempty"`
+	Description      string `json:"description,omitempty" yaml:"description,omitempty"`
+	Keywords         string `json:"keywords,omitempty" yaml:"keywords,omitempty"`
+	WhatsNew         string `json:"whatsNew,omitempty" yaml:"whatsNew,omitempty"`
+	PromotionalText  string `json:"promotionalText,omitempty" yaml:"promotionalText,omitempty"`
+	MarketingUrl     string `json:"marketingUrl,omitempty" yaml:"marketingUrl,omitempty"`
+	SupportUrl       string `json:"supportUrl,omitempty" yaml:"supportUrl,omitempty"`
+}
+
+// metadataFields 字段 目录中的文件名(兼容 fastlane deliver) 以及所属资源
+var metadataFields = []struct {
+	name   string
+	file   string
+	target string
+	get    func(*LocalizedMetadata) *string
+}{
+	{"name", "name.txt", MetadataTargetAppInfo, func(m *LocalizedMetadata) *string { return &m.Name }},
+	{"subtitle", "subtitle.txt", MetadataTargetAppInfo, func(m *LocalizedMetadata) *string { return &m.Subtitle }},
+	{"privacyPolicyUrl", "privacy_url.txt", MetadataTargetAppInfo, func(m *LocalizedMetadata) *string { return &m.PrivacyPolicyUrl }},
+	{"description", "description.txt", MetadataTargetVersion, func(m *LocalizedMetadata) *string { return &m.Description }},
+	{"keywords", "keywords.txt", MetadataTargetVersion, func(m *LocalizedMetadata) *string { return &m.Keywords }},
+	{"whatsNew", "release_notes.txt", MetadataTargetVersion, func(m *LocalizedMetadata) *string { return &m.WhatsNew }},
+	{"promotionalText", "promotional_text.txt", MetadataTargetVersion, func(m *LocalizedMetadata) *string { return &m.PromotionalText }},
+	{"marketingUrl", "marketing_url.txt", MetadataTargetVersion, func(m *LocalizedMetadata) *string { return &m.MarketingUrl }},
+	{"supportUrl", "support_url.txt", MetadataTargetVersion, func(m *LocalizedMetadata) *string { return &m.SupportUrl }},
+}
+
+// LoadMetadataDir 从目录读取多语言信息 目录结构为 dir/<locale>/description.txt 等
+func LoadMetadataDir(dir string) (map[string]LocalizedMetadata, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]LocalizedMetadata)
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		var (
+			m     LocalizedMetadata
+			found bool
+		)
+		for _, f := range metadataFields {
+			buf, err := os.ReadFile(filepath.Join(dir, e.Name(), f.file))
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			*f.get(&m) = strings.TrimSpace(string(buf))
+			found = true
+		}
+		if found {
+			result[e.Name()] = m
+		}
+	}
+	return result, nil
+}
+
+// MetadataChange 单个字段的变更 Old 为空且 Create 为 true 表示需要新增语言
+type MetadataChange struct {
+	Target string // MetadataTargetVersion 或 MetadataTargetAppInfo
+	Locale string
+	Field  string
+	Old    string
+	New    string
+	Create bool
+}
+
+func (c MetadataChange) String() string {
+	return fmt.Sprintf("%s %s: %q => %q", c.Locale, c.Field, c.Old, c.New)
+}
+
+// metadataState 线上的多语言信息
+type metadataState struct {
+	version map[string]*AppStoreVersionLocalization
+	appInfo map[string]*AppInfoLocalization
+}
+
+func (a *Api) metadataState(versionID, appInfoID string) (*metadataState, error) {
+	s := &metadataState{version: map[string]*AppStoreVersionLocalization{}, appInfo: map[string]*AppInfoLocalization{}}
+	ctx, q := context.Background(), NewApiQuery().Limit(200).Values()
+	if versionID != "" {
+		list, err := NewApiIterator[AppStoreVersionLocalizationAttributes](a, "appStoreVersions/"+versionID+"/appStoreVersionLocalizations", q).All(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("获取版本多语言失败 %w", err)
+		}
+		for i := range list {
+			s.version[list[i].Attributes.Locale] = &list[i]
+		}
+	}
+	if appInfoID != "" {
+		list, err := NewApiIterator[AppInfoLocalizationAttributes](a, "appInfos/"+appInfoID+"/appInfoLocalizations", q).All(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("获取应用信息多语言失败 %w", err)
+		}
+		for i := range list {
+			s.appInfo[list[i].Attributes.Locale] = &list[i]
+		}
+	}
+	return s, nil
+}
+func (s *metadataState) current(locale string) (m LocalizedMetadata, hasVersion, hasAppInfo bool) {
+	if v := s.version[locale]; v != nil {
+		hasVersion = true
+		m.Description, m.Keywords, m.WhatsNew = v.Attributes.Description, v.Attributes.Keywords, v.Attributes.WhatsNew
+		m.PromotionalText, m.MarketingUrl, m.SupportUrl = v.Attributes.PromotionalText, v.Attributes.MarketingUrl, v.Attributes.SupportUrl
+	}
+	if v := s.appInfo[locale]; v != nil {
+		hasAppInfo = true
+		m.Name, m.Subtitle, m.PrivacyPolicyUrl = v.Attributes.Name, v.Attributes.Subtitle, v.Attributes.PrivacyPolicyUrl
+	}
+	return
+}
+func (s *metadataState) diff(versionID, appInfoID string, desired map[string]LocalizedMetadata) []MetadataChange {
+	var changes []MetadataChange
+	for _, locale := range sortedKeys(desired) {
+		want := desired[locale]
+		cur, hasVersion, hasAppInfo := s.current(locale)
+		for _, f := range metadataFields {
+			n, o := strings.TrimSpace(*f.get(&want)), *f.get(&cur)
+			if n == "" || n == o {
+				continue
+			}
+			create := !hasVersion
+			if f.target == MetadataTargetAppInfo {
+				if appInfoID == "" {
+					continue
+				}
+				create = !hasAppInfo
+			} else if versionID == "" {
+				continue
+			}
+			changes = append(changes, MetadataChange{Target: f.target, Locale: locale, Field: f.name, Old: o, New: n, Create: create})
+		}
+	}
+	return changes
+}
+
+// DiffMetadata 比较线上与期望的多语言信息 appInfoID 为空时不比较名称 副标题 隐私政策
+func (a *Api) DiffMetadata(versionID, appInfoID string, desired map[string]LocalizedMetadata) ([]MetadataChange, error) {
+	s, err := a.metadataState(versionID, appInfoID)
+	if err != nil {
+		return nil, err
+	}
+	return s.diff(versionID, appInfoID, desired), nil
+}
+
+// ApplyMetadata 只提交有差异的语言和字段 返回已提交的变更
+func (a *Api) ApplyMetadata(versionID, appInfoID string, desired map[string]LocalizedMetadata) ([]MetadataChange, error) {
+	s, err := a.metadataState(versionID, appInfoID)
+	if err != nil {
+		return nil, err
+	}
+	changes := s.diff(versionID, appInfoID, desired)
+	// 同一语言同一资源的字段合并为一次请求
+	type key struct{ target, locale string }
+	var (
+		order   []key
+		pending = make(map[key]*LocalizedMetadata)
+	)
+	for _, c := range changes {
+		k := key{c.Target, c.Locale}
+		if pending[k] == nil {
+			pending[k] = &LocalizedMetadata{}
+			order = append(order, k)
+		}
+		for _, f := range metadataFields {
+			if f.name == c.Field {
+				*f.get(pending[k]) = c.New
+			}
+		}
+	}
+	for _, k := range order {
+		m := pending[k]
+		if k.target == MetadataTargetVersion {
+			attr := AppStoreVersionLocalizationAttributes{Locale: k.locale, Description: m.Description, Keywords: m.Keywords, WhatsNew: m.WhatsNew,
+				PromotionalText: m.PromotionalText, MarketingUrl: m.MarketingUrl, SupportUrl: m.SupportUrl}
+			if cur := s.version[k.locale]; cur != nil {
+				_, err = a.UpdateAppStoreVersionLocalization(cur.ID, attr)
+			} else {
+				_, err = a.CreateAppStoreVersionLocalization(versionID, attr)
+			}
+		} else {
+			attr := AppInfoLocalizationAttributes{Locale: k.locale, Name: m.Name, Subtitle: m.Subtitle, PrivacyPolicyUrl: m.PrivacyPolicyUrl}
+			if cur := s.appInfo[k.locale]; cur != nil {
+				_, err = a.UpdateAppInfoLocalization(cur.ID, attr)
+			} else {
+				_, err = a.CreateAppInfoLocalization(appInfoID, attr)
+			}
+		}
+		if err != nil {
+			return changes, fmt.Errorf("%s %s 提交失败 %w", k.locale, k.target, err)
+		}
+	}
+	return changes, nil
+}
+
+// editableVersionStates 可以修改商店信息的版本状态
+var editableVersionStates = map[string]bool{
+	"PREPARE_FOR_SUBMISSION": true,
+	"DEVELOPER_REJECTED":     true,
+	"REJECTED":               true,
+	"METADATA_REJECTED":      true,
+}
+
+// EnsureAppStoreVersion 取可编辑的指定版本 不存在时创建 platform 默认 IOS
+// 版本已提交或已上架时无法修改 已有其他可编辑版本时无法创建 均返回错误
+func (a *Api) EnsureAppStoreVersion(appID, platform, version string) (*AppStoreVersion, error) {
+	if platform == "" {
+		platform = "IOS"
+	}
+	list, err := a.ListAppStoreVersions(appID, NewApiQuery().Filter("versionString", version).Filter("platform", platform).Values())
+	if err != nil {
+		return nil, err
+	}
+	for i, v := range list.Data {
+		if v.Attributes.VersionString != version {
+			continue
+		}
+		if !editableVersionStates[v.Attributes.AppStoreState] {
+			return nil, fmt.Errorf("版本 %s 当前状态 %s 不可编辑", version, v.Attributes.AppStoreState)
+		}
+		return &list.Data[i], nil
+	}
+	// 同一平台只能有一个编辑中的版本 直接创建会返回 409
+	states := make([]string, 0, len(editableVersionStates))
+	for s := range editableVersionStates {
+		states = append(states, s)
+	}
+	sort.Strings(states)
+	editing, err := a.ListAppStoreVersions(appID, NewApiQuery().Filter("platform", platform).Filter("appStoreState", states...).Values())
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range editing.Data {
+		if editableVersionStates[v.Attributes.AppStoreState] {
+			return nil, fmt.Errorf("已有可编辑的版本 %s 状态 %s 无法创建版本 %s", v.Attributes.VersionString, v.Attributes.AppStoreState, version)
+		}
+	}
+	return a.CreateAppStoreVersion(appID, AppStoreVersionAttributes{Platform: platform, VersionString: version})
+}
+
+// EditableAppInfo 取编辑中的应用信息 只有一份时直接返回
+func (a *Api) EditableAppInfo(appID string) (*AppInfo, error) {
+	list, err := a.ListAppInfos(appID, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(list.Data) == 0 {
+		return nil, errors.New("应用信息不存在")
+	}
+	sort.SliceStable(list.Data, func(i, j int) bool {
+		return list.Data[i].Attributes.AppStoreState != "READY_FOR_SALE" && list.Data[j].Attributes.AppStoreState == "READY_FOR_SALE"
+	})
+	return &list.Data[0], nil
+}
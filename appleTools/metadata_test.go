@@ -0,0 +1,121 @@
+package appleTools
+
+import (
+	"github.com/tidwall/gjson"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestLoadMetadataDir(t *testing.T) {
+	m, err := LoadMetadataDir("testdata/metadata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 2 || m["en-US"].Keywords != "demo,tool" || m["zh-Hans"].WhatsNew != "修复问题" || m["zh-Hans"].Name != "演示" {
+		t.Fatalf("目录解析错误 %+v", m)
+	}
+	if _, err = LoadMetadataDir("testdata/none"); err == nil {
+		t.Fatal("目录不存在应返回错误")
+	}
+}
+
+func TestApi_ApplyMetadata(t *testing.T) {
+	var writes []string
+	api := newTestApi(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Method != "GET" {
+			writes = append(writes, r.Method+" "+r.URL.Path+" "+gjson.GetBytes(body, "data.attributes").Raw)
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "GET /v1/apps/app1/appStoreVersions":
+			if r.URL.Query().Get("filter[appStoreState]") != "" {
+				writeTestJson(w, 200, `{"data":[]}`)
+				return
+			}
+			if r.URL.Query().Get("filter[versionString]") != "1.3" {
+				t.Errorf("版本筛选错误 %s", r.URL.RawQuery)
+			}
+			writeTestJson(w, 200, `{"data":[]}`)
+		case "POST /v1/appStoreVersions":
+			writeTestJson(w, 201, `{"data":{"type":"appStoreVersions","id":"v1","attributes":{"versionString":"1.3","platform":"IOS"}}}`)
+		case "GET /v1/apps/app1/appInfos":
+			writeTestJson(w, 200, `{"data":[{"type":"appInfos","id":"live","attributes":{"appStoreState":"READY_FOR_SALE"}},
+				{"type":"appInfos","id":"edit","attributes":{"appStoreState":"PREPARE_FOR_SUBMISSION"}}]}`)
+		case "GET /v1/appStoreVersions/v1/appStoreVersionLocalizations":
+			writeTestJson(w, 200, `{"data":[{"type":"appStoreVersionLocalizations","id":"vl1","attributes":{"locale":"en-US","description":"A demo app.","keywords":"demo"}}]}`)
+		case "GET /v1/appInfos/edit/appInfoLocalizations":
+			writeTestJson(w, 200, `{"data":[{"type":"appInfoLocalizations","id":"il1","attributes":{"locale":"en-US","name":"Demo"}},
+				{"type":"appInfoLocalizations","id":"il2","attributes":{"locale":"zh-Hans","name":"旧名称"}}]}`)
+		case "PATCH /v1/appStoreVersionLocalizations/vl1", "POST /v1/appStoreVersionLocalizations", "PATCH /v1/appInfoLocalizations/il2":
+			writeTestJson(w, 200, `{"data":{"type":"x","id":"x"}}`)
+		default:
+			t.Errorf("未知请求 %s %s", r.Method, r.URL.Path)
+		}
+	})
+	version, err := api.EnsureAppStoreVersion("app1", "", "1.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := api.EditableAppInfo("app1")
+	if err != nil || info.ID != "edit" {
+		t.Fatalf("应用信息错误 %+v %v", info, err)
+	}
+	desired, _ := LoadMetadataDir("testdata/metadata")
+
+	changes, err := api.DiffMetadata(version.ID, info.ID, desired)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, c := range changes {
+		got = append(got, c.String())
+	}
+	want := `en-US keywords: "demo" => "demo,tool"|zh-Hans name: "旧名称" => "演示"|zh-Hans description: "" => "演示应用"|zh-Hans whatsNew: "" => "修复问题"`
+	if strings.Join(got, "|") != want {
+		t.Fatalf("差异错误 %s", strings.Join(got, "|"))
+	}
+	if len(writes) != 1 {
+		t.Fatalf("比较时不应提交 %v", writes)
+	}
+
+	writes = nil
+	if _, err = api.ApplyMetadata(version.ID, info.ID, desired); err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(writes)
+	want = `PATCH /v1/appInfoLocalizations/il2 {"name":"演示"}|PATCH /v1/appStoreVersionLocalizations/vl1 {"keywords":"demo,tool"}|` +
+		`POST /v1/appStoreVersionLocalizations {"locale":"zh-Hans","description":"演示应用","whatsNew":"修复问题"}`
+	if strings.Join(writes, "|") != want {
+		t.Fatalf("提交内容错误 %s", strings.Join(writes, "|"))
+	}
+}
+
+func TestApi_EnsureAppStoreVersion(t *testing.T) {
+	api := newTestApi(t, func(w http.ResponseWriter, r *http.Request) {
+		switch v := r.URL.Query().Get("filter[versionString]"); {
+		case r.Method == "GET" && r.URL.Query().Get("filter[appStoreState]") == "DEVELOPER_REJECTED,METADATA_REJECTED,PREPARE_FOR_SUBMISSION,REJECTED":
+			writeTestJson(w, 200, `{"data":[{"type":"appStoreVersions","id":"v13","attributes":{"versionString":"1.3","appStoreState":"DEVELOPER_REJECTED"}}]}`)
+		case r.Method == "GET" && v == "1.4":
+			writeTestJson(w, 200, `{"data":[]}`)
+		case r.Method == "GET" && v == "1.2":
+			writeTestJson(w, 200, `{"data":[{"type":"appStoreVersions","id":"v12","attributes":{"versionString":"1.2","appStoreState":"READY_FOR_SALE"}}]}`)
+		case r.Method == "GET" && v == "1.3":
+			writeTestJson(w, 200, `{"data":[{"type":"appStoreVersions","id":"v13","attributes":{"versionString":"1.3","appStoreState":"DEVELOPER_REJECTED"}}]}`)
+		default:
+			t.Errorf("未知请求 %s %s", r.Method, r.URL.String())
+		}
+	})
+	if v, err := api.EnsureAppStoreVersion("app1", "", "1.3"); err != nil || v.ID != "v13" {
+		t.Fatalf("被拒的版本应可以复用 %+v %v", v, err)
+	}
+	if _, err := api.EnsureAppStoreVersion("app1", "", "1.2"); err == nil || !strings.Contains(err.Error(), "READY_FOR_SALE") {
+		t.Fatalf("已上架的版本不应复用 %v", err)
+	}
+	// 已有其他编辑中的版本时不创建
+	if _, err := api.EnsureAppStoreVersion("app1", "", "1.4"); err == nil || !strings.Contains(err.Error(), "已有可编辑的版本 1.3") {
+		t.Fatalf("应返回编辑中的版本 %v", err)
+	}
+}
@@ -0,0 +1,259 @@
+package appleTools
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"howett.net/plist"
+	"strings"
+	"time"
+)
+
+const (
+	ProfileTypeIOSAdHoc       = "IOS_APP_ADHOC"
+	ProfileTypeIOSDevelopment = "IOS_APP_DEVELOPMENT"
+	ProfileTypeIOSAppStore    = "IOS_APP_STORE"
+
+	maxDevicesPerClass = 100 // 每个会员年度每类设备最多100台
+)
+
+// DeviceInput 待注册的设备 注册前按 DeviceClass 检查数量上限
+type DeviceInput struct {
+	Name        string
+	Udid        string
+	Platform    string // 默认 IOS
+	DeviceClass string // IPHONE IPAD ... 为空时 MAC_OS 按 MAC 计算 其他按 IPHONE 计算
+}
+
+// deviceClass 未填写时按平台推断
+func (in DeviceInput) deviceClass() string {
+	switch {
+	case in.DeviceClass != "":
+		return in.DeviceClass
+	case in.Platform == "MAC_OS":
+		return "MAC"
+	}
+	return "IPHONE"
+}
+
+// DeviceRegistration 批量注册结果
+type DeviceRegistration struct {
+	Created  []Device
+	Existing []Device
+	Enabled  []Device         // 已注册但被禁用 本次重新启用的设备
+	Failed   map[string]error // udid => 错误
+}
+
+// Devices 注册后可用的全部设备(已存在+重新启用+新注册)
+func (r *DeviceRegistration) Devices() []Device {
+	return append(append(append([]Device{}, r.Existing...), r.Enabled...), r.Created...)
+}
+
+// RegisterDevices 批量注册设备 按 UDID 去重 已注册的不会重复提交 被禁用的会重新启用
+func (a *Api) RegisterDevices(devices []DeviceInput) (*DeviceRegistration, error) {
+	existing, err := NewApiIterator[DeviceAttributes](a, "devices", NewApiQuery().Limit(200).Values()).All(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("获取设备列表失败 %s", err)
+	}
+	var (
+		result = &DeviceRegistration{Failed: make(map[string]error)}
+		byUdid = make(map[string]Device)
+		count  = make(map[string]int)
+		seen   = make(map[string]bool)
+	)
+	for _, d := range existing {
+		byUdid[normalizeUdid(d.Attributes.Udid)] = d
+		count[d.Attributes.DeviceClass]++
+	}
+	for _, in := range devices {
+		udid := normalizeUdid(in.Udid)
+		if udid == "" || seen[udid] {
+			continue
+		}
+		seen[udid] = true
+		if d, ok := byUdid[udid]; ok {
+			if d.Attributes.Status != "DISABLED" {
+				result.Existing = append(result.Existing, d)
+				continue
+			}
+			enabled, err := a.UpdateDevice(d.ID, "", "ENABLED")
+			if err != nil {
+				result.Failed[in.Udid] = fmt.Errorf("启用设备失败 %w", err)
+				continue
+			}
+			result.Enabled = append(result.Enabled, *enabled)
+			continue
+		}
+		class := in.deviceClass()
+		if count[class] >= maxDevicesPerClass {
+			result.Failed[in.Udid] = fmt.Errorf("%s 设备已达 %d 台上限", class, maxDevicesPerClass)
+			continue
+		}
+		platform := in.Platform
+		if platform == "" {
+			platform = "IOS"
+		}
+		name := in.Name
+		if name == "" {
+			name = in.Udid
+		}
+		d, err := a.RegisterDevice(name, in.Udid, platform)
+		if err != nil {
+			result.Failed[in.Udid] = err
+			continue
+		}
+		if d.Attributes.DeviceClass != "" {
+			class = d.Attributes.DeviceClass
+		}
+		count[class]++
+		result.Created = append(result.Created, *d)
+	}
+	return result, nil
+}
+
+func normalizeUdid(udid string) string {
+	return strings.ToUpper(strings.TrimSpace(udid))
+}
+
+// ProfileOptions 生成描述文件参数
+type ProfileOptions struct {
+	Name         string
+	ProfileType  string   // 默认 IOS_APP_ADHOC
+	BundleID     string   // 套装ID 如 com.demo.app
+	Certificates []string // 证书资源ID
+	Devices      []string // 设备资源ID 为空时使用平台下全部已启用设备 App Store 类型忽略
+}
+
+// RegenerateProfile 重新生成同名描述文件 使新注册的设备生效
+// 描述文件不能重名 先用临时名称创建 确认参数有效后再删除旧的 重新按原名称创建 最后删除临时的
+// 按原名称创建失败时返回临时描述文件和错误
+func (a *Api) RegenerateProfile(opt ProfileOptions) (*Profile, error) {
+	if opt.Name == "" || opt.BundleID == "" || len(opt.Certificates) == 0 {
+		return nil, errors.New("描述文件名称 套装ID 证书不能为空")
+	}
+	if opt.ProfileType == "" {
+		opt.ProfileType = ProfileTypeIOSAdHoc
+	}
+	bundle, err := a.findBundleID(opt.BundleID)
+	if err != nil {
+		return nil, err
+	}
+	devices := opt.Devices
+	if opt.ProfileType == ProfileTypeIOSAppStore {
+		devices = nil
+	} else if len(devices) == 0 {
+		list, err := NewApiIterator[DeviceAttributes](a, "devices", NewApiQuery().
+			Filter("status", "ENABLED").Filter("platform", bundlePlatform(bundle)).Limit(200).Values()).All(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("获取设备列表失败 %s", err)
+		}
+		for _, d := range list {
+			devices = append(devices, d.ID)
+		}
+		if len(devices) == 0 {
+			return nil, errors.New("没有可用的设备")
+		}
+	}
+	old, err := a.ListProfiles(NewApiQuery().Filter("name", opt.Name).Filter("profileType", opt.ProfileType).Values())
+	if err != nil {
+		return nil, err
+	}
+	tmp, err := a.CreateProfile(fmt.Sprintf("%s %d", opt.Name, time.Now().Unix()), opt.ProfileType, bundle.ID, opt.Certificates, devices)
+	if err != nil {
+		return nil, fmt.Errorf("创建描述文件失败 %w", err)
+	}
+	for _, p := range old.Data {
+		if p.Attributes.Name != opt.Name {
+			continue
+		}
+		if err = a.DeleteProfile(p.ID); err != nil {
+			return tmp, fmt.Errorf("删除旧描述文件失败 %w", err)
+		}
+	}
+	p, err := a.CreateProfile(opt.Name, opt.ProfileType, bundle.ID, opt.Certificates, devices)
+	if err != nil {
+		return tmp, fmt.Errorf("创建描述文件失败 %w", err)
+	}
+	if err = a.DeleteProfile(tmp.ID); err != nil {
+		return p, fmt.Errorf("删除临时描述文件 %s 失败 %w", tmp.Attributes.Name, err)
+	}
+	return p, nil
+}
+
+// findBundleID 按 identifier 精确查找套装ID
+func (a *Api) findBundleID(identifier string) (*BundleID, error) {
+	b, err := a.lookupBundleID(identifier)
+	if err == nil && b == nil {
+		err = fmt.Errorf("套装ID %s 不存在", identifier)
+	}
+	return b, err
+}
+
+// lookupBundleID 按 identifier 精确查找套装ID 不存在时返回 nil
+// filter[identifier] 是前缀匹配 需要翻页直到找到完全相同的
+func (a *Api) lookupBundleID(identifier string) (*BundleID, error) {
+	ctx := context.Background()
+	it := NewApiIterator[BundleIDAttributes](a, "bundleIds", NewApiQuery().Filter("identifier", identifier).Limit(200).Values())
+	for it.Next(ctx) {
+		if b := it.Item(); b.Attributes.Identifier == identifier {
+			found := *b
+			return &found, nil
+		}
+	}
+	return nil, it.Err()
+}
+
+func bundlePlatform(b *BundleID) string {
+	if b.Attributes.Platform == "MAC_OS" {
+		return "MAC_OS"
+	}
+	return "IOS"
+}
+
+// MobileProvision 解析后的 .mobileprovision
+type MobileProvision struct {
+	AppIDName                   string         `plist:"AppIDName"`
+	ApplicationIdentifierPrefix []string       `plist:"ApplicationIdentifierPrefix"`
+	CreationDate                time.Time      `plist:"CreationDate"`
+	ExpirationDate              time.Time      `plist:"ExpirationDate"`
+	Name                        string         `plist:"Name"`
+	Platform                    []string       `plist:"Platform"`
+	TeamIdentifier              []string       `plist:"TeamIdentifier"`
+	TeamName                    string         `plist:"TeamName"`
+	UUID                        string         `plist:"UUID"`
+	Version                     int            `plist:"Version"`
+	ProvisionedDevices          []string       `plist:"ProvisionedDevices"`
+	ProvisionsAllDevices        bool           `plist:"ProvisionsAllDevices"`
+	DeveloperCertificates       [][]byte       `plist:"DeveloperCertificates"`
+	Entitlements                map[string]any `plist:"Entitlements"`
+	Content                     []byte         `plist:"-"` // 原始文件内容
+}
+
+// ParseMobileProvision 从 CMS 签名的描述文件中取出 plist 并解析
+func ParseMobileProvision(content []byte) (*MobileProvision, error) {
+	start := bytes.Index(content, []byte("<?xml"))
+	end := bytes.LastIndex(content, []byte("</plist>"))
+	if start == -1 || end == -1 || end < start {
+		return nil, errors.New("描述文件中找不到 plist")
+	}
+	p := &MobileProvision{Content: content}
+	if _, err := plist.Unmarshal(content[start:end+len("</plist>")], p); err != nil {
+		return nil, fmt.Errorf("描述文件解析失败 %s", err)
+	}
+	return p, nil
+}
+
+// DownloadProfile 下载描述文件并解析
+func (a *Api) DownloadProfile(id string) (*MobileProvision, error) {
+	doc, err := a.GetProfile(id, nil)
+	if err != nil {
+		return nil, err
+	}
+	content, err := base64.StdEncoding.DecodeString(doc.Data.Attributes.ProfileContent)
+	if err != nil {
+		return nil, fmt.Errorf("描述文件内容不是base64 %s", err)
+	}
+	return ParseMobileProvision(content)
+}
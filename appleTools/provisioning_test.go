@@ -0,0 +1,149 @@
+package appleTools
+
+import (
+	"encoding/base64"
+	"fmt"
+	"github.com/tidwall/gjson"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+const testMobileProvision = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>AppIDName</key><string>Demo</string>
+	<key>ApplicationIdentifierPrefix</key><array><string>TEAM123</string></array>
+	<key>CreationDate</key><date>2022-10-01T08:00:00Z</date>
+	<key>ExpirationDate</key><date>2023-10-01T08:00:00Z</date>
+	<key>Name</key><string>adhoc</string>
+	<key>Platform</key><array><string>iOS</string></array>
+	<key>ProvisionedDevices</key><array><string>00008101-AAA</string><string>00008101-BBB</string></array>
+	<key>TeamIdentifier</key><array><string>TEAM123</string></array>
+	<key>TeamName</key><string>Demo Ltd</string>
+	<key>UUID</key><string>1111-2222</string>
+	<key>Version</key><integer>1</integer>
+	<key>Entitlements</key><dict><key>application-identifier</key><string>TEAM123.com.demo</string></dict>
+	<key>DeveloperCertificates</key><array><data>AQID</data></array>
+</dict>
+</plist>`
+
+func TestApi_Provisioning(t *testing.T) {
+	var (
+		devices  []string
+		created  []string
+		deleted  []string
+		profiles string
+	)
+	for i := 0; i < maxDevicesPerClass; i++ {
+		devices = append(devices, fmt.Sprintf(`{"type":"devices","id":"iphone%d","attributes":{"udid":"00008101-%03d","deviceClass":"IPHONE","status":"ENABLED","platform":"IOS"}}`, i, i))
+	}
+	devices = append(devices, `{"type":"devices","id":"ipad0","attributes":{"udid":"aaaa-ipad","deviceClass":"IPAD","status":"ENABLED","platform":"IOS"}}`,
+		`{"type":"devices","id":"ipad1","attributes":{"udid":"bbbb-off","deviceClass":"IPAD","status":"DISABLED","platform":"IOS"}}`)
+	api := newTestApi(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		switch r.Method + " " + r.URL.Path {
+		case "GET /v1/devices":
+			if r.URL.Query().Get("filter[status]") == "ENABLED" && r.URL.Query().Get("filter[platform]") != "IOS" {
+				t.Errorf("设备筛选错误 %s", r.URL.RawQuery)
+			}
+			writeTestJson(w, 200, `{"data":[`+strings.Join(devices, ",")+`],"links":{}}`)
+		case "POST /v1/devices":
+			udid := gjson.GetBytes(body, "data.attributes.udid").String()
+			created = append(created, udid)
+			writeTestJson(w, 201, `{"data":{"type":"devices","id":"new-`+udid+`","attributes":{"udid":"`+udid+`","deviceClass":"IPAD"}}}`)
+		case "PATCH /v1/devices/ipad1":
+			if gjson.GetBytes(body, "data.attributes.status").String() != "ENABLED" {
+				t.Errorf("启用设备错误 %s", body)
+			}
+			writeTestJson(w, 200, `{"data":{"type":"devices","id":"ipad1","attributes":{"udid":"bbbb-off","deviceClass":"IPAD","status":"ENABLED"}}}`)
+		case "GET /v1/bundleIds":
+			// 第一页只有前缀匹配的结果
+			if r.URL.Query().Get("cursor") == "" {
+				if r.URL.Query().Get("limit") != "200" {
+					t.Errorf("套装ID分页错误 %s", r.URL.RawQuery)
+				}
+				writeTestJson(w, 200, `{"data":[{"type":"bundleIds","id":"b0","attributes":{"identifier":"com.demo.app2"}}],"links":{"next":"http://`+r.Host+`/v1/bundleIds?cursor=2"}}`)
+				return
+			}
+			writeTestJson(w, 200, `{"data":[{"type":"bundleIds","id":"b1","attributes":{"identifier":"com.demo","platform":"IOS"}}]}`)
+		case "GET /v1/profiles":
+			if r.URL.Query().Get("filter[name]") != "adhoc" || r.URL.Query().Get("filter[profileType]") != ProfileTypeIOSAdHoc {
+				t.Errorf("描述文件筛选错误 %s", r.URL.RawQuery)
+			}
+			writeTestJson(w, 200, `{"data":[{"type":"profiles","id":"old","attributes":{"name":"adhoc"}}]}`)
+		case "DELETE /v1/profiles/old", "DELETE /v1/profiles/tmp":
+			deleted = append(deleted, strings.TrimPrefix(r.URL.Path, "/v1/profiles/"))
+			w.WriteHeader(204)
+		case "POST /v1/profiles":
+			profiles = string(body)
+			// 先用临时名称创建
+			if name := gjson.GetBytes(body, "data.attributes.name").String(); name != "adhoc" {
+				if len(deleted) != 0 || !strings.HasPrefix(name, "adhoc ") {
+					t.Errorf("应先创建临时描述文件 %s %v", name, deleted)
+				}
+				writeTestJson(w, 201, `{"data":{"type":"profiles","id":"tmp","attributes":{"name":"`+name+`"}}}`)
+				return
+			}
+			writeTestJson(w, 201, `{"data":{"type":"profiles","id":"p1","attributes":{"name":"adhoc"}}}`)
+		case "GET /v1/profiles/p1":
+			content := base64.StdEncoding.EncodeToString([]byte("\x30\x80\x06\x09cms-header" + testMobileProvision + "\x00\x00signature"))
+			writeTestJson(w, 200, `{"data":{"type":"profiles","id":"p1","attributes":{"profileContent":"`+content+`"}}}`)
+		default:
+			t.Errorf("未知请求 %s %s", r.Method, r.URL.Path)
+		}
+	})
+
+	res, err := api.RegisterDevices([]DeviceInput{
+		{Name: "已存在", Udid: "AAAA-IPAD"},
+		{Name: "重复", Udid: "00008101-new", DeviceClass: "IPAD"},
+		{Name: "重复", Udid: "00008101-NEW", DeviceClass: "IPAD"},
+		{Name: "超限", Udid: "00008101-full", DeviceClass: "IPHONE"},
+		{Name: "未知类型按 IPHONE 计算", Udid: "00008101-unknown"},
+		{Name: "平板", Udid: "00008101-pad", DeviceClass: "IPAD"},
+		{Name: "已禁用", Udid: "BBBB-OFF"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Existing) != 1 || res.Existing[0].ID != "ipad0" {
+		t.Errorf("已存在设备识别错误 %+v", res.Existing)
+	}
+	if len(res.Enabled) != 1 || res.Enabled[0].Attributes.Status != "ENABLED" {
+		t.Errorf("已禁用设备应重新启用 %+v", res.Enabled)
+	}
+	if len(created) != 2 || len(res.Created) != 2 || len(res.Devices()) != 4 {
+		t.Errorf("注册设备错误 %v", created)
+	}
+	if res.Failed["00008101-full"] == nil || res.Failed["00008101-unknown"] == nil || len(res.Failed) != 2 {
+		t.Errorf("应拒绝超出上限的设备 %v", res.Failed)
+	}
+
+	p, err := api.RegenerateProfile(ProfileOptions{Name: "adhoc", BundleID: "com.demo", Certificates: []string{"c1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.ID != "p1" || strings.Join(deleted, ",") != "old,tmp" {
+		t.Fatalf("重新生成描述文件错误 %+v %v", p, deleted)
+	}
+	if gjson.Get(profiles, "data.relationships.devices.data.#").Int() != int64(len(devices)) || gjson.Get(profiles, "data.relationships.bundleId.data.id").String() != "b1" {
+		t.Fatalf("描述文件请求错误 %s", profiles)
+	}
+	if _, err = api.RegenerateProfile(ProfileOptions{Name: "adhoc", BundleID: "com.none", Certificates: []string{"c1"}}); err == nil {
+		t.Fatal("套装ID不存在应返回错误")
+	}
+
+	mp, err := api.DownloadProfile("p1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mp.UUID != "1111-2222" || len(mp.ProvisionedDevices) != 2 || mp.TeamIdentifier[0] != "TEAM123" ||
+		mp.ExpirationDate.Year() != 2023 || mp.Entitlements["application-identifier"] != "TEAM123.com.demo" || len(mp.DeveloperCertificates[0]) != 3 {
+		t.Fatalf("描述文件解析错误 %+v", mp)
+	}
+	if _, err = ParseMobileProvision([]byte("garbage")); err == nil {
+		t.Fatal("无效内容应返回错误")
+	}
+}
@@ -0,0 +1,284 @@
+package appleTools
+
+import (
+	"context"
+	"github.com/xml520/wqutils/httpclient"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	rateLimitLowWater      = 100              // 剩余额度低于该值时排队
+	rateLimitMaxRetries    = 3                // 429 最多重试次数
+	rateLimitRetryDelay    = 5 * time.Second  // 没有 Retry-After 时的首次重试间隔 之后翻倍
+	rateLimitMaxRetryDelay = 80 * time.Second // 重试间隔上限
+	rateLimitWindow        = time.Hour        // 苹果按小时滚动计算额度
+)
+
+// apiRateLimiters 按密钥共享限流器 同一密钥的多个 Api 共用额度
+var apiRateLimiters sync.Map
+
+// RateLimit X-Rate-Limit 响应头 形如 user-hour-lim:3500;user-hour-rem:3499;
+type RateLimit struct {
+	Limit     int       // 每小时额度
+	Remaining int       // 剩余额度
+	UpdatedAt time.Time // 最后一次收到响应头的时间 零值表示还没有数据
+}
+
+// ParseRateLimit 解析 X-Rate-Limit 响应头
+func ParseRateLimit(header string) (RateLimit, bool) {
+	var (
+		r              RateLimit
+		hasLim, hasRem bool
+	)
+	for _, item := range strings.Split(header, ";") {
+		k, v, ok := strings.Cut(strings.TrimSpace(item), ":")
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			continue
+		}
+		switch strings.TrimSpace(k) {
+		case "user-hour-lim":
+			r.Limit, hasLim = n, true
+		case "user-hour-rem":
+			r.Remaining, hasRem = n, true
+		}
+	}
+	return r, hasLim && hasRem
+}
+
+// RateLimitStats 限流统计 用于监控
+type RateLimitStats struct {
+	Quota     RateLimit
+	Requests  int64         // 已发送的请求 含重试
+	Throttled int64         // 收到 429 的次数
+	Retries   int64         // 429 后重试的次数
+	Queued    int64         // 因额度不足排队的请求
+	Waiting   int           // 当前正在排队的请求
+	WaitTime  time.Duration // 排队和重试累计等待时间
+}
+
+// ApiRateLimiter 单个密钥的限流器 剩余额度低于阈值时请求排队 按额度均匀发送 收到 429 时延迟重试
+type ApiRateLimiter struct {
+	mu         sync.Mutex
+	quota      RateLimit
+	stats      RateLimitStats
+	last       time.Time
+	lowWater   int
+	maxRetries int
+	retryDelay time.Duration
+	queue      chan struct{}
+	now        func() time.Time
+	sleep      func(ctx context.Context, d time.Duration) error
+}
+
+// NewApiRateLimiter 创建限流器 一般通过 Api.RateLimiter 获取共享的实例
+func NewApiRateLimiter() *ApiRateLimiter {
+	return &ApiRateLimiter{
+		lowWater:   rateLimitLowWater,
+		maxRetries: rateLimitMaxRetries,
+		retryDelay: rateLimitRetryDelay,
+		queue:      make(chan struct{}, 1),
+		now:        time.Now,
+		sleep:      sleepContext,
+	}
+}
+
+// SetLowWater 设置排队阈值 为0时只在额度用完后排队
+func (l *ApiRateLimiter) SetLowWater(n int) {
+	l.mu.Lock()
+	l.lowWater = n
+	l.mu.Unlock()
+}
+
+// SetRetry 设置 429 重试次数和首次重试间隔 n 为0时不重试
+func (l *ApiRateLimiter) SetRetry(n int, delay time.Duration) {
+	l.mu.Lock()
+	l.maxRetries, l.retryDelay = n, delay
+	l.mu.Unlock()
+}
+
+// Quota 最近一次响应的额度
+func (l *ApiRateLimiter) Quota() RateLimit {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.quota
+}
+
+// Stats 统计信息
+func (l *ApiRateLimiter) Stats() RateLimitStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	s := l.stats
+	s.Quota = l.quota
+	return s
+}
+
+// Low 剩余额度是否低于阈值 超过一小时没有更新的额度视为已恢复
+func (l *ApiRateLimiter) Low() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.low()
+}
+func (l *ApiRateLimiter) low() bool {
+	return l.quota.Limit > 0 && l.quota.Remaining <= l.lowWater && l.now().Sub(l.quota.UpdatedAt) < rateLimitWindow
+}
+
+// do 发送请求 send 每次重试都会重新调用
+func (l *ApiRateLimiter) do(ctx context.Context, send func() (*httpclient.Response, error)) (*httpclient.Response, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	for attempt := 0; ; attempt++ {
+		release, err := l.acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		res, err := send()
+		release()
+		l.observe(res)
+		if res == nil || res.Response == nil || res.StatusCode != 429 {
+			return res, err
+		}
+		l.mu.Lock()
+		l.stats.Throttled++
+		retry := attempt < l.maxRetries
+		delay := l.retryDelay << attempt
+		l.mu.Unlock()
+		if !retry {
+			return res, err
+		}
+		if d, ok := parseRetryAfter(res.Header.Get("Retry-After"), l.now()); ok {
+			delay = d
+		}
+		if delay > rateLimitMaxRetryDelay {
+			delay = rateLimitMaxRetryDelay
+		}
+		res.Body.Close()
+		if err = l.wait(ctx, delay); err != nil {
+			return nil, err
+		}
+		l.mu.Lock()
+		l.stats.Retries++
+		l.mu.Unlock()
+	}
+}
+
+// acquire 额度充足时直接发送 否则排队并按 每小时额度 的间隔依次发送
+func (l *ApiRateLimiter) acquire(ctx context.Context) (func(), error) {
+	done := func() {
+		l.mu.Lock()
+		l.last = l.now()
+		l.mu.Unlock()
+	}
+	l.mu.Lock()
+	l.stats.Requests++
+	if !l.low() {
+		l.mu.Unlock()
+		return done, nil
+	}
+	l.stats.Queued++
+	l.stats.Waiting++
+	l.mu.Unlock()
+	start := l.now()
+	select {
+	case l.queue <- struct{}{}:
+	case <-ctx.Done():
+		l.mu.Lock()
+		l.stats.Waiting--
+		l.mu.Unlock()
+		return nil, ctx.Err()
+	}
+	l.mu.Lock()
+	l.stats.Waiting--
+	l.stats.WaitTime += l.now().Sub(start)
+	delay := time.Duration(0)
+	if l.quota.Limit > 0 {
+		delay = rateLimitWindow/time.Duration(l.quota.Limit) - l.now().Sub(l.last)
+	}
+	l.mu.Unlock()
+	if delay > 0 {
+		if err := l.wait(ctx, delay); err != nil {
+			<-l.queue
+			return nil, err
+		}
+	}
+	return func() {
+		done()
+		<-l.queue
+	}, nil
+}
+
+// observe 记录响应头中的额度
+func (l *ApiRateLimiter) observe(res *httpclient.Response) {
+	if res == nil || res.Response == nil {
+		return
+	}
+	q, ok := ParseRateLimit(res.Header.Get("X-Rate-Limit"))
+	if !ok {
+		return
+	}
+	l.mu.Lock()
+	q.UpdatedAt = l.now()
+	l.quota = q
+	l.mu.Unlock()
+}
+func (l *ApiRateLimiter) wait(ctx context.Context, d time.Duration) error {
+	err := l.sleep(ctx, d)
+	l.mu.Lock()
+	l.stats.WaitTime += d
+	l.mu.Unlock()
+	return err
+}
+
+// parseRetryAfter Retry-After 可以是秒数或 HTTP 时间
+func parseRetryAfter(v string, now time.Time) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+		return time.Duration(n) * time.Second, true
+	}
+	if t, err := time.Parse(time.RFC1123, v); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RateLimiter 当前密钥共享的限流器
+func (a *Api) RateLimiter() *ApiRateLimiter {
+	key := a.cacheKey()
+	if l, ok := apiRateLimiters.Load(key); ok {
+		return l.(*ApiRateLimiter)
+	}
+	l, _ := apiRateLimiters.LoadOrStore(key, NewApiRateLimiter())
+	return l.(*ApiRateLimiter)
+}
+
+// RateLimit 当前密钥最近一次响应的额度
+func (a *Api) RateLimit() RateLimit {
+	return a.RateLimiter().Quota()
+}
+
+// RateLimitStats 当前密钥的限流统计
+func (a *Api) RateLimitStats() RateLimitStats {
+	return a.RateLimiter().Stats()
+}
@@ -0,0 +1,147 @@
+package appleTools
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	r, ok := ParseRateLimit("user-hour-lim:3500;user-hour-rem:3421;")
+	if !ok || r.Limit != 3500 || r.Remaining != 3421 {
+		t.Errorf("解析错误 %+v %v", r, ok)
+	}
+	if _, ok = ParseRateLimit("user-hour-lim:3500;"); ok {
+		t.Error("缺少剩余额度应解析失败")
+	}
+	if _, ok = ParseRateLimit(""); ok {
+		t.Error("空响应头应解析失败")
+	}
+	if d, ok := parseRetryAfter("7", time.Now()); !ok || d != 7*time.Second {
+		t.Errorf("Retry-After 解析错误 %v", d)
+	}
+}
+
+func TestApiRateLimiter_Retry(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		calls int
+	)
+	api := newTestApi(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		n := calls
+		mu.Unlock()
+		// 重试的请求也必须带上 token
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ey") {
+			t.Errorf("第%d次请求缺少 token", n)
+			writeTestJson(w, 401, `{"errors":[{"status":"401","code":"NOT_AUTHORIZED"}]}`)
+			return
+		}
+		w.Header().Set("X-Rate-Limit", "user-hour-lim:3600;user-hour-rem:0;")
+		if n <= 2 {
+			if n == 2 {
+				w.Header().Set("Retry-After", "3")
+			}
+			writeTestJson(w, 429, `{"errors":[{"status":"429","code":"RATE_LIMIT_EXCEEDED","title":"The request rate limit has been reached."}]}`)
+			return
+		}
+		w.Header().Set("X-Rate-Limit", "user-hour-lim:3600;user-hour-rem:3000;")
+		writeTestJson(w, 200, `{"data":[]}`)
+	})
+	l := api.RateLimiter()
+	var delays []time.Duration
+	l.sleep = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return nil
+	}
+	l.SetRetry(3, time.Second)
+	if _, err := api.ListApps(nil); err != nil {
+		t.Fatal(err)
+	}
+	// 429 后先按默认间隔重试 第二次使用 Retry-After 额度用完后每次重试前还要排队等待一个间隔
+	want := []time.Duration{time.Second, time.Second, 3 * time.Second, time.Second}
+	if len(delays) != len(want) {
+		t.Fatalf("等待次数错误 %v", delays)
+	}
+	for i := range want {
+		if d := want[i] - delays[i]; d < 0 || d > 100*time.Millisecond {
+			t.Errorf("第%d次等待 %v 应为 %v", i, delays[i], want[i])
+		}
+	}
+	s := api.RateLimitStats()
+	if s.Requests != 3 || s.Throttled != 2 || s.Retries != 2 || s.Quota.Remaining != 3000 || s.Quota.Limit != 3600 {
+		t.Errorf("统计错误 %+v", s)
+	}
+	if api.RateLimit().Remaining != 3000 || l.Low() {
+		t.Errorf("额度错误 %+v", api.RateLimit())
+	}
+
+	// Do 的重试同样重新签发 token
+	mu.Lock()
+	calls = 0
+	mu.Unlock()
+	if res, err := api.Do("GET", "apps", nil); err != nil || res.StatusCode != 200 {
+		t.Errorf("Do 重试失败 %v", err)
+	}
+
+	// 超过重试次数返回 429 错误
+	mu.Lock()
+	calls = 0
+	mu.Unlock()
+	l.SetRetry(0, time.Second)
+	if _, err := api.ListApps(nil); !IsApiStatus(err, 429) {
+		t.Errorf("应返回 429 错误 %v", err)
+	}
+}
+
+func TestApiRateLimiter_Queue(t *testing.T) {
+	l := NewApiRateLimiter()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+	var delays []time.Duration
+	l.sleep = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		now = now.Add(d)
+		return nil
+	}
+	l.quota = RateLimit{Limit: 3600, Remaining: 500, UpdatedAt: now}
+	release, err := l.acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	if len(delays) != 0 {
+		t.Errorf("额度充足不应等待 %v", delays)
+	}
+	l.quota.Remaining = 50
+	for i := 0; i < 2; i++ {
+		release, err = l.acquire(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+	}
+	if len(delays) != 2 || delays[0] != time.Second || delays[1] != time.Second {
+		t.Errorf("排队间隔错误 %v", delays)
+	}
+	if s := l.Stats(); s.Queued != 2 || s.Requests != 3 || s.Waiting != 0 {
+		t.Errorf("统计错误 %+v", s)
+	}
+	// 额度超过一小时未更新视为已恢复
+	now = now.Add(2 * time.Hour)
+	if l.Low() {
+		t.Error("过期的额度不应排队")
+	}
+	// 排队时取消
+	now = now.Add(-2 * time.Hour)
+	l.queue <- struct{}{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = l.acquire(ctx); err != context.Canceled {
+		t.Errorf("应返回取消错误 %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+package appleTools
+
+import (
+	"errors"
+	"fmt"
+	"github.com/xml520/wqutils/httpclient"
+	"strings"
+)
+
+// RepairAction 412 修复流程需要完成的操作
+type RepairAction string
+
+const (
+	RepairPrivacyConsent    RepairAction = "privacy_consent"      // 接受隐私协议
+	RepairTerms             RepairAction = "terms_and_conditions" // 接受更新后的条款
+	RepairSecurityQuestions RepairAction = "security_questions"   // 设置安全提示问题
+	RepairHSA2Enrollment    RepairAction = "hsa2_enrollment"      // 升级双重认证
+)
+
+// repairSkippable 可以选择"以后再说"的操作
+var repairSkippable = map[RepairAction]string{
+	RepairHSA2Enrollment: "/repair/hsa2/later",
+}
+
+// repairAcceptable 可以直接同意的协议
+var repairAcceptable = map[RepairAction]string{
+	RepairPrivacyConsent: "/privacy/accept",
+	RepairTerms:          "/repair/terms/accept",
+}
+
+// RepairRequired 登录需要人工完成修复流程 errors.Is(err, AuthError412) 仍然成立
+type RepairRequired struct {
+	Actions []RepairAction
+	Session *AuthSession
+}
+
+func (e *RepairRequired) Error() string {
+	var s []string
+	for _, action := range e.Actions {
+		s = append(s, string(action))
+	}
+	return fmt.Sprintf("%s 需要处理: %s", AuthError412, strings.Join(s, ","))
+}
+func (e *RepairRequired) Is(target error) bool {
+	return target == AuthError412
+}
+
+// RepairOptions 获取 412 修复流程需要完成的操作 接口返回 404 时视为没有需要处理的操作
+func (a *AuthSession) RepairOptions() ([]RepairAction, error) {
+	res, err := a.repairDo("GET", "/repair/options", nil)
+	if err != nil {
+		if res != nil && res.Response != nil && res.StatusCode == 404 {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var actions []RepairAction
+	for _, step := range res.ToJson("requiredSteps").Array() {
+		actions = append(actions, RepairAction(step.String()))
+	}
+	return actions, nil
+}
+
+// AcceptAgreements 同意隐私协议和更新后的条款
+func (a *AuthSession) AcceptAgreements(actions ...RepairAction) error {
+	for _, action := range actions {
+		path, ok := repairAcceptable[action]
+		if !ok {
+			return fmt.Errorf("%s 不是协议类操作", action)
+		}
+		if _, err := a.repairDo("PUT", path, nil); err != nil {
+			return fmt.Errorf("%s %s", action, err)
+		}
+	}
+	return nil
+}
+
+// SkipRepair 选择"以后再说" 目前仅支持跳过升级双重认证
+func (a *AuthSession) SkipRepair(action RepairAction) error {
+	path, ok := repairSkippable[action]
+	if !ok {
+		return fmt.Errorf("%s 无法跳过", action)
+	}
+	if _, err := a.repairDo("POST", path, nil); err != nil {
+		return fmt.Errorf("%s %s", action, err)
+	}
+	return nil
+}
+
+// CompleteRepair 所有操作完成后结束修复流程
+func (a *AuthSession) CompleteRepair() error {
+	return a.accept()
+}
+
+// repair 自动处理能处理的修复操作 剩余的以 RepairRequired 返回
+func (a *AuthSession) repair() error {
+	// 没有需要处理的操作时按旧流程直接完成
+	actions, err := a.RepairOptions()
+	if err != nil {
+		return fmt.Errorf("获取修复选项失败 %w", err)
+	}
+	var pending []RepairAction
+	for _, action := range actions {
+		switch {
+		case repairAcceptable[action] != "":
+			err = a.AcceptAgreements(action)
+		case repairSkippable[action] != "":
+			err = a.SkipRepair(action)
+		default:
+			pending = append(pending, action)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if len(pending) > 0 {
+		return &RepairRequired{Actions: pending, Session: a}
+	}
+	return a.accept()
+}
+func (a *AuthSession) repairDo(method, path string, data any) (*httpclient.Response, error) {
+	if a.Header["X-Apple-Repair-Session-Token"] == "" {
+		return nil, errors.New("缺少修复会话")
+	}
+	if data == nil {
+		data = ""
+	}
+	res, err := a.accountHttp().Json(method, a.Auth.accountUrl(path), data)
+	if err != nil {
+		return res, err
+	}
+	a.Auth.setHttpCookie(res.Cookies())
+	a.extractAccountHeader(res)
+	if token := res.Header.Get("X-Apple-Repair-Session-Token"); token != "" {
+		a.Header["X-Apple-Repair-Session-Token"] = token
+	}
+	return res, nil
+}
@@ -0,0 +1,62 @@
+package appleTools
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestAuthSession_Repair(t *testing.T) {
+	srv := newReplayServer(t, "repair_replay.json")
+
+	a := &Auth{Account: "test@example.com"}
+	a.SetBaseUrl(srv.URL)
+	s := &AuthSession{Auth: a, Header: map[string]string{
+		"X-Apple-ID-Session-Id":        "sid-1",
+		"scnt":                         "scnt-1",
+		"X-Apple-Repair-Session-Token": "repair-1",
+	}}
+	err := s.repair()
+	var repairErr *RepairRequired
+	if !errors.As(err, &repairErr) {
+		t.Fatalf("期望 RepairRequired 实际 %v", err)
+	}
+	if !errors.Is(err, AuthError412) {
+		t.Fatal("RepairRequired 应兼容 AuthError412")
+	}
+	if len(repairErr.Actions) != 1 || repairErr.Actions[0] != RepairSecurityQuestions || repairErr.Session != s {
+		t.Fatalf("剩余操作错误 %+v", repairErr)
+	}
+	if err = s.AcceptAgreements(RepairTerms); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.AcceptAgreements(RepairSecurityQuestions); err == nil {
+		t.Fatal("安全问题不能直接同意")
+	}
+	if err = s.SkipRepair(RepairPrivacyConsent); err == nil {
+		t.Fatal("隐私协议不能跳过")
+	}
+}
+
+func TestAuthSession_RepairWithoutToken(t *testing.T) {
+	s := &AuthSession{Auth: &Auth{}, Header: map[string]string{}}
+	if _, err := s.RepairOptions(); err == nil {
+		t.Fatal("缺少修复会话应返回错误")
+	}
+}
+
+func TestAuthSession_RepairFallback(t *testing.T) {
+	srv := newReplayServer(t, "repair_fallback_replay.json")
+
+	a := &Auth{Account: "test@example.com"}
+	a.SetBaseUrl(srv.URL)
+	s := &AuthSession{Auth: a, Header: map[string]string{"X-Apple-Repair-Session-Token": "repair-1"}}
+	// 404 表示没有需要处理的操作 直接完成
+	if err := s.repair(); err != nil {
+		t.Fatal(err)
+	}
+	// 其他错误直接返回 不能跳过修复
+	if err := s.repair(); err == nil || !strings.Contains(err.Error(), "服务暂不可用") {
+		t.Fatalf("获取修复选项失败应返回错误 %v", err)
+	}
+}
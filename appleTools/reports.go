@@ -0,0 +1,339 @@
+package appleTools
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/xml520/wqutils/httpclient"
+	"io"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ReportFrequencyDaily   = "DAILY"
+	ReportFrequencyWeekly  = "WEEKLY"
+	ReportFrequencyMonthly = "MONTHLY"
+	ReportFrequencyYearly  = "YEARLY"
+
+	SalesReportTypeSales        = "SALES"
+	SalesReportTypeSubscription = "SUBSCRIPTION"
+	SalesReportSubTypeSummary   = "SUMMARY"
+	SalesReportSubTypeDetailed  = "DETAILED"
+
+	FinanceReportTypeFinancial = "FINANCIAL"
+	FinanceReportTypeDetail    = "FINANCE_DETAIL"
+
+	reportDateLayout = "01/02/2006"
+)
+
+// SalesReportFilter 销售报告参数 ReportDate 按频率分别为 2006-01-02 2006-01 2006
+type SalesReportFilter struct {
+	VendorNumber  string
+	Frequency     string // 默认 DAILY
+	ReportType    string // 默认 SALES
+	ReportSubType string // 默认 SUMMARY
+	ReportDate    string // 为空时为最新一期
+	Version       string // 默认 1_0 订阅报告为 1_3
+}
+
+// Values 转为 filter[...] 参数
+func (f SalesReportFilter) Values() url.Values {
+	if f.Frequency == "" {
+		f.Frequency = ReportFrequencyDaily
+	}
+	if f.ReportType == "" {
+		f.ReportType = SalesReportTypeSales
+	}
+	if f.ReportSubType == "" {
+		f.ReportSubType = SalesReportSubTypeSummary
+	}
+	if f.Version == "" {
+		f.Version = "1_0"
+		if f.ReportType == SalesReportTypeSubscription {
+			f.Version = "1_3"
+		}
+	}
+	q := NewApiQuery().Filter("vendorNumber", f.VendorNumber).Filter("frequency", f.Frequency).
+		Filter("reportType", f.ReportType).Filter("reportSubType", f.ReportSubType).Filter("version", f.Version)
+	if f.ReportDate != "" {
+		q.Filter("reportDate", f.ReportDate)
+	}
+	return q.Values()
+}
+
+// FinanceReportFilter 财务报告参数 ReportDate 为苹果财务月 2006-01
+type FinanceReportFilter struct {
+	VendorNumber string
+	RegionCode   string // 如 US EU ZZ(全部)
+	ReportDate   string
+	ReportType   string // 默认 FINANCIAL
+}
+
+// Values 转为 filter[...] 参数
+func (f FinanceReportFilter) Values() url.Values {
+	if f.ReportType == "" {
+		f.ReportType = FinanceReportTypeFinancial
+	}
+	return NewApiQuery().Filter("vendorNumber", f.VendorNumber).Filter("regionCode", f.RegionCode).
+		Filter("reportDate", f.ReportDate).Filter("reportType", f.ReportType).Values()
+}
+
+// Report 解析后的 TSV 报告
+type Report struct {
+	Header []string
+	Rows   [][]string
+}
+
+// Get 取第 i 行指定列 不存在时返回空
+func (r *Report) Get(i int, column string) string {
+	for j, h := range r.Header {
+		if h == column && i < len(r.Rows) && j < len(r.Rows[i]) {
+			return r.Rows[i][j]
+		}
+	}
+	return ""
+}
+
+// SalesReport 下载销售报告
+func (a *Api) SalesReport(f SalesReportFilter) (*Report, error) {
+	if f.VendorNumber == "" {
+		return nil, errors.New("VendorNumber 不能为空")
+	}
+	buf, err := a.download(context.Background(), "salesReports", f.Values())
+	if err != nil {
+		return nil, err
+	}
+	return ParseReport(buf)
+}
+
+// FinanceReport 下载财务报告
+func (a *Api) FinanceReport(f FinanceReportFilter) (*Report, error) {
+	if f.VendorNumber == "" || f.RegionCode == "" || f.ReportDate == "" {
+		return nil, errors.New("VendorNumber RegionCode ReportDate 不能为空")
+	}
+	buf, err := a.download(context.Background(), "financeReports", f.Values())
+	if err != nil {
+		return nil, err
+	}
+	return ParseReport(buf)
+}
+
+// SalesSummary 下载并解析销售汇总报告
+func (a *Api) SalesSummary(f SalesReportFilter) ([]SalesSummaryRow, error) {
+	f.ReportType, f.ReportSubType = SalesReportTypeSales, SalesReportSubTypeSummary
+	r, err := a.SalesReport(f)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeReport[SalesSummaryRow](r)
+}
+
+// download 下载报告文件 返回解压后的内容
+func (a *Api) download(ctx context.Context, path string, query url.Values) ([]byte, error) {
+	if a.pool != nil {
+		return a.pool.download(ctx, path, query)
+	}
+	u := a.url(path)
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	res, err := a.RateLimiter().do(ctx, func() (*httpclient.Response, error) {
+		client, err := a.http()
+		if err != nil {
+			return nil, err
+		}
+		return client.WithHeader("Accept", "application/a-gzip").WithOption(httpclient.OPT_CONTEXT, ctx).Get(u)
+	})
+	if res != nil && res.Response != nil {
+		defer res.Body.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+	buf, err := res.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	return gunzip(buf)
+}
+
+// gunzip 内容为 gzip 时解压 否则原样返回
+func gunzip(buf []byte) ([]byte, error) {
+	if len(buf) < 2 || buf[0] != 0x1f || buf[1] != 0x8b {
+		return buf, nil
+	}
+	r, err := gzip.NewReader(bytes.NewReader(buf))
+	if err != nil {
+		return nil, fmt.Errorf("报告解压失败 %s", err)
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// ParseReport 解析 TSV 报告 支持 gzip 压缩的内容 财务报告末尾的汇总行会被忽略
+func ParseReport(data []byte) (*Report, error) {
+	data, err := gunzip(data)
+	if err != nil {
+		return nil, err
+	}
+	r := &Report{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if r.Header == nil {
+			if line = strings.TrimPrefix(line, "\ufeff"); line != "" {
+				r.Header = strings.Split(line, "\t")
+			}
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			break
+		}
+		r.Rows = append(r.Rows, strings.Split(line, "\t"))
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	if r.Header == nil {
+		return nil, errors.New("报告内容为空")
+	}
+	return r, nil
+}
+
+// DecodeReport 按 report 标签把每行解析为 T 支持 string int float64 time.Time(MM/DD/YYYY)
+func DecodeReport[T any](r *Report) ([]T, error) {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	if typ.Kind() != reflect.Struct {
+		return nil, errors.New("报告行类型必须为结构体")
+	}
+	columns := make(map[string]int, len(r.Header))
+	for i, h := range r.Header {
+		columns[strings.TrimSpace(h)] = i
+	}
+	list := make([]T, 0, len(r.Rows))
+	for n, row := range r.Rows {
+		var item T
+		v := reflect.ValueOf(&item).Elem()
+		for i := 0; i < typ.NumField(); i++ {
+			col, ok := columns[typ.Field(i).Tag.Get("report")]
+			if !ok || col >= len(row) {
+				continue
+			}
+			if err := setReportField(v.Field(i), strings.TrimSpace(row[col])); err != nil {
+				return nil, fmt.Errorf("第 %d 行 %s 解析失败 %s", n+1, typ.Field(i).Tag.Get("report"), err)
+			}
+		}
+		list = append(list, item)
+	}
+	return list, nil
+}
+func setReportField(f reflect.Value, s string) error {
+	if s == "" || s == " " {
+		return nil
+	}
+	switch f.Interface().(type) {
+	case string:
+		f.SetString(s)
+	case int:
+		n, err := strconv.Atoi(strings.ReplaceAll(s, ",", ""))
+		if err != nil {
+			return err
+		}
+		f.SetInt(int64(n))
+	case float64:
+		n, err := strconv.ParseFloat(strings.ReplaceAll(s, ",", ""), 64)
+		if err != nil {
+			return err
+		}
+		f.SetFloat(n)
+	case time.Time:
+		t, err := time.Parse(reportDateLayout, s)
+		if err != nil {
+			return err
+		}
+		f.Set(reflect.ValueOf(t))
+	}
+	return nil
+}
+
+// SalesSummaryRow 销售汇总报告 SALES SUMMARY 1_0
+type SalesSummaryRow struct {
+	Provider              string    `report:"Provider"`
+	ProviderCountry       string    `report:"Provider Country"`
+	SKU                   string    `report:"SKU"`
+	Developer             string    `report:"Developer"`
+	Title                 string    `report:"Title"`
+	Version               string    `report:"Version"`
+	ProductTypeIdentifier string    `report:"Product Type Identifier"`
+	Units                 int       `report:"Units"`
+	DeveloperProceeds     float64   `report:"Developer Proceeds"`
+	BeginDate             time.Time `report:"Begin Date"`
+	EndDate               time.Time `report:"End Date"`
+	CustomerCurrency      string    `report:"Customer Currency"`
+	CountryCode           string    `report:"Country Code"`
+	CurrencyOfProceeds    string    `report:"Currency of Proceeds"`
+	AppleIdentifier       string    `report:"Apple Identifier"`
+	CustomerPrice         float64   `report:"Customer Price"`
+	PromoCode             string    `report:"Promo Code"`
+	ParentIdentifier      string    `report:"Parent Identifier"`
+	Subscription          string    `report:"Subscription"`
+	Period                string    `report:"Period"`
+	Category              string    `report:"Category"`
+	Device                string    `report:"Device"`
+	SupportedPlatforms    string    `report:"Supported Platforms"`
+	ProceedsReason        string    `report:"Proceeds Reason"`
+	Client                string    `report:"Client"`
+	OrderType             string    `report:"Order Type"`
+}
+
+// SubscriptionSummaryRow 订阅汇总报告 SUBSCRIPTION SUMMARY 1_3
+type SubscriptionSummaryRow struct {
+	AppName             string  `report:"App Name"`
+	AppAppleID          string  `report:"App Apple ID"`
+	SubscriptionName    string  `report:"Subscription Name"`
+	SubscriptionAppleID string  `report:"Subscription Apple ID"`
+	SubscriptionGroupID string  `report:"Subscription Group ID"`
+	StandardDuration    string  `report:"Standard Subscription Duration"`
+	CustomerPrice       float64 `report:"Customer Price"`
+	CustomerCurrency    string  `report:"Customer Currency"`
+	DeveloperProceeds   float64 `report:"Developer Proceeds"`
+	ProceedsCurrency    string  `report:"Proceeds Currency"`
+	Country             string  `report:"Country"`
+	Device              string  `report:"Device"`
+	ActiveStandardPrice int     `report:"Active Standard Price Subscriptions"`
+	ActiveFreeTrial     int     `report:"Active Free Trial Introductory Offer Subscriptions"`
+	ActivePayUpFront    int     `report:"Active Pay Up Front Introductory Offer Subscriptions"`
+	ActivePayAsYouGo    int     `report:"Active Pay As You Go Introductory Offer Subscriptions"`
+	BillingRetry        int     `report:"Billing Retry"`
+	GracePeriod         int     `report:"Grace Period"`
+	Subscribers         int     `report:"Subscribers"`
+}
+
+// FinanceRow 财务报告 FINANCIAL
+type FinanceRow struct {
+	StartDate             time.Time `report:"Start Date"`
+	EndDate               time.Time `report:"End Date"`
+	VendorIdentifier      string    `report:"Vendor Identifier"`
+	Quantity              int       `report:"Quantity"`
+	PartnerShare          float64   `report:"Partner Share"`
+	ExtendedPartnerShare  float64   `report:"Extended Partner Share"`
+	PartnerShareCurrency  string    `report:"Partner Share Currency"`
+	SalesOrReturn         string    `report:"Sales or Return"`
+	AppleIdentifier       string    `report:"Apple Identifier"`
+	Developer             string    `report:"Artist/Show/Developer/Author"`
+	Title                 string    `report:"Title"`
+	ProductTypeIdentifier string    `report:"Product Type Identifier"`
+	CountryOfSale         string    `report:"Country Of Sale"`
+	PreOrderFlag          string    `report:"Pre-order Flag"`
+	PromoCode             string    `report:"Promo Code"`
+	CustomerPrice         float64   `report:"Customer Price"`
+	CustomerCurrency      string    `report:"Customer Currency"`
+}
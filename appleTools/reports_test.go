@@ -0,0 +1,116 @@
+package appleTools
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func gzipFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	raw, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write(raw)
+	w.Close()
+	return buf.Bytes()
+}
+
+func TestApi_Reports(t *testing.T) {
+	sales, finance := gzipFixture(t, "sales_summary_1_0.txt"), gzipFixture(t, "finance_financial.txt")
+	throttled := false
+	api := newTestApi(t, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.Header.Get("Accept") != "application/a-gzip" || !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+			t.Errorf("请求头错误 %v", r.Header)
+		}
+		switch {
+		case r.URL.Path == "/v1/salesReports" && !throttled:
+			// 第一次限流 重试时应带上相同的请求头
+			throttled = true
+			writeTestJson(w, 429, `{"errors":[{"status":"429","detail":"rate limit"}]}`)
+		case q.Get("filter[reportType]") == "NEWSSTAND":
+			writeTestJson(w, 404, `{"errors":[{"status":"404","code":"NOT_FOUND","detail":"There were no sales for the date specified."}]}`)
+		case r.URL.Path == "/v1/salesReports":
+			if q.Get("filter[vendorNumber]") != "8000" || q.Get("filter[frequency]") != "DAILY" || q.Get("filter[reportType]") != "SALES" ||
+				q.Get("filter[reportSubType]") != "SUMMARY" || q.Get("filter[version]") != "1_0" || q.Get("filter[reportDate]") != "2026-10-18" {
+				t.Errorf("销售报告参数错误 %s", r.URL.RawQuery)
+			}
+			w.Header().Set("Content-Type", "application/a-gzip")
+			w.Write(sales)
+		case r.URL.Path == "/v1/financeReports":
+			if q.Get("filter[regionCode]") != "US" || q.Get("filter[reportType]") != "FINANCIAL" || q.Get("filter[reportDate]") != "2026-09" {
+				t.Errorf("财务报告参数错误 %s", r.URL.RawQuery)
+			}
+			w.Header().Set("Content-Type", "application/a-gzip")
+			w.Write(finance)
+		default:
+			t.Errorf("未知请求 %s", r.URL.Path)
+		}
+	})
+
+	api.RateLimiter().sleep = func(ctx context.Context, d time.Duration) error { return nil }
+
+	rows, err := api.SalesSummary(SalesReportFilter{VendorNumber: "8000", ReportDate: "2026-10-18"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0].Units != 12 || rows[0].SKU != "demo.app" || rows[0].BeginDate.Day() != 18 ||
+		rows[1].DeveloperProceeds != 0.7 || rows[1].ParentIdentifier != "demo.app" || rows[1].CountryCode != "CN" || rows[0].PromoCode != "" {
+		t.Fatalf("销售报告解析错误 %+v", rows)
+	}
+	if st := api.RateLimitStats(); st.Throttled != 1 || st.Retries != 1 {
+		t.Errorf("下载报告应经过限流重试 %+v", st)
+	}
+
+	report, err := api.FinanceReport(FinanceReportFilter{VendorNumber: "8000", RegionCode: "US", ReportDate: "2026-09"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Rows) != 2 || report.Get(1, "Sales or Return") != "R" {
+		t.Fatalf("财务报告应忽略汇总行 %v", report.Rows)
+	}
+	list, err := DecodeReport[FinanceRow](report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list[0].Quantity != 1200 || list[0].ExtendedPartnerShare != 840 || list[1].Quantity != -2 || list[0].StartDate.Month() != 9 {
+		t.Fatalf("财务报告解析错误 %+v", list)
+	}
+
+	if _, err = api.FinanceReport(FinanceReportFilter{VendorNumber: "8000"}); err == nil {
+		t.Fatal("缺少参数应返回错误")
+	}
+	if _, err = api.SalesReport(SalesReportFilter{VendorNumber: "8000", ReportType: "NEWSSTAND"}); !IsApiStatus(err, 404) {
+		t.Fatalf("应返回接口错误 %v", err)
+	}
+}
+
+func TestParseReport(t *testing.T) {
+	raw, _ := os.ReadFile("testdata/sales_summary_1_0.txt")
+	r, err := ParseReport(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Header) != 28 || len(r.Rows) != 2 {
+		t.Fatalf("未压缩报告解析错误 %d %d", len(r.Header), len(r.Rows))
+	}
+	if _, err = ParseReport(nil); err == nil {
+		t.Fatal("空报告应返回错误")
+	}
+	if _, err = DecodeReport[string](r); err == nil {
+		t.Fatal("非结构体应返回错误")
+	}
+	r.Rows[0][7] = "x"
+	if _, err = DecodeReport[SalesSummaryRow](r); err == nil {
+		t.Fatal("数字格式错误应返回错误")
+	}
+}
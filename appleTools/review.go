@@ -0,0 +1,206 @@
+package appleTools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	ReviewStateReadyForReview   = "READY_FOR_REVIEW"
+	ReviewStateWaitingForReview = "WAITING_FOR_REVIEW"
+	ReviewStateInReview         = "IN_REVIEW"
+	ReviewStateUnresolvedIssues = "UNRESOLVED_ISSUES"
+	ReviewStateComplete         = "COMPLETE"
+
+	ReviewItemApproved = "APPROVED"
+	ReviewItemAccepted = "ACCEPTED"
+	ReviewItemRejected = "REJECTED"
+)
+
+// ReviewOptions 提交审核参数
+type ReviewOptions struct {
+	AppID                   string
+	VersionID               string
+	BuildID                 string                          // 为空时使用版本已选择的构建版本
+	Platform                string                          // 默认 IOS
+	UsesNonExemptEncryption *bool                           // 出口合规 nil 时不修改
+	UsesIdfa                *bool                           // 广告标识符 nil 时不修改
+	ReviewDetail            *AppStoreReviewDetailAttributes // 审核联系人 nil 时不修改
+}
+
+// ReviewResult 审核结果
+type ReviewResult struct {
+	Submission ReviewSubmission
+	Items      []ReviewSubmissionItem
+}
+
+// Approved 所有提交项均已通过
+func (r *ReviewResult) Approved() bool {
+	if len(r.Items) == 0 {
+		return false
+	}
+	for _, item := range r.Items {
+		if item.Attributes.State != ReviewItemApproved && item.Attributes.State != ReviewItemAccepted {
+			return false
+		}
+	}
+	return true
+}
+
+// Rejected 被拒绝的提交项
+func (r *ReviewResult) Rejected() []ReviewSubmissionItem {
+	var list []ReviewSubmissionItem
+	for _, item := range r.Items {
+		if item.Attributes.State == ReviewItemRejected {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// ReviewRejected 审核被拒 需要在 App Store Connect 查看解决方案中心
+type ReviewRejected struct {
+	Result *ReviewResult
+}
+
+func (e *ReviewRejected) Error() string {
+	var ids []string
+	for _, item := range e.Result.Rejected() {
+		ids = append(ids, item.ID)
+	}
+	return fmt.Sprintf("审核提交 %s 被拒 状态 %s 被拒项 %s", e.Result.Submission.ID, e.Result.Submission.Attributes.State, strings.Join(ids, ","))
+}
+
+// SubmitForReview 选择构建版本 填写合规和审核信息后提交审核 返回已提交的审核
+func (a *Api) SubmitForReview(opt ReviewOptions) (*ReviewSubmission, error) {
+	if opt.AppID == "" || opt.VersionID == "" {
+		return nil, errors.New("应用ID和版本ID不能为空")
+	}
+	if opt.Platform == "" {
+		opt.Platform = "IOS"
+	}
+	if opt.BuildID != "" {
+		if err := a.SetVersionBuild(opt.VersionID, opt.BuildID); err != nil {
+			return nil, fmt.Errorf("选择构建版本失败 %w", err)
+		}
+	}
+	if opt.UsesNonExemptEncryption != nil {
+		buildID := opt.BuildID
+		if buildID == "" {
+			// 未指定构建版本时设置到版本已选择的构建版本上
+			doc, err := a.GetVersionBuild(opt.VersionID)
+			if err != nil && !IsApiStatus(err, 404) {
+				return nil, fmt.Errorf("获取版本的构建版本失败 %w", err)
+			}
+			if err == nil {
+				buildID = doc.Data.ID
+			}
+			if buildID == "" {
+				return nil, errors.New("版本未选择构建版本 无法设置出口合规")
+			}
+		}
+		if _, err := a.SetBuildEncryption(buildID, *opt.UsesNonExemptEncryption); err != nil {
+			return nil, fmt.Errorf("设置出口合规失败 %w", err)
+		}
+	}
+	if opt.UsesIdfa != nil {
+		if _, err := a.UpdateAppStoreVersion(opt.VersionID, AppStoreVersionAttributes{UsesIdfa: opt.UsesIdfa}); err != nil {
+			return nil, fmt.Errorf("设置广告标识符失败 %w", err)
+		}
+	}
+	if opt.ReviewDetail != nil {
+		if err := a.saveReviewDetail(opt.VersionID, *opt.ReviewDetail); err != nil {
+			return nil, fmt.Errorf("填写审核信息失败 %w", err)
+		}
+	}
+	// 复用未提交的审核 避免重复创建
+	list, err := a.ListReviewSubmissions(NewApiQuery().Filter("app", opt.AppID).Filter("platform", opt.Platform).
+		Filter("state", ReviewStateReadyForReview).Values())
+	if err != nil {
+		return nil, err
+	}
+	var submission *ReviewSubmission
+	if len(list.Data) > 0 {
+		submission = &list.Data[0]
+	} else if submission, err = a.CreateReviewSubmission(opt.AppID, opt.Platform); err != nil {
+		return nil, fmt.Errorf("创建审核提交失败 %w", err)
+	}
+	if _, err = a.AddReviewSubmissionVersion(submission.ID, opt.VersionID); err != nil && !IsApiStatus(err, 409) {
+		return nil, fmt.Errorf("添加版本到审核失败 %w", err)
+	}
+	submitted := true
+	if submission, err = a.UpdateReviewSubmission(submission.ID, ReviewSubmissionAttributes{Submitted: &submitted}); err != nil {
+		return nil, fmt.Errorf("提交审核失败 %w", err)
+	}
+	return submission, nil
+}
+func (a *Api) saveReviewDetail(versionID string, attr AppStoreReviewDetailAttributes) error {
+	doc, err := a.GetAppStoreReviewDetail(versionID)
+	switch {
+	case err == nil && doc.Data.ID != "":
+		_, err = a.UpdateAppStoreReviewDetail(doc.Data.ID, attr)
+	case err == nil || IsApiStatus(err, 404):
+		_, err = a.CreateAppStoreReviewDetail(versionID, attr)
+	}
+	return err
+}
+
+// ReviewResultOf 查询审核提交及其提交项
+func (a *Api) ReviewResultOf(ctx context.Context, submissionID string) (*ReviewResult, error) {
+	var (
+		doc   ApiDocument[ReviewSubmission]
+		items ApiDocument[[]ReviewSubmissionItem]
+	)
+	if err := a.request(ctx, "GET", "reviewSubmissions/"+submissionID, nil, nil, &doc); err != nil {
+		return nil, err
+	}
+	if err := a.request(ctx, "GET", "reviewSubmissions/"+submissionID+"/items", NewApiQuery().Limit(200).Values(), nil, &items); err != nil {
+		return nil, err
+	}
+	return &ReviewResult{Submission: doc.Data, Items: items.Data}, nil
+}
+
+// WaitForReview 轮询审核直到完成或出现问题 被拒时返回 ReviewRejected
+// 审核通常需要数小时到数天 interval 默认10分钟 timeout 为0时只受 ctx 控制 网络错误和 5xx 会继续轮询
+func (a *Api) WaitForReview(ctx context.Context, submissionID string, interval, timeout time.Duration) (*ReviewResult, error) {
+	if interval <= 0 {
+		interval = 10 * time.Minute
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	var last *ReviewResult
+	for {
+		result, err := a.ReviewResultOf(ctx, submissionID)
+		var apiErr *ApiErrors
+		if errors.As(err, &apiErr) && apiErr.StatusCode < 500 {
+			return nil, err
+		}
+		if err == nil {
+			last = result
+			switch result.Submission.Attributes.State {
+			case ReviewStateComplete:
+				if len(result.Rejected()) > 0 {
+					return result, &ReviewRejected{Result: result}
+				}
+				return result, nil
+			case ReviewStateUnresolvedIssues:
+				return result, &ReviewRejected{Result: result}
+			}
+		}
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			state := ""
+			if last != nil {
+				state = last.Submission.Attributes.State
+			}
+			return last, fmt.Errorf("等待审核超时 状态 %s %w", state, ctx.Err())
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package appleTools
+
+import (
+	"context"
+	"errors"
+	"github.com/tidwall/gjson"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApi_SubmitForReview(t *testing.T) {
+	var (
+		calls []string
+		polls int
+	)
+	api := newTestApi(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		switch r.Method + " " + r.URL.Path {
+		case "PATCH /v1/appStoreVersions/v1/relationships/build":
+			if gjson.GetBytes(body, "data.id").String() != "b1" {
+				t.Errorf("构建版本错误 %s", body)
+			}
+			w.WriteHeader(204)
+		case "PATCH /v1/builds/b1":
+			if !gjson.GetBytes(body, "data.attributes.usesNonExemptEncryption").Exists() || gjson.GetBytes(body, "data.attributes.usesNonExemptEncryption").Bool() {
+				t.Errorf("出口合规错误 %s", body)
+			}
+			writeTestJson(w, 200, `{"data":{"type":"builds","id":"b1"}}`)
+		case "PATCH /v1/appStoreVersions/v1":
+			if gjson.GetBytes(body, "data.attributes.usesIdfa").Raw != "false" {
+				t.Errorf("广告标识符错误 %s", body)
+			}
+			writeTestJson(w, 200, `{"data":{"type":"appStoreVersions","id":"v1"}}`)
+		case "GET /v1/appStoreVersions/v1/appStoreReviewDetail":
+			writeTestJson(w, 404, `{"errors":[{"status":"404","code":"NOT_FOUND","detail":"not found"}]}`)
+		case "POST /v1/appStoreReviewDetails":
+			if gjson.GetBytes(body, "data.attributes.contactEmail").String() != "review@test.com" {
+				t.Errorf("审核信息错误 %s", body)
+			}
+			writeTestJson(w, 201, `{"data":{"type":"appStoreReviewDetails","id":"d1"}}`)
+		case "GET /v1/reviewSubmissions":
+			if r.URL.Query().Get("filter[state]") != ReviewStateReadyForReview {
+				t.Errorf("审核筛选错误 %s", r.URL.RawQuery)
+			}
+			writeTestJson(w, 200, `{"data":[]}`)
+		case "POST /v1/reviewSubmissions":
+			writeTestJson(w, 201, `{"data":{"type":"reviewSubmissions","id":"s1","attributes":{"state":"READY_FOR_REVIEW"}}}`)
+		case "POST /v1/reviewSubmissionItems":
+			if gjson.GetBytes(body, "data.relationships.appStoreVersion.data.id").String() != "v1" {
+				t.Errorf("审核项错误 %s", body)
+			}
+			writeTestJson(w, 201, `{"data":{"type":"reviewSubmissionItems","id":"i1"}}`)
+		case "PATCH /v1/reviewSubmissions/s1":
+			if !gjson.GetBytes(body, "data.attributes.submitted").Bool() {
+				t.Errorf("提交审核错误 %s", body)
+			}
+			writeTestJson(w, 200, `{"data":{"type":"reviewSubmissions","id":"s1","attributes":{"state":"WAITING_FOR_REVIEW"}}}`)
+		case "GET /v1/appStoreVersions/v1/build":
+			writeTestJson(w, 200, `{"data":{"type":"builds","id":"b1"}}`)
+		case "GET /v1/appStoreVersions/v2/build":
+			writeTestJson(w, 200, `{"data":null}`)
+		case "GET /v1/reviewSubmissions/s1":
+			polls++
+			state := []string{"WAITING_FOR_REVIEW", "", "IN_REVIEW", "UNRESOLVED_ISSUES"}[polls-1]
+			if state == "" {
+				writeTestJson(w, 503, `{"errors":[{"status":"503","detail":"unavailable"}]}`)
+				return
+			}
+			writeTestJson(w, 200, `{"data":{"type":"reviewSubmissions","id":"s1","attributes":{"state":"`+state+`"}}}`)
+		case "GET /v1/reviewSubmissions/s1/items":
+			state := "READY_FOR_REVIEW"
+			if polls == 4 {
+				state = "REJECTED"
+			}
+			writeTestJson(w, 200, `{"data":[{"type":"reviewSubmissionItems","id":"i1","attributes":{"state":"`+state+`"}}]}`)
+		default:
+			t.Errorf("未知请求 %s %s", r.Method, r.URL.Path)
+		}
+	})
+	no := false
+	s, err := api.SubmitForReview(ReviewOptions{
+		AppID: "app1", VersionID: "v1", BuildID: "b1",
+		UsesNonExemptEncryption: &no, UsesIdfa: &no,
+		ReviewDetail: &AppStoreReviewDetailAttributes{ContactEmail: "review@test.com", ContactPhone: "+86 100"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Attributes.State != ReviewStateWaitingForReview || len(calls) != 9 {
+		t.Fatalf("提交审核错误 %+v %v", s, calls)
+	}
+
+	// 未指定构建版本时出口合规设置到版本已选择的构建版本
+	calls = nil
+	if _, err = api.SubmitForReview(ReviewOptions{AppID: "app1", VersionID: "v1", UsesNonExemptEncryption: &no}); err != nil {
+		t.Fatal(err)
+	}
+	if calls[0] != "GET /v1/appStoreVersions/v1/build" || calls[1] != "PATCH /v1/builds/b1" {
+		t.Fatalf("应设置已选择构建版本的出口合规 %v", calls)
+	}
+	calls = nil
+	if _, err = api.SubmitForReview(ReviewOptions{AppID: "app1", VersionID: "v2", UsesNonExemptEncryption: &no}); err == nil || len(calls) != 1 {
+		t.Fatalf("版本未选择构建版本时应返回错误 %v %v", err, calls)
+	}
+
+	// 轮询遇到 503 继续等待
+	result, err := api.WaitForReview(context.Background(), "s1", time.Millisecond, 0)
+	var rejected *ReviewRejected
+	if !errors.As(err, &rejected) || polls != 4 {
+		t.Fatalf("应返回被拒 %v %d", err, polls)
+	}
+	if result.Approved() || len(result.Rejected()) != 1 || !strings.Contains(err.Error(), "i1") {
+		t.Fatalf("审核结果错误 %+v", result)
+	}
+
+	// ctx 取消后不再发起请求
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = api.ReviewResultOf(ctx, "s1"); !errors.Is(err, context.Canceled) || polls != 4 {
+		t.Fatalf("ctx 取消应返回错误 %v %d", err, polls)
+	}
+}
@@ -0,0 +1,247 @@
+package appleTools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/xml520/wqutils/httpclient"
+	"net/url"
+	"strings"
+)
+
+const (
+	serverApiProduction = "https://api.storekit.itunes.apple.com"
+	serverApiSandbox    = "https://api.storekit-sandbox.itunes.apple.com"
+)
+
+// ServerApiError App Store Server API 错误
+type ServerApiError struct {
+	StatusCode   int
+	ErrorCode    int    `json:"errorCode"`
+	ErrorMessage string `json:"errorMessage"`
+}
+
+func (e *ServerApiError) Error() string {
+	if e.ErrorMessage != "" {
+		return fmt.Sprintf("%d %s", e.ErrorCode, e.ErrorMessage)
+	}
+	return fmt.Sprintf("App Store Server API 未知错误 状态码：%v", e.StatusCode)
+}
+
+// ServerApi App Store Server API 客户端 Api 需使用内购密钥(In-App Purchase Key)
+type ServerApi struct {
+	Api      *Api
+	BundleID string
+	Sandbox  bool
+	baseUrl  string
+}
+
+// NewServerApi 创建 App Store Server API 客户端
+func NewServerApi(a *Api, bundleID string, sandbox bool) *ServerApi {
+	return &ServerApi{Api: a, BundleID: bundleID, Sandbox: sandbox}
+}
+
+// SetBaseUrl 设置接口地址 为空时按 Sandbox 使用正式或沙盒地址
+func (s *ServerApi) SetBaseUrl(u string) {
+	s.baseUrl = strings.TrimRight(u, "/")
+}
+func (s *ServerApi) url(path string) string {
+	switch {
+	case s.baseUrl != "":
+		return s.baseUrl + path
+	case s.Sandbox:
+		return serverApiSandbox + path
+	default:
+		return serverApiProduction + path
+	}
+}
+
+// Token 签发带 bid 声明的 token 不支持密钥池的 Api
+func (s *ServerApi) Token() (string, error) {
+	if s.Api.pool != nil {
+		return "", errors.New("ServerApi 需要单个内购密钥 不支持密钥池")
+	}
+	p, err := s.Api.tokenProvider()
+	if err != nil {
+		return "", err
+	}
+	return p.BundleToken(s.BundleID)
+}
+
+func (s *ServerApi) do(method, path string, query url.Values, body any, out any) error {
+	u := s.url(path)
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	if body == nil {
+		body = ""
+	}
+	// 与 Api.request 一样经过限流器 429 时按 Retry-After 重试
+	res, err := s.Api.RateLimiter().do(context.Background(), func() (*httpclient.Response, error) {
+		token, err := s.Token()
+		if err != nil {
+			return nil, fmt.Errorf("token 生成失败 %s", err)
+		}
+		return httpclient.NewHttpClient().Defaults(map[interface{}]interface{}{
+			httpclient.OPT_COOKIEJAR: false,
+			httpclient.OPT_TIMEOUT:   30,
+			"Accept":                 jsonContentType,
+			"Authorization":          "Bearer " + token,
+		}).Json(method, u, body)
+	})
+	if res != nil && res.Response != nil {
+		defer res.Body.Close()
+	}
+	if err != nil {
+		return err
+	}
+	buf, err := res.ReadAll()
+	if err != nil {
+		return err
+	}
+	if res.StatusCode >= 300 {
+		e := &ServerApiError{StatusCode: res.StatusCode}
+		json.Unmarshal(buf, e)
+		return e
+	}
+	if out == nil || len(buf) == 0 {
+		return nil
+	}
+	return json.Unmarshal(buf, out)
+}
+
+// TransactionHistoryResponse 交易历史 一页最多20条
+type TransactionHistoryResponse struct {
+	Revision           string   `json:"revision"`
+	HasMore            bool     `json:"hasMore"`
+	BundleID           string   `json:"bundleId"`
+	AppAppleID         int64    `json:"appAppleId"`
+	Environment        string   `json:"environment"`
+	SignedTransactions []string `json:"signedTransactions"`
+}
+
+// TransactionHistory 查询交易历史 revision 为上一页返回的值 首页为空 query 可设置 sort productId 等
+func (s *ServerApi) TransactionHistory(transactionID, revision string, query url.Values) (*TransactionHistoryResponse, error) {
+	q := url.Values{}
+	for k, v := range query {
+		q[k] = v
+	}
+	if revision != "" {
+		q.Set("revision", revision)
+	}
+	res := &TransactionHistoryResponse{}
+	if err := s.do("GET", "/inApps/v2/history/"+transactionID, q, nil, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// AllTransactionHistory 读取全部交易历史
+func (s *ServerApi) AllTransactionHistory(transactionID string, query url.Values) ([]string, error) {
+	var (
+		list     []string
+		revision string
+	)
+	for {
+		res, err := s.TransactionHistory(transactionID, revision, query)
+		if err != nil {
+			return list, err
+		}
+		list = append(list, res.SignedTransactions...)
+		if !res.HasMore {
+			return list, nil
+		}
+		if res.Revision == "" || res.Revision == revision {
+			return list, errors.New("交易历史 revision 未变化")
+		}
+		revision = res.Revision
+	}
+}
+
+// TransactionInfo 查询单笔交易 返回 signedTransactionInfo
+func (s *ServerApi) TransactionInfo(transactionID string) (string, error) {
+	var res struct {
+		SignedTransactionInfo string `json:"signedTransactionInfo"`
+	}
+	if err := s.do("GET", "/inApps/v1/transactions/"+transactionID, nil, nil, &res); err != nil {
+		return "", err
+	}
+	return res.SignedTransactionInfo, nil
+}
+
+// SubscriptionStatus 订阅组内最新交易的状态
+type SubscriptionStatus struct {
+	SubscriptionGroupIdentifier string `json:"subscriptionGroupIdentifier"`
+	LastTransactions            []struct {
+		OriginalTransactionID string `json:"originalTransactionId"`
+		Status                int    `json:"status"` // 1有效 2过期 3账单重试 4宽限期 5已撤销
+		SignedTransactionInfo string `json:"signedTransactionInfo"`
+		SignedRenewalInfo     string `json:"signedRenewalInfo"`
+	} `json:"lastTransactions"`
+}
+
+// SubscriptionStatusesResponse 全部订阅状态
+type SubscriptionStatusesResponse struct {
+	Environment string               `json:"environment"`
+	BundleID    string               `json:"bundleId"`
+	AppAppleID  int64                `json:"appAppleId"`
+	Data        []SubscriptionStatus `json:"data"`
+}
+
+// SubscriptionStatuses 查询用户全部订阅的状态 status 可按状态过滤
+func (s *ServerApi) SubscriptionStatuses(transactionID string, status ...int) (*SubscriptionStatusesResponse, error) {
+	q := url.Values{}
+	for _, st := range status {
+		q.Add("status", fmt.Sprint(st))
+	}
+	res := &SubscriptionStatusesResponse{}
+	if err := s.do("GET", "/inApps/v1/subscriptions/"+transactionID, q, nil, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// RefundHistoryResponse 退款历史 一页最多20条
+type RefundHistoryResponse struct {
+	Revision           string   `json:"revision"`
+	HasMore            bool     `json:"hasMore"`
+	SignedTransactions []string `json:"signedTransactions"`
+}
+
+// RefundHistory 查询用户已退款的交易 revision 为上一页返回的值 首页为空
+func (s *ServerApi) RefundHistory(transactionID, revision string) (*RefundHistoryResponse, error) {
+	q := url.Values{}
+	if revision != "" {
+		q.Set("revision", revision)
+	}
+	res := &RefundHistoryResponse{}
+	if err := s.do("GET", "/inApps/v2/refund/lookup/"+transactionID, q, nil, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// ConsumptionRequest 退款请求的消耗信息 字段取值见苹果文档
+type ConsumptionRequest struct {
+	CustomerConsented        bool   `json:"customerConsented"`
+	ConsumptionStatus        int    `json:"consumptionStatus"`
+	Platform                 int    `json:"platform"`
+	SampleContentProvided    bool   `json:"sampleContentProvided"`
+	DeliveryStatus           int    `json:"deliveryStatus"`
+	AppAccountToken          string `json:"appAccountToken"`
+	AccountTenure            int    `json:"accountTenure"`
+	PlayTime                 int    `json:"playTime"`
+	LifetimeDollarsRefunded  int    `json:"lifetimeDollarsRefunded"`
+	LifetimeDollarsPurchased int    `json:"lifetimeDollarsPurchased"`
+	UserStatus               int    `json:"userStatus"`
+	RefundPreference         int    `json:"refundPreference,omitempty"`
+}
+
+// SendConsumptionInfo 收到 CONSUMPTION_REQUEST 通知后12小时内提交消耗信息
+func (s *ServerApi) SendConsumptionInfo(transactionID string, req ConsumptionRequest) error {
+	if !req.CustomerConsented {
+		return errors.New("需要用户同意才能提交消耗信息")
+	}
+	return s.do("PUT", "/inApps/v1/transactions/consumption/"+transactionID, nil, req, nil)
+}
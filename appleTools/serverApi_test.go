@@ -0,0 +1,124 @@
+package appleTools
+
+import (
+	"context"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/tidwall/gjson"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestServerApi(t *testing.T, handler http.HandlerFunc) *ServerApi {
+	t.Helper()
+	api := newTestApi(t, nil)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		claims := jwt.MapClaims{}
+		if _, _, err := new(jwt.Parser).ParseUnverified(token, claims); err != nil {
+			t.Errorf("token 解析失败 %s", err)
+		}
+		if claims["bid"] != "com.demo" || claims["aud"] != apiTokenAudience || claims["iss"] != api.IssuerID {
+			t.Errorf("token 声明错误 %v", claims)
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	s := NewServerApi(api, "com.demo", true)
+	s.SetBaseUrl(srv.URL + "/")
+	return s
+}
+
+func TestServerApi_History(t *testing.T) {
+	s := newTestServerApi(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" || r.URL.Path != "/inApps/v2/history/t1" || r.URL.Query().Get("sort") != "DESCENDING" {
+			t.Errorf("请求错误 %s %s", r.Method, r.URL)
+		}
+		switch r.URL.Query().Get("revision") {
+		case "":
+			writeTestJson(w, 200, `{"revision":"r1","hasMore":true,"bundleId":"com.demo","environment":"Sandbox","signedTransactions":["a","b"]}`)
+		case "r1":
+			writeTestJson(w, 200, `{"revision":"r2","hasMore":false,"signedTransactions":["c"]}`)
+		default:
+			t.Errorf("revision 错误 %s", r.URL.RawQuery)
+		}
+	})
+	list, err := s.AllTransactionHistory("t1", map[string][]string{"sort": {"DESCENDING"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(list, ",") != "a,b,c" {
+		t.Errorf("交易历史错误 %v", list)
+	}
+}
+
+func TestServerApi_Subscriptions(t *testing.T) {
+	s := newTestServerApi(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		switch r.Method + " " + r.URL.Path {
+		case "GET /inApps/v1/subscriptions/t1":
+			if r.URL.Query()["status"][1] != "4" {
+				t.Errorf("status 参数错误 %s", r.URL.RawQuery)
+			}
+			writeTestJson(w, 200, `{"environment":"Sandbox","bundleId":"com.demo","data":[{"subscriptionGroupIdentifier":"g1",
+				"lastTransactions":[{"originalTransactionId":"t1","status":1,"signedTransactionInfo":"x","signedRenewalInfo":"y"}]}]}`)
+		case "GET /inApps/v2/refund/lookup/t1":
+			writeTestJson(w, 200, `{"hasMore":false,"revision":"r","signedTransactions":["z"]}`)
+		case "PUT /inApps/v1/transactions/consumption/t1":
+			if !gjson.GetBytes(body, "customerConsented").Bool() || gjson.GetBytes(body, "consumptionStatus").Int() != 2 {
+				t.Errorf("消耗信息错误 %s", body)
+			}
+			w.WriteHeader(202)
+		case "GET /inApps/v1/transactions/t404":
+			writeTestJson(w, 404, `{"errorCode":4040010,"errorMessage":"Transaction id not found."}`)
+		default:
+			t.Errorf("未知请求 %s %s", r.Method, r.URL)
+		}
+	})
+	res, err := s.SubscriptionStatuses("t1", 1, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Data) != 1 || res.Data[0].LastTransactions[0].SignedRenewalInfo != "y" || res.Data[0].LastTransactions[0].Status != 1 {
+		t.Errorf("订阅状态错误 %+v", res)
+	}
+	refund, err := s.RefundHistory("t1", "")
+	if err != nil || refund.SignedTransactions[0] != "z" {
+		t.Errorf("退款历史错误 %+v %v", refund, err)
+	}
+	if err = s.SendConsumptionInfo("t1", ConsumptionRequest{ConsumptionStatus: 2}); err == nil {
+		t.Error("未同意时应返回错误")
+	}
+	if err = s.SendConsumptionInfo("t1", ConsumptionRequest{CustomerConsented: true, ConsumptionStatus: 2}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.TransactionInfo("t404")
+	if e, ok := err.(*ServerApiError); !ok || e.StatusCode != 404 || e.ErrorCode != 4040010 {
+		t.Errorf("错误解析失败 %v", err)
+	}
+}
+
+func TestServerApi_RateLimit(t *testing.T) {
+	calls := 0
+	s := newTestServerApi(t, func(w http.ResponseWriter, r *http.Request) {
+		if calls++; calls == 1 {
+			w.Header().Set("Retry-After", "1")
+			writeTestJson(w, 429, `{"errorCode":4290000,"errorMessage":"Rate limit exceeded."}`)
+			return
+		}
+		writeTestJson(w, 200, `{"signedTransactionInfo":"jws"}`)
+	})
+	s.Api.RateLimiter().sleep = func(ctx context.Context, d time.Duration) error { return nil }
+	if info, err := s.TransactionInfo("t1"); err != nil || info != "jws" || calls != 2 {
+		t.Fatalf("429 应重试 %v %s %d", err, info, calls)
+	}
+
+	// 密钥池没有单个密钥 无法签发带 bid 的 token
+	pooled := NewServerApi(NewApiPool(s.Api).Api(), "com.demo", true)
+	if _, err := pooled.TransactionInfo("t1"); err == nil || calls != 2 {
+		t.Fatalf("密钥池应返回错误 %v", err)
+	}
+}
@@ -0,0 +1,198 @@
+package appleTools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// TeamMember 期望的团队成员
+type TeamMember struct {
+	Email               string
+	FirstName           string
+	LastName            string
+	Roles               []string
+	AllAppsVisible      bool
+	ProvisioningAllowed bool
+	VisibleApps         []string // 应用ID AllAppsVisible 为 false 时生效
+}
+
+// TeamPlan 团队成员差异
+type TeamPlan struct {
+	Invite   []TeamMember // 新邀请
+	Reinvite []TeamMember // 邀请内容不一致 取消后重新邀请
+	Update   []TeamMember // 已加入的成员修改角色或权限
+	Remove   []string     // 移除的成员邮箱
+	Cancel   []string     // 取消的邀请邮箱
+	Failed   map[string]error
+}
+
+// Changed 是否有变更
+func (p *TeamPlan) Changed() bool {
+	return len(p.Invite)+len(p.Reinvite)+len(p.Update)+len(p.Remove)+len(p.Cancel) > 0
+}
+
+// ReconcileTeam 使团队成员与 members 一致 prune 为 true 时移除列表外的成员和邀请(账户持有人除外)
+// dryRun 为 true 时只计算差异 单个成员失败记录在 Failed 中不影响其他成员
+func (a *Api) ReconcileTeam(members []TeamMember, prune, dryRun bool) (*TeamPlan, error) {
+	ctx := context.Background()
+	users, err := NewApiIterator[UserAttributes](a, "users", NewApiQuery().Include("visibleApps").LimitRelated("visibleApps", 50).Limit(200).Values()).All(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取成员列表失败 %w", err)
+	}
+	invitations, err := NewApiIterator[UserInvitationAttributes](a, "userInvitations", NewApiQuery().Include("visibleApps").LimitRelated("visibleApps", 50).Limit(200).Values()).All(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取邀请列表失败 %w", err)
+	}
+	var (
+		plan     = &TeamPlan{Failed: make(map[string]error)}
+		userBy   = make(map[string]User)
+		inviteBy = make(map[string]UserInvitation)
+		want     = make(map[string]bool)
+	)
+	for _, u := range users {
+		userBy[normalizeEmail(u.Attributes.Username)] = u
+	}
+	for _, inv := range invitations {
+		inviteBy[normalizeEmail(inv.Attributes.Email)] = inv
+	}
+	for _, m := range members {
+		email := normalizeEmail(m.Email)
+		if email == "" || want[email] {
+			continue
+		}
+		want[email] = true
+		m.Email = email
+		if u, ok := userBy[email]; ok {
+			visible, err := a.allVisibleApps(ctx, "users/"+u.ID, u.Related("visibleApps"))
+			if err != nil {
+				plan.Failed[email] = err
+				continue
+			}
+			if !sameMember(m, u.Attributes.Roles, u.Attributes.AllAppsVisible, u.Attributes.ProvisioningAllowed, visible) {
+				plan.Update = append(plan.Update, m)
+			}
+			continue
+		}
+		if inv, ok := inviteBy[email]; ok {
+			visible, err := a.allVisibleApps(ctx, "userInvitations/"+inv.ID, inv.Related("visibleApps"))
+			if err != nil {
+				plan.Failed[email] = err
+				continue
+			}
+			if !sameMember(m, inv.Attributes.Roles, inv.Attributes.AllAppsVisible, inv.Attributes.ProvisioningAllowed, visible) {
+				plan.Reinvite = append(plan.Reinvite, m)
+			}
+			continue
+		}
+		plan.Invite = append(plan.Invite, m)
+	}
+	if prune {
+		for email, u := range userBy {
+			if !want[email] && !containsString(u.Attributes.Roles, UserRoleAccountHolder) {
+				plan.Remove = append(plan.Remove, email)
+			}
+		}
+		for email := range inviteBy {
+			if !want[email] {
+				plan.Cancel = append(plan.Cancel, email)
+			}
+		}
+		sort.Strings(plan.Remove)
+		sort.Strings(plan.Cancel)
+	}
+	if dryRun {
+		return plan, nil
+	}
+	for _, m := range plan.Update {
+		all, provisioning := m.AllAppsVisible, m.ProvisioningAllowed
+		var apps []string
+		if !all {
+			apps = m.VisibleApps
+		}
+		if _, err = a.UpdateUser(userBy[m.Email].ID, UserUpdate{Roles: m.Roles, AllAppsVisible: &all, ProvisioningAllowed: &provisioning}, apps...); err != nil {
+			plan.Failed[m.Email] = err
+		}
+	}
+	for _, m := range plan.Reinvite {
+		if err = a.CancelUserInvitation(inviteBy[m.Email].ID); err != nil {
+			plan.Failed[m.Email] = err
+			continue
+		}
+		if err = a.inviteMember(m); err != nil {
+			plan.Failed[m.Email] = err
+		}
+	}
+	for _, m := range plan.Invite {
+		if err = a.inviteMember(m); err != nil {
+			plan.Failed[m.Email] = err
+		}
+	}
+	for _, email := range plan.Remove {
+		if err = a.RemoveUser(userBy[email].ID); err != nil {
+			plan.Failed[email] = err
+		}
+	}
+	for _, email := range plan.Cancel {
+		if err = a.CancelUserInvitation(inviteBy[email].ID); err != nil {
+			plan.Failed[email] = err
+		}
+	}
+	return plan, nil
+}
+func (a *Api) inviteMember(m TeamMember) error {
+	_, err := a.InviteUser(UserInvitationAttributes{
+		Email:               m.Email,
+		FirstName:           m.FirstName,
+		LastName:            m.LastName,
+		Roles:               m.Roles,
+		AllAppsVisible:      m.AllAppsVisible,
+		ProvisioningAllowed: m.ProvisioningAllowed,
+	}, m.VisibleApps...)
+	return err
+}
+
+// sameMember 比较角色和权限 角色忽略顺序 可见应用仅在返回了关系数据时比较
+// allVisibleApps include 最多返回50个可见应用 被截断时分页读取全部
+func (a *Api) allVisibleApps(ctx context.Context, path string, rel *ApiRelationship) (*ApiRelationship, error) {
+	if !rel.Truncated() {
+		return rel, nil
+	}
+	apps, err := NewApiIterator[AppAttributes](a, path+"/visibleApps", NewApiQuery().Limit(200).Values()).All(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取可见应用失败 %w", err)
+	}
+	ids := make([]string, 0, len(apps))
+	for _, app := range apps {
+		ids = append(ids, app.ID)
+	}
+	return ToMany("apps", ids...), nil
+}
+func sameMember(m TeamMember, roles []string, allApps, provisioning bool, visible *ApiRelationship) bool {
+	if !sameStringSet(m.Roles, roles) || m.AllAppsVisible != allApps || m.ProvisioningAllowed != provisioning {
+		return false
+	}
+	if m.AllAppsVisible || visible == nil || len(visible.Data) == 0 {
+		return true
+	}
+	var apps []string
+	for _, l := range visible.Many() {
+		apps = append(apps, l.ID)
+	}
+	return sameStringSet(m.VisibleApps, apps)
+}
+func sameStringSet(a, b []string) bool {
+	x, y := append([]string{}, a...), append([]string{}, b...)
+	sort.Strings(x)
+	sort.Strings(y)
+	return strings.Join(x, ",") == strings.Join(y, ",")
+}
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
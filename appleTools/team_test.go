@@ -0,0 +1,82 @@
+package appleTools
+
+import (
+	"github.com/tidwall/gjson"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestApi_ReconcileTeam(t *testing.T) {
+	var writes []string
+	api := newTestApi(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Method != "GET" {
+			writes = append(writes, r.Method+" "+r.URL.Path)
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "GET /v1/users":
+			if r.URL.Query().Get("include") != "visibleApps" {
+				t.Errorf("缺少 include %s", r.URL.RawQuery)
+			}
+			writeTestJson(w, 200, `{"data":[
+				{"type":"users","id":"u0","attributes":{"username":"owner@test.com","roles":["ACCOUNT_HOLDER","ADMIN"],"allAppsVisible":true}},
+				{"type":"users","id":"u1","attributes":{"username":"Dev@test.com","roles":["DEVELOPER"],"allAppsVisible":false},
+					"relationships":{"visibleApps":{"data":[{"type":"apps","id":"a1"}]}}},
+				{"type":"users","id":"u2","attributes":{"username":"same@test.com","roles":["MARKETING","SALES"],"allAppsVisible":true}},
+				{"type":"users","id":"u3","attributes":{"username":"left@test.com","roles":["SALES"],"allAppsVisible":true}},
+				{"type":"users","id":"u4","attributes":{"username":"many@test.com","roles":["DEVELOPER"],"allAppsVisible":false},
+					"relationships":{"visibleApps":{"data":[{"type":"apps","id":"a1"}],"meta":{"paging":{"total":2,"limit":1}}}}}]}`)
+		case "GET /v1/users/u4/visibleApps":
+			writeTestJson(w, 200, `{"data":[{"type":"apps","id":"a1"},{"type":"apps","id":"a2"}]}`)
+		case "GET /v1/userInvitations":
+			writeTestJson(w, 200, `{"data":[
+				{"type":"userInvitations","id":"i1","attributes":{"email":"pending@test.com","roles":["ADMIN"],"allAppsVisible":true}},
+				{"type":"userInvitations","id":"i2","attributes":{"email":"stale@test.com","roles":["ADMIN"],"allAppsVisible":true}}]}`)
+		case "PATCH /v1/users/u1":
+			if gjson.GetBytes(body, "data.relationships.visibleApps.data.#").Int() != 2 || gjson.GetBytes(body, "data.attributes.roles.0").String() != "DEVELOPER" {
+				t.Errorf("修改成员请求错误 %s", body)
+			}
+			writeTestJson(w, 200, `{"data":{"type":"users","id":"u1"}}`)
+		case "POST /v1/userInvitations":
+			if gjson.GetBytes(body, "data.attributes.email").String() == "new@test.com" {
+				writeTestJson(w, 409, `{"errors":[{"status":"409","detail":"email 已被邀请"}]}`)
+				return
+			}
+			writeTestJson(w, 201, `{"data":{"type":"userInvitations","id":"i9"}}`)
+		case "DELETE /v1/users/u3", "DELETE /v1/userInvitations/i1", "DELETE /v1/userInvitations/i2":
+			w.WriteHeader(204)
+		default:
+			t.Errorf("未知请求 %s %s", r.Method, r.URL.Path)
+		}
+	})
+	members := []TeamMember{
+		{Email: "dev@test.com", Roles: []string{"DEVELOPER"}, VisibleApps: []string{"a1", "a2"}},
+		{Email: "same@test.com", Roles: []string{"SALES", "MARKETING"}, AllAppsVisible: true},
+		{Email: "pending@test.com", Roles: []string{"APP_MANAGER"}, AllAppsVisible: true},
+		{Email: "new@test.com", Roles: []string{"DEVELOPER"}, AllAppsVisible: true},
+		// include 截断的可见应用需要分页读取后比较
+		{Email: "many@test.com", Roles: []string{"DEVELOPER"}, VisibleApps: []string{"a2", "a1"}},
+	}
+	plan, err := api.ReconcileTeam(members, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(writes) != 0 || len(plan.Update) != 1 || len(plan.Reinvite) != 1 || len(plan.Invite) != 1 ||
+		strings.Join(plan.Remove, ",") != "left@test.com" || strings.Join(plan.Cancel, ",") != "stale@test.com" {
+		t.Fatalf("差异计算错误 %+v", plan)
+	}
+	if plan, err = api.ReconcileTeam(members, true, false); err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(writes)
+	want := "DELETE /v1/userInvitations/i1,DELETE /v1/userInvitations/i2,DELETE /v1/users/u3,PATCH /v1/users/u1,POST /v1/userInvitations,POST /v1/userInvitations"
+	if strings.Join(writes, ",") != want {
+		t.Fatalf("提交的变更错误 %v", writes)
+	}
+	if len(plan.Failed) != 1 || plan.Failed["new@test.com"] == nil {
+		t.Fatalf("失败记录错误 %v", plan.Failed)
+	}
+}
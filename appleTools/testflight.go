@@ -0,0 +1,209 @@
+package appleTools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	maxPublicLinkLimit = 10000 // 公开链接最多10000名测试员
+	betaTesterBatch    = 50    // 按邮箱批量查询测试员的数量
+)
+
+// EnablePublicLink 开启测试组公开链接 limit 大于0时限制测试员数量
+func (a *Api) EnablePublicLink(groupID string, limit int) (*BetaGroup, error) {
+	if limit > maxPublicLinkLimit {
+		return nil, fmt.Errorf("公开链接最多 %d 名测试员", maxPublicLinkLimit)
+	}
+	enabled, limited := true, limit > 0
+	attr := BetaGroupUpdate{PublicLinkEnabled: &enabled, PublicLinkLimitEnabled: &limited}
+	if limited {
+		attr.PublicLinkLimit = &limit
+	}
+	return a.UpdateBetaGroup(groupID, attr)
+}
+
+// DisablePublicLink 关闭测试组公开链接
+func (a *Api) DisablePublicLink(groupID string) (*BetaGroup, error) {
+	enabled := false
+	return a.UpdateBetaGroup(groupID, BetaGroupUpdate{PublicLinkEnabled: &enabled})
+}
+
+// BetaTesterResult 批量操作测试员结果
+type BetaTesterResult struct {
+	Done   []string         // 处理成功的邮箱
+	Failed map[string]error // 邮箱 => 错误
+}
+
+// findBetaTesters 按邮箱查找已存在的测试员 key 为小写邮箱
+func (a *Api) findBetaTesters(emails []string, groupID string) (map[string]BetaTester, error) {
+	result := make(map[string]BetaTester)
+	for i := 0; i < len(emails); i += betaTesterBatch {
+		end := i + betaTesterBatch
+		if end > len(emails) {
+			end = len(emails)
+		}
+		q := NewApiQuery().Filter("email", emails[i:end]...).Limit(200)
+		if groupID != "" {
+			q.Filter("betaGroups", groupID)
+		}
+		list, err := NewApiIterator[BetaTesterAttributes](a, "betaTesters", q.Values()).All(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range list {
+			result[normalizeEmail(t.Attributes.Email)] = t
+		}
+	}
+	return result, nil
+}
+
+// AddBetaTesters 按邮箱批量把测试员加入测试组 已存在的测试员直接关联 不存在的新建
+func (a *Api) AddBetaTesters(groupID string, testers []BetaTesterAttributes) (*BetaTesterResult, error) {
+	var (
+		result = &BetaTesterResult{Failed: make(map[string]error)}
+		emails []string
+		byMail = make(map[string]BetaTesterAttributes)
+	)
+	for _, t := range testers {
+		email := normalizeEmail(t.Email)
+		if email == "" {
+			continue
+		}
+		if _, ok := byMail[email]; ok {
+			continue
+		}
+		byMail[email] = t
+		emails = append(emails, email)
+	}
+	existing, err := a.findBetaTesters(emails, "")
+	if err != nil {
+		return nil, fmt.Errorf("查询测试员失败 %w", err)
+	}
+	var ids []string
+	for _, email := range emails {
+		if t, ok := existing[email]; ok {
+			ids = append(ids, t.ID)
+			continue
+		}
+		if _, err = a.CreateBetaTester(byMail[email], groupID); err != nil {
+			result.Failed[email] = err
+			continue
+		}
+		result.Done = append(result.Done, email)
+	}
+	if len(ids) > 0 {
+		if err = a.AddBetaTestersToGroup(groupID, ids...); err != nil {
+			for _, email := range emails {
+				if _, ok := existing[email]; ok {
+					result.Failed[email] = err
+				}
+			}
+		} else {
+			for _, email := range emails {
+				if _, ok := existing[email]; ok {
+					result.Done = append(result.Done, email)
+				}
+			}
+		}
+	}
+	return result, nil
+}
+
+// RemoveBetaTesters 按邮箱批量把测试员移出测试组 不在组内的邮箱记为失败
+func (a *Api) RemoveBetaTesters(groupID string, emails ...string) (*BetaTesterResult, error) {
+	result := &BetaTesterResult{Failed: make(map[string]error)}
+	var list []string
+	for _, email := range emails {
+		if email = normalizeEmail(email); email != "" {
+			list = append(list, email)
+		}
+	}
+	existing, err := a.findBetaTesters(list, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("查询测试员失败 %w", err)
+	}
+	var ids, found []string
+	for _, email := range list {
+		if t, ok := existing[email]; ok {
+			ids = append(ids, t.ID)
+			found = append(found, email)
+		} else {
+			result.Failed[email] = errors.New("测试员不在该测试组")
+		}
+	}
+	if len(ids) == 0 {
+		return result, nil
+	}
+	if err = a.RemoveBetaTestersFromGroup(groupID, ids...); err != nil {
+		for _, email := range found {
+			result.Failed[email] = err
+		}
+		return result, nil
+	}
+	result.Done = found
+	return result, nil
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// SetWhatToTest 设置构建版本各语言的测试说明 locale => 内容 已存在的语言会被覆盖
+func (a *Api) SetWhatToTest(buildID string, notes map[string]string) error {
+	list, err := a.ListBetaBuildLocalizations(buildID, NewApiQuery().Limit(200).Values())
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]BetaBuildLocalization)
+	for _, l := range list.Data {
+		existing[l.Attributes.Locale] = l
+	}
+	for _, locale := range sortedKeys(notes) {
+		if l, ok := existing[locale]; ok {
+			if l.Attributes.WhatsNew == notes[locale] {
+				continue
+			}
+			_, err = a.UpdateBetaBuildLocalization(l.ID, notes[locale])
+		} else {
+			_, err = a.CreateBetaBuildLocalization(buildID, locale, notes[locale])
+		}
+		if err != nil {
+			return fmt.Errorf("%s 测试说明设置失败 %w", locale, err)
+		}
+	}
+	return nil
+}
+
+// BuildDistribution 构建版本分发参数
+type BuildDistribution struct {
+	BuildID      string
+	Groups       []string          // 测试组ID
+	WhatToTest   map[string]string // locale => 测试说明
+	SubmitReview bool              // 外部测试组需要先通过审核
+}
+
+// DistributeBuild 上传处理完成后设置测试说明 分发到测试组并按需提交审核
+func (a *Api) DistributeBuild(d BuildDistribution) error {
+	if d.BuildID == "" {
+		return errors.New("构建版本ID不能为空")
+	}
+	if len(d.WhatToTest) > 0 {
+		if err := a.SetWhatToTest(d.BuildID, d.WhatToTest); err != nil {
+			return err
+		}
+	}
+	for _, group := range d.Groups {
+		if err := a.AddBuildsToGroup(group, d.BuildID); err != nil {
+			return fmt.Errorf("分发到测试组 %s 失败 %w", group, err)
+		}
+	}
+	if d.SubmitReview {
+		if _, err := a.SubmitForBetaReview(d.BuildID); err != nil {
+			return fmt.Errorf("提交测试审核失败 %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package appleTools
+
+import (
+	"github.com/tidwall/gjson"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestApi_TestFlight(t *testing.T) {
+	var calls []string
+	api := newTestApi(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		switch r.Method + " " + r.URL.Path {
+		case "PATCH /v1/betaGroups/g1":
+			writeTestJson(w, 200, `{"data":{"type":"betaGroups","id":"g1","attributes":`+gjson.GetBytes(body, "data.attributes").Raw+`}}`)
+		case "GET /v1/betaTesters":
+			q := r.URL.Query()
+			if q.Get("filter[betaGroups]") == "g1" {
+				writeTestJson(w, 200, `{"data":[{"type":"betaTesters","id":"t1","attributes":{"email":"Old@Test.com"}}]}`)
+				return
+			}
+			if q.Get("filter[email]") != "old@test.com,new@test.com" {
+				t.Errorf("邮箱筛选错误 %s", r.URL.RawQuery)
+			}
+			writeTestJson(w, 200, `{"data":[{"type":"betaTesters","id":"t1","attributes":{"email":"Old@Test.com"}}]}`)
+		case "POST /v1/betaTesters":
+			if gjson.GetBytes(body, "data.relationships.betaGroups.data.0.id").String() != "g1" {
+				t.Errorf("新测试员未关联测试组 %s", body)
+			}
+			writeTestJson(w, 201, `{"data":{"type":"betaTesters","id":"t2"}}`)
+		case "POST /v1/betaGroups/g1/relationships/betaTesters", "DELETE /v1/betaGroups/g1/relationships/betaTesters":
+			if gjson.GetBytes(body, "data.0.id").String() != "t1" {
+				t.Errorf("关联测试员错误 %s", body)
+			}
+			w.WriteHeader(204)
+		case "GET /v1/builds/b1/betaBuildLocalizations":
+			writeTestJson(w, 200, `{"data":[{"type":"betaBuildLocalizations","id":"l1","attributes":{"locale":"en-US","whatsNew":"old"}},
+				{"type":"betaBuildLocalizations","id":"l2","attributes":{"locale":"ja","whatsNew":"same"}}]}`)
+		case "PATCH /v1/betaBuildLocalizations/l1":
+			writeTestJson(w, 200, `{"data":{"type":"betaBuildLocalizations","id":"l1"}}`)
+		case "POST /v1/betaBuildLocalizations":
+			if gjson.GetBytes(body, "data.attributes.locale").String() != "zh-Hans" {
+				t.Errorf("测试说明语言错误 %s", body)
+			}
+			writeTestJson(w, 201, `{"data":{"type":"betaBuildLocalizations","id":"l3"}}`)
+		case "POST /v1/betaGroups/g1/relationships/builds":
+			w.WriteHeader(204)
+		case "POST /v1/betaAppReviewSubmissions":
+			writeTestJson(w, 201, `{"data":{"type":"betaAppReviewSubmissions","id":"s1","attributes":{"betaReviewState":"WAITING_FOR_REVIEW"}}}`)
+		default:
+			t.Errorf("未知请求 %s %s", r.Method, r.URL.Path)
+		}
+	})
+
+	g, err := api.EnablePublicLink("g1", 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !g.Attributes.PublicLinkEnabled || !g.Attributes.PublicLinkLimitEnabled || g.Attributes.PublicLinkLimit != 500 {
+		t.Fatalf("公开链接设置错误 %+v", g.Attributes)
+	}
+	if _, err = api.EnablePublicLink("g1", 20000); err == nil {
+		t.Fatal("超出测试员上限应返回错误")
+	}
+
+	res, err := api.AddBetaTesters("g1", []BetaTesterAttributes{{Email: "old@test.com"}, {Email: " NEW@test.com"}, {Email: "new@test.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Done) != 2 || len(res.Failed) != 0 {
+		t.Fatalf("添加测试员错误 %+v", res)
+	}
+	if res, err = api.RemoveBetaTesters("g1", "OLD@test.com", "none@test.com"); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(res.Done, ",") != "old@test.com" || res.Failed["none@test.com"] == nil {
+		t.Fatalf("移除测试员错误 %+v", res)
+	}
+
+	calls = nil
+	err = api.DistributeBuild(BuildDistribution{
+		BuildID:      "b1",
+		Groups:       []string{"g1"},
+		WhatToTest:   map[string]string{"en-US": "new", "ja": "same", "zh-Hans": "新功能"},
+		SubmitReview: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "GET /v1/builds/b1/betaBuildLocalizations,PATCH /v1/betaBuildLocalizations/l1,POST /v1/betaBuildLocalizations," +
+		"POST /v1/betaGroups/g1/relationships/builds,POST /v1/betaAppReviewSubmissions"
+	if strings.Join(calls, ",") != want {
+		t.Fatalf("分发流程错误 %v", calls)
+	}
+}
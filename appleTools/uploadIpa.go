@@ -348,7 +348,7 @@ func (u *Uploader) do(method string, data any) (*httpclient.Response, error) {
 		return nil, errors.New("json 格式化失败")
 	}
 	if u.auth.Api != nil {
-		//token, err := u.auth.Api.generateToken(300)
+		//token, err := u.auth.Api.Token()
 		//if err != nil {
 		//	return nil, err
 		//}
@@ -372,7 +372,7 @@ func (u *Uploader) authDo(method string, data any) (*httpclient.Response, error)
 	var header map[string]string
 	//log.Println(data)
 	if u.auth.Api != nil {
-		token, err := u.auth.Api.generateToken(300)
+		token, err := u.auth.Api.Token()
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,210 @@
+package appleTools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/xml520/wqutils/httpclient"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	CiProgressPending  = "PENDING"
+	CiProgressRunning  = "RUNNING"
+	CiProgressComplete = "COMPLETE"
+
+	CiStatusSucceeded = "SUCCEEDED"
+	CiStatusFailed    = "FAILED"
+	CiStatusErrored   = "ERRORED"
+	CiStatusCanceled  = "CANCELED"
+	CiStatusSkipped   = "SKIPPED"
+)
+
+// FindCiWorkflow 按产品名和工作流名查找工作流 productName 也可以是产品ID
+func (a *Api) FindCiWorkflow(ctx context.Context, productName, workflowName string) (*CiWorkflow, error) {
+	products, err := NewApiIterator[CiProductAttributes](a, "ciProducts", NewApiQuery().Limit(200).Values()).All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range products {
+		if p.ID != productName && !strings.EqualFold(p.Attributes.Name, productName) {
+			continue
+		}
+		workflows, err := NewApiIterator[CiWorkflowAttributes](a, "ciProducts/"+p.ID+"/workflows", NewApiQuery().Limit(200).Values()).All(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for i := range workflows {
+			if workflows[i].ID == workflowName || strings.EqualFold(workflows[i].Attributes.Name, workflowName) {
+				return &workflows[i], nil
+			}
+		}
+		return nil, fmt.Errorf("产品 %s 中不存在工作流 %s", p.Attributes.Name, workflowName)
+	}
+	return nil, fmt.Errorf("Xcode Cloud 产品 %s 不存在", productName)
+}
+
+// FindGitReference 查找工作流仓库中的分支或标签
+// ref 可以是分支名 标签名 或 refs/heads/main refs/tags/v1.0 分支和标签同名时需要使用完整名称
+func (a *Api) FindGitReference(ctx context.Context, workflowID, ref string) (*ScmGitReference, error) {
+	repo, err := a.GetCiWorkflowRepository(workflowID)
+	if err != nil {
+		return nil, fmt.Errorf("获取工作流仓库失败 %w", err)
+	}
+	refs, err := NewApiIterator[ScmGitReferenceAttributes](a, "scmRepositories/"+repo.Data.ID+"/gitReferences", NewApiQuery().Limit(200).Values()).All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var found []ScmGitReference
+	for _, r := range refs {
+		if r.Attributes.IsDeleted {
+			continue
+		}
+		if r.Attributes.CanonicalName == ref {
+			return &r, nil
+		}
+		if r.Attributes.Name == ref {
+			found = append(found, r)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil, fmt.Errorf("仓库 %s 中不存在分支或标签 %s", repo.Data.Attributes.RepositoryName, ref)
+	case 1:
+		return &found[0], nil
+	default:
+		return nil, fmt.Errorf("%s 同时匹配分支和标签 请使用 refs/heads/%s 或 refs/tags/%s", ref, ref, ref)
+	}
+}
+
+// StartCiBuild 使用分支或标签开始构建 ref 为空时使用工作流的默认配置
+func (a *Api) StartCiBuild(ctx context.Context, workflowID, ref string, clean bool) (*CiBuildRun, error) {
+	refID := ""
+	if ref != "" {
+		r, err := a.FindGitReference(ctx, workflowID, ref)
+		if err != nil {
+			return nil, err
+		}
+		refID = r.ID
+	}
+	return a.CreateCiBuildRun(workflowID, refID, clean)
+}
+
+// CiBuildRunChange 构建状态变化
+type CiBuildRunChange struct {
+	Progress string
+	Run      *CiBuildRun
+	Time     time.Time
+}
+
+// CiBuildFailed 构建完成但结果不是 SUCCEEDED
+type CiBuildFailed struct {
+	Status string
+	Run    *CiBuildRun
+}
+
+func (e *CiBuildFailed) Error() string {
+	return fmt.Sprintf("Xcode Cloud 构建 #%d 未成功 %s", e.Run.Attributes.Number, e.Status)
+}
+
+// WaitCiBuildOptions 等待构建参数
+type WaitCiBuildOptions struct {
+	Interval time.Duration           // 轮询间隔 默认30秒
+	Timeout  time.Duration           // 超时时间 默认2小时
+	Changes  chan<- CiBuildRunChange // 状态变化 可为空
+}
+
+// WaitCiBuildRun 轮询构建直到完成 未成功时返回 CiBuildFailed
+// 网络错误和5xx会继续轮询 其他接口错误直接返回
+func (a *Api) WaitCiBuildRun(ctx context.Context, runID string, opt WaitCiBuildOptions) (*CiBuildRun, error) {
+	if opt.Interval <= 0 {
+		opt.Interval = 30 * time.Second
+	}
+	if opt.Timeout <= 0 {
+		opt.Timeout = 2 * time.Hour
+	}
+	ctx, cancel := context.WithTimeout(ctx, opt.Timeout)
+	defer cancel()
+	last := ""
+	for {
+		var doc ApiDocument[CiBuildRun]
+		err := a.request(ctx, "GET", "ciBuildRuns/"+runID, nil, nil, &doc)
+		var apiErr *ApiErrors
+		if errors.As(err, &apiErr) && apiErr.StatusCode < 500 {
+			return nil, err
+		}
+		if err == nil {
+			run := &doc.Data
+			progress := run.Attributes.ExecutionProgress
+			if progress != last {
+				last = progress
+				if opt.Changes != nil {
+					select {
+					case opt.Changes <- CiBuildRunChange{Progress: progress, Run: run, Time: time.Now()}:
+					case <-ctx.Done():
+					}
+				}
+			}
+			if progress == CiProgressComplete {
+				if status := run.Attributes.CompletionStatus; status != CiStatusSucceeded {
+					return run, &CiBuildFailed{Status: status, Run: run}
+				}
+				return run, nil
+			}
+		}
+		select {
+		case <-time.After(opt.Interval):
+		case <-ctx.Done():
+			return nil, fmt.Errorf("等待构建 %s %s 最后状态 %s %w", runID, waitStopped(ctx.Err()), last, ctx.Err())
+		}
+	}
+}
+
+// CiActionReport 动作及其问题和产物
+type CiActionReport struct {
+	Action    CiBuildAction
+	Issues    []CiIssue
+	Artifacts []CiArtifact
+}
+
+// CiBuildReport 读取构建的全部动作 问题和产物
+func (a *Api) CiBuildReport(ctx context.Context, runID string) ([]CiActionReport, error) {
+	actions, err := NewApiIterator[CiBuildActionAttributes](a, "ciBuildRuns/"+runID+"/actions", NewApiQuery().Limit(200).Values()).All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]CiActionReport, 0, len(actions))
+	for _, action := range actions {
+		r := CiActionReport{Action: action}
+		if r.Issues, err = NewApiIterator[CiIssueAttributes](a, "ciBuildActions/"+action.ID+"/issues", NewApiQuery().Limit(200).Values()).All(ctx); err != nil {
+			return nil, fmt.Errorf("获取 %s 的问题失败 %w", action.Attributes.Name, err)
+		}
+		if r.Artifacts, err = NewApiIterator[CiArtifactAttributes](a, "ciBuildActions/"+action.ID+"/artifacts", NewApiQuery().Limit(200).Values()).All(ctx); err != nil {
+			return nil, fmt.Errorf("获取 %s 的产物失败 %w", action.Attributes.Name, err)
+		}
+		list = append(list, r)
+	}
+	return list, nil
+}
+
+// DownloadCiArtifact 下载产物写入 w 下载地址是临时地址 不需要 token
+func (a *Api) DownloadCiArtifact(ctx context.Context, artifact *CiArtifact, w io.Writer) (int64, error) {
+	if artifact.Attributes.DownloadUrl == "" {
+		doc, err := a.GetCiArtifact(artifact.ID)
+		if err != nil {
+			return 0, err
+		}
+		artifact = &doc.Data
+	}
+	res, err := httpclient.NewHttpClient().WithOption(httpclient.OPT_CONTEXT, ctx).Get(artifact.Attributes.DownloadUrl)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		return 0, fmt.Errorf("产物 %s 下载失败 状态码 %v", artifact.Attributes.FileName, res.StatusCode)
+	}
+	return io.Copy(w, res.Body)
+}
@@ -0,0 +1,140 @@
+package appleTools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/tidwall/gjson"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestApi_XcodeCloud(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		polls int
+		base  string
+	)
+	api := newTestApi(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		switch r.Method + " " + r.URL.Path {
+		case "GET /v1/ciProducts":
+			writeTestJson(w, 200, `{"data":[{"type":"ciProducts","id":"p0","attributes":{"name":"Other"}},{"type":"ciProducts","id":"p1","attributes":{"name":"Demo","productType":"APP"}}]}`)
+		case "GET /v1/ciProducts/p1/workflows":
+			writeTestJson(w, 200, `{"data":[{"type":"ciWorkflows","id":"w1","attributes":{"name":"Release","isEnabled":true}}]}`)
+		case "GET /v1/ciWorkflows/w1/repository":
+			writeTestJson(w, 200, `{"data":{"type":"scmRepositories","id":"repo1","attributes":{"repositoryName":"demo"}}}`)
+		case "GET /v1/scmRepositories/repo1/gitReferences":
+			writeTestJson(w, 200, `{"data":[
+				{"type":"scmGitReferences","id":"g1","attributes":{"name":"main","canonicalName":"refs/heads/main","kind":"BRANCH"}},
+				{"type":"scmGitReferences","id":"g2","attributes":{"name":"v1.0","canonicalName":"refs/tags/v1.0","kind":"TAG"}},
+				{"type":"scmGitReferences","id":"g3","attributes":{"name":"v1.0","canonicalName":"refs/heads/v1.0","kind":"BRANCH"}},
+				{"type":"scmGitReferences","id":"g4","attributes":{"name":"old","canonicalName":"refs/heads/old","kind":"BRANCH","isDeleted":true}}]}`)
+		case "POST /v1/ciBuildRuns":
+			if gjson.GetBytes(body, "data.relationships.workflow.data.id").String() != "w1" ||
+				gjson.GetBytes(body, "data.relationships.sourceBranchOrTag.data.id").String() != "g2" ||
+				!gjson.GetBytes(body, "data.attributes.clean").Bool() {
+				t.Errorf("开始构建请求错误 %s", body)
+			}
+			writeTestJson(w, 201, `{"data":{"type":"ciBuildRuns","id":"run1","attributes":{"number":7,"executionProgress":"PENDING"}}}`)
+		case "GET /v1/ciBuildRuns/run1":
+			mu.Lock()
+			polls++
+			n := polls
+			mu.Unlock()
+			switch n {
+			case 1:
+				writeTestJson(w, 200, `{"data":{"type":"ciBuildRuns","id":"run1","attributes":{"number":7,"executionProgress":"PENDING"}}}`)
+			case 2:
+				writeTestJson(w, 503, `{"errors":[{"status":"503","detail":"busy"}]}`)
+			case 3:
+				writeTestJson(w, 200, `{"data":{"type":"ciBuildRuns","id":"run1","attributes":{"number":7,"executionProgress":"RUNNING"}}}`)
+			default:
+				writeTestJson(w, 200, `{"data":{"type":"ciBuildRuns","id":"run1","attributes":{"number":7,"executionProgress":"COMPLETE","completionStatus":"FAILED","issueCounts":{"errors":1}}}}`)
+			}
+		case "GET /v1/ciBuildRuns/run1/actions":
+			writeTestJson(w, 200, `{"data":[{"type":"ciBuildActions","id":"a1","attributes":{"name":"Archive - iOS","actionType":"ARCHIVE","completionStatus":"FAILED"}}]}`)
+		case "GET /v1/ciBuildActions/a1/issues":
+			writeTestJson(w, 200, `{"data":[{"type":"ciIssues","id":"i1","attributes":{"issueType":"ERROR","message":"No such module","fileSource":{"path":"App.swift","lineNumber":3}}}]}`)
+		case "GET /v1/ciBuildActions/a1/artifacts":
+			writeTestJson(w, 200, `{"data":[{"type":"ciArtifacts","id":"f1","attributes":{"fileType":"LOG_BUNDLE","fileName":"logs.zip","downloadUrl":"`+base+`/download/logs.zip"}}]}`)
+		case "GET /download/logs.zip":
+			if r.Header.Get("Authorization") != "" {
+				t.Error("下载产物不应带 token")
+			}
+			w.Write([]byte("zipdata"))
+		default:
+			t.Errorf("未知请求 %s %s", r.Method, r.URL)
+			writeTestJson(w, 404, `{"errors":[{"status":"404"}]}`)
+		}
+	})
+	base = api.baseUrl
+	ctx := context.Background()
+
+	wf, err := api.FindCiWorkflow(ctx, "demo", "release")
+	if err != nil || wf.ID != "w1" {
+		t.Fatalf("查找工作流失败 %+v %v", wf, err)
+	}
+	if _, err = api.FindCiWorkflow(ctx, "Demo", "Debug"); err == nil {
+		t.Error("不存在的工作流应返回错误")
+	}
+	if _, err = api.StartCiBuild(ctx, "w1", "v1.0", true); err == nil {
+		t.Error("分支和标签同名时应返回错误")
+	}
+	if _, err = api.FindGitReference(ctx, "w1", "old"); err == nil {
+		t.Error("已删除的分支不应匹配")
+	}
+	if ref, err := api.FindGitReference(ctx, "w1", "main"); err != nil || ref.ID != "g1" {
+		t.Errorf("分支匹配错误 %+v %v", ref, err)
+	}
+	run, err := api.StartCiBuild(ctx, "w1", "refs/tags/v1.0", true)
+	if err != nil || run.ID != "run1" {
+		t.Fatalf("开始构建失败 %+v %v", run, err)
+	}
+
+	changes := make(chan CiBuildRunChange, 10)
+	run, err = api.WaitCiBuildRun(ctx, run.ID, WaitCiBuildOptions{Interval: time.Millisecond, Changes: changes})
+	var failed *CiBuildFailed
+	if !errors.As(err, &failed) || failed.Status != CiStatusFailed || run.Attributes.IssueCounts.Errors != 1 {
+		t.Fatalf("应返回构建失败 %+v %v", run, err)
+	}
+	close(changes)
+	var progress []string
+	for c := range changes {
+		progress = append(progress, c.Progress)
+	}
+	if len(progress) != 3 || progress[0] != CiProgressPending || progress[2] != CiProgressComplete {
+		t.Errorf("状态变化错误 %v", progress)
+	}
+
+	report, err := api.CiBuildReport(ctx, "run1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report) != 1 || report[0].Issues[0].Attributes.FileSource.LineNumber != 3 || len(report[0].Artifacts) != 1 {
+		t.Fatalf("构建报告错误 %+v", report)
+	}
+	var buf bytes.Buffer
+	if n, err := api.DownloadCiArtifact(ctx, &report[0].Artifacts[0], &buf); err != nil || n != 7 || buf.String() != "zipdata" {
+		t.Errorf("下载产物失败 %d %q %v", n, buf.String(), err)
+	}
+
+	// 超时
+	mu.Lock()
+	polls = 0
+	mu.Unlock()
+	_, err = api.WaitCiBuildRun(ctx, "run1", WaitCiBuildOptions{Interval: time.Hour, Timeout: 10 * time.Millisecond})
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "run1 超时") {
+		t.Errorf("应超时 %v", err)
+	}
+	cancelled, cancel := context.WithCancel(ctx)
+	time.AfterFunc(10*time.Millisecond, cancel)
+	_, err = api.WaitCiBuildRun(cancelled, "run1", WaitCiBuildOptions{Interval: time.Hour})
+	if !errors.Is(err, context.Canceled) || !strings.Contains(err.Error(), "run1 已取消") {
+		t.Errorf("应返回已取消 %v", err)
+	}
+}